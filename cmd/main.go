@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
-	"fmt"
 	cache "go-pattern/internal/cache/multilevel"
 	"go-pattern/internal/config"
 	"go-pattern/internal/initializer"
+	"go-pattern/internal/logging"
 	"go-pattern/internal/model"
+	"go-pattern/internal/outbox"
+	"go-pattern/internal/registry"
 	repoFactory "go-pattern/internal/repo/factory"
+	genericRepo "go-pattern/internal/repo/generic"
 	orderService "go-pattern/internal/service/order"
 	productService "go-pattern/internal/service/product"
-	"go-pattern/internal/table"
-	"log"
+	"go-pattern/internal/tenant"
+	"log/slog"
+	"os"
 	"time"
 )
 
@@ -19,108 +23,171 @@ func main() {
 	// 初始化数据库
 	configs, err := config.InitConfig()
 	if err != nil {
-		log.Fatalf("InitConfig(): 初始化配置失败: %v", err)
+		fatal(slog.Default(), "init config failed", err)
 	}
+	// 按配置创建日志器,未注入日志器的组件通过 slog.Default() 使用同一个
+	logger, err := logging.New(&configs.Log, os.Stdout)
+	if err != nil {
+		fatal(slog.Default(), "init logger failed", err)
+	}
+	slog.SetDefault(logger)
+
 	// 连接数据库
-	gormDB, err := initializer.GormDB(&configs.Database)
+	gormDB, err := initializer.GormDB(&configs.Database, logger)
 	if err != nil {
-		log.Fatalf("GormDB: 数据库连接失败: %v", err)
+		fatal(logger, "connect database failed", err)
 	}
 	// 创建所有表
-	err = table.NewAllTables(gormDB)
+	err = registry.Migrate(gormDB, logger)
 	if err != nil {
-		panic(err)
+		fatal(logger, "create tables failed", err)
 	}
 
-	repoFactory := repoFactory.NewRepoFactory(gormDB)
-
-	//userService := userService.NewUserService(repoFactory)
-	orderService := orderService.NewOrderService(repoFactory)
-	productService := productService.NewProductService(repoFactory)
+	// 连接只读副本,读操作按轮询路由到健康副本
+	replicas, err := initializer.GormReplicas(&configs.Database, logger)
+	if err != nil {
+		fatal(logger, "connect replicas failed", err)
+	}
+	replicaPool := genericRepo.NewReplicaPool(replicas...)
+	healthCheckInterval, err := time.ParseDuration(configs.Database.ReplicaHealthCheckInterval)
+	if err != nil || healthCheckInterval <= 0 {
+		healthCheckInterval = 10 * time.Second
+	}
+	replicaPool.StartHealthCheck(context.Background(), healthCheckInterval, logger)
 
 	redis, err := initializer.Redis(&configs.Redis)
 	if err != nil {
-		log.Fatalf("Redis: 创建Redis失败: %v", err)
+		fatal(logger, "create redis client failed", err)
+	}
+
+	cacheFactory := cache.NewMultiLevelCacheFactory(redis, logger)
+	orderCache, err := cacheFactory.Order(&configs.LocalCache, 15*time.Second)
+	if err != nil {
+		fatal(logger, "create order cache failed", err)
+	}
+	productCache, err := cacheFactory.Product(&configs.LocalCache, 15*time.Second)
+	if err != nil {
+		fatal(logger, "create product cache failed", err)
 	}
 
-	cacheFactory := cache.NewMultiLevelCacheFactory(redis)
-	orderCache := cacheFactory.Order(&configs.LocalCache, 15*time.Second)
-	productCache := cacheFactory.Product(&configs.LocalCache, 15*time.Second)
+	// 仓储调用失败时记录日志,超过阈值的调用告警
+	slowQueryThreshold, err := time.ParseDuration(configs.Database.SlowQueryThreshold)
+	if err != nil || slowQueryThreshold <= 0 {
+		slowQueryThreshold = 200 * time.Millisecond
+	}
+	interceptors := []genericRepo.Interceptor{
+		genericRepo.LoggingInterceptor(logger),
+		genericRepo.SlowQueryInterceptor(slowQueryThreshold, func(ctx context.Context, inv *genericRepo.Invocation, elapsed time.Duration) {
+			logger.WarnContext(ctx, "slow repo call",
+				slog.String("operation", inv.Operation),
+				slog.String("table", inv.Table),
+				slog.Duration("elapsed", elapsed))
+		}),
+	}
 
-	err = productService.CreateProduct(context.Background(), &model.Product{
+	// 订单与商品仓储按ID读取时走多级缓存,写操作自动失效缓存并记录审计日志
+	repoFactory := repoFactory.NewRepoFactory(gormDB,
+		genericRepo.WithCursorSecret([]byte(configs.Pagination.CursorSecret)),
+		genericRepo.WithReplicas(replicaPool),
+		genericRepo.WithAudit(true),
+		genericRepo.WithInterceptors(interceptors...),
+	).WithCaches(
+		repoFactory.Cached(orderCache),
+		repoFactory.Cached(productCache),
+	).WithLogger(logger)
+
+	// 发件箱中继:轮询已提交的领域事件并投递
+	var publisher outbox.Publisher
+	switch configs.Outbox.Publisher {
+	case "memory":
+		bus := outbox.NewBus()
+		bus.Subscribe(orderService.TopicOrderCreated, func(ctx context.Context, event *model.OutboxEvent) error {
+			logger.InfoContext(ctx, "order created event", slog.Uint64("event_id", event.ID), slog.String("payload", string(event.Payload)))
+			return nil
+		})
+		publisher = bus
+	default:
+		publisher = outbox.NewRedisStreamPublisher(redis, configs.Outbox.StreamPrefix, configs.Outbox.StreamMaxLen)
+	}
+	relayOpts := []outbox.RelayOption{outbox.WithLogger(logger)}
+	if pollInterval, err := time.ParseDuration(configs.Outbox.PollInterval); err == nil && pollInterval > 0 {
+		relayOpts = append(relayOpts, outbox.WithPollInterval(pollInterval))
+	}
+	if configs.Outbox.BatchSize > 0 {
+		relayOpts = append(relayOpts, outbox.WithBatchSize(configs.Outbox.BatchSize))
+	}
+	if configs.Outbox.MaxAttempts > 0 {
+		relayOpts = append(relayOpts, outbox.WithMaxAttempts(configs.Outbox.MaxAttempts))
+	}
+	relay := outbox.NewRelay(repoFactory, publisher, relayOpts...)
+	go relay.Run(context.Background())
+
+	//userService := userService.NewUserService(repoFactory)
+	orderService := orderService.NewOrderService(repoFactory, logger)
+	productService := productService.NewProductService(repoFactory)
+
+	// 演示请求都属于租户 1
+	ctx := tenant.WithTenant(context.Background(), 1)
+
+	err = productService.CreateProduct(ctx, &model.Product{
 		Name:        "testproduct",
 		Description: "testproduct",
 		Price:       100,
 		Quantity:    1000,
 	})
 	if err != nil {
-		log.Fatalf("create product failed: %v", err)
+		fatal(logger, "create product failed", err)
 	}
 
-	productPointer, err := productService.GetProduct(context.Background(), 1)
+	// 第一次读取回源数据库并写入缓存,第二次直接命中缓存
+	productPointer, err := productService.GetProduct(ctx, 1)
 	if err != nil {
-		log.Fatalf("get product failed: %v", err)
+		fatal(logger, "get product failed", err)
 	}
-	log.Printf("product: %v", productPointer)
+	logger.InfoContext(ctx, "product loaded", slog.Any("product", productPointer))
 
-	productCache.SetWithDefaultTTL(context.Background(), fmt.Sprintf("product:%d", productPointer.ID), *productPointer)
-
-	product, err := productCache.Get(context.Background(), fmt.Sprintf("product:%d", productPointer.ID))
+	product, err := productService.GetProduct(ctx, productPointer.ID)
 	if err != nil {
-		log.Fatalf("get product from cache failed: %v", err)
+		fatal(logger, "get product from cache failed", err)
 	}
-	log.Printf("product from cache: %v", product)
+	logger.InfoContext(ctx, "product loaded from cache", slog.Any("product", product))
 
-	err = productService.ReduceQuantity(context.Background(), productPointer.ID, 100)
-	if err != nil {
-		log.Fatalf("reduce product quantity failed: %v", err)
-	}
-	productPointer, err = productService.GetProduct(context.Background(), productPointer.ID)
+	// 扣减库存会删除该商品的缓存,随后的读取拿到最新数据
+	err = productService.ReduceQuantity(ctx, productPointer.ID, 100)
 	if err != nil {
-		log.Fatalf("get product failed: %v", err)
+		fatal(logger, "reduce product quantity failed", err)
 	}
-	log.Printf("product after reduce quantity: %v", productPointer)
-
-	productCache.Del(context.Background(), fmt.Sprintf("product:%d", productPointer.ID))
-
-	product, err = productCache.Get(context.Background(), fmt.Sprintf("product:%d", productPointer.ID))
+	productPointer, err = productService.GetProduct(ctx, productPointer.ID)
 	if err != nil {
-		log.Fatalf("get product from cache failed: %v", err)
+		fatal(logger, "get product failed", err)
 	}
-	log.Printf("product from cache after del: %v", product)
+	logger.InfoContext(ctx, "product after reduce quantity", slog.Any("product", productPointer))
 
-	orders, err := orderService.GetOrdersByUserID(context.Background(), 2)
+	orders, err := orderService.GetOrdersByUserID(ctx, 2)
 	if err != nil {
-		log.Fatalf("get orders failed: %v", err)
+		fatal(logger, "get orders failed", err)
 	}
-	log.Printf("orders: %v", orders)
+	logger.InfoContext(ctx, "orders loaded", slog.Int("count", len(orders)))
 	for _, order := range orders {
-		log.Printf("order: %v", order)
+		logger.DebugContext(ctx, "order", slog.Any("order", order))
 	}
 
-	for _, order := range orders {
-		orderCache.SetWithDefaultTTL(context.Background(), fmt.Sprintf("order:%d", order.ID), *order)
-	}
 	start := time.Now()
 	for range 10000 {
 		for _, order := range orders {
-			_, err := orderCache.Get(context.Background(), fmt.Sprintf("order:%d", order.ID))
+			_, err := orderService.GetOrder(ctx, order.ID)
 			if err != nil {
-				log.Fatalf("get order from cache failed: %v", err)
+				fatal(logger, "get order failed", err)
 			}
 		}
 	}
-	log.Printf("cost: %v", time.Since(start))
+	logger.InfoContext(ctx, "get orders benchmark finished", slog.Duration("cost", time.Since(start)))
+	logger.InfoContext(ctx, "transaction retry stats", slog.Any("stats", repoFactory.RetryStats()))
 
-	start = time.Now()
-	for range 10000 {
-		for _, order := range orders {
-			_, err := orderService.GetOrder(context.Background(), order.ID)
-			if err != nil {
-				log.Fatalf("get order from db failed: %v", err)
-			}
-		}
-	}
-	log.Printf("cost: %v", time.Since(start))
+}
 
+// fatal 记录错误后退出进程
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
@@ -0,0 +1,21 @@
+package audit
+
+import "context"
+
+// SystemActor 上下文中没有操作者时记录的默认值,如后台任务
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor 将操作者写入上下文,仓储记录审计日志时读取
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 读取上下文中的操作者,不存在时返回 SystemActor
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
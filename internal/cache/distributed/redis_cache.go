@@ -3,8 +3,11 @@ package distributedCache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
+	"log/slog"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -13,21 +16,22 @@ import (
 type redisCache[T any] struct {
 	client     *redis.Client
 	defaultTTL time.Duration
+	logger     *slog.Logger
 }
 
-func NewRedisCache[T any](client *redis.Client, defaultTTL time.Duration) DistributedCache[T] {
-	return &redisCache[T]{client: client, defaultTTL: defaultTTL}
+func NewRedisCache[T any](client *redis.Client, defaultTTL time.Duration, logger *slog.Logger) DistributedCache[T] {
+	return &redisCache[T]{client: client, defaultTTL: defaultTTL, logger: logging.OrDefault(logger)}
 }
 
 func (r *redisCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) error {
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
-		log.Printf("json marshal error: %v", err)
+		r.logger.ErrorContext(ctx, "json marshal error", slog.String("key", key), slog.Any("error", err))
 		return fmt.Errorf("json marshal error: %w", err)
 	}
 	err = r.client.Set(ctx, key, jsonValue, ttl).Err()
 	if err != nil {
-		log.Printf("redis set error: %v", err)
+		r.logger.ErrorContext(ctx, "redis set error", slog.String("key", key), slog.Any("error", err))
 		return fmt.Errorf("redis set error: %w", err)
 	}
 	return nil
@@ -36,12 +40,12 @@ func (r *redisCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl
 func (r *redisCache[T]) SetWithDefaultTTL(ctx context.Context, key string, value T) error {
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
-		log.Printf("json marshal error: %v", err)
+		r.logger.ErrorContext(ctx, "json marshal error", slog.String("key", key), slog.Any("error", err))
 		return fmt.Errorf("json marshal error: %w", err)
 	}
 	err = r.client.Set(ctx, key, jsonValue, r.defaultTTL).Err()
 	if err != nil {
-		log.Printf("redis set error: %v", err)
+		r.logger.ErrorContext(ctx, "redis set error", slog.String("key", key), slog.Any("error", err))
 		return fmt.Errorf("redis set error: %w", err)
 	}
 	return nil
@@ -50,13 +54,16 @@ func (r *redisCache[T]) SetWithDefaultTTL(ctx context.Context, key string, value
 func (r *redisCache[T]) Get(ctx context.Context, key string) (T, error) {
 	var result T
 	jsonValue, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return result, fmt.Errorf("redis get key %s: %w", key, errs.ErrCacheMiss)
+	}
 	if err != nil {
-		log.Printf("redis get error: %v", err)
+		r.logger.ErrorContext(ctx, "redis get error", slog.String("key", key), slog.Any("error", err))
 		return result, fmt.Errorf("redis get error: %w", err)
 	}
 	err = json.Unmarshal(jsonValue, &result)
 	if err != nil {
-		log.Printf("json unmarshal error: %v", err)
+		r.logger.ErrorContext(ctx, "json unmarshal error", slog.String("key", key), slog.Any("error", err))
 		return result, fmt.Errorf("json unmarshal error: %w", err)
 	}
 	return result, nil
@@ -65,13 +72,16 @@ func (r *redisCache[T]) Get(ctx context.Context, key string) (T, error) {
 func (r *redisCache[T]) GetPointer(ctx context.Context, key string) (*T, error) {
 	var result T
 	jsonValue, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("redis get key %s: %w", key, errs.ErrCacheMiss)
+	}
 	if err != nil {
-		log.Printf("redis get error: %v", err)
+		r.logger.ErrorContext(ctx, "redis get error", slog.String("key", key), slog.Any("error", err))
 		return nil, fmt.Errorf("redis get error: %w", err)
 	}
 	err = json.Unmarshal(jsonValue, &result)
 	if err != nil {
-		log.Printf("json unmarshal error: %v", err)
+		r.logger.ErrorContext(ctx, "json unmarshal error", slog.String("key", key), slog.Any("error", err))
 		return nil, fmt.Errorf("json unmarshal error: %w", err)
 	}
 	return &result, nil
@@ -80,7 +90,7 @@ func (r *redisCache[T]) GetPointer(ctx context.Context, key string) (*T, error)
 func (r *redisCache[T]) Del(ctx context.Context, key string) error {
 	err := r.client.Del(ctx, key).Err()
 	if err != nil {
-		log.Printf("redis del error: %v", err)
+		r.logger.ErrorContext(ctx, "redis del error", slog.String("key", key), slog.Any("error", err))
 		return fmt.Errorf("redis del error: %w", err)
 	}
 	return nil
@@ -4,7 +4,6 @@ import (
 	"context"
 	"go-pattern/internal/config"
 	"go-pattern/internal/initializer"
-	"log"
 	"time"
 
 	"github.com/dgraph-io/ristretto/v2"
@@ -23,27 +22,17 @@ func NewRistrettoCache[T any](localCacheConfig *config.LocalCacheConfig) (LocalC
 	return &ristrettoCache[T]{cache: localCache, defaultTTL: time.Duration(localCacheConfig.DefaultTTL) * time.Second}, nil
 }
 func (r *ristrettoCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) bool {
-	isSuccess := r.cache.SetWithTTL(key, value, 1, ttl)
-	if !isSuccess {
-		log.Printf("ristretto set drop key: %s", key)
-		return false
-	}
-	return true
+	// 写入可能被准入策略丢弃,由调用方决定是否记录
+	return r.cache.SetWithTTL(key, value, 1, ttl)
 }
 
 func (r *ristrettoCache[T]) SetWithDefaultTTL(ctx context.Context, key string, value T) bool {
-	isSuccess := r.cache.SetWithTTL(key, value, 1, r.defaultTTL)
-	if !isSuccess {
-		log.Printf("ristretto set drop key: %s", key)
-		return false
-	}
-	return true
+	return r.cache.SetWithTTL(key, value, 1, r.defaultTTL)
 }
 
 func (r *ristrettoCache[T]) Get(ctx context.Context, key string) (T, bool) {
 	value, isExist := r.cache.Get(key)
 	if !isExist {
-		log.Printf("ristretto get not exist key: %s", key)
 		var zeroValue T
 		return zeroValue, false
 	}
@@ -53,7 +42,6 @@ func (r *ristrettoCache[T]) Get(ctx context.Context, key string) (T, bool) {
 func (r *ristrettoCache[T]) GetPointer(ctx context.Context, key string) (*T, bool) {
 	value, isExist := r.cache.Get(key)
 	if !isExist {
-		log.Printf("ristretto get not exist key: %s", key)
 		return nil, false
 	}
 	return &value, true
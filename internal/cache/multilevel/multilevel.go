@@ -5,8 +5,10 @@ import (
 	"fmt"
 	distributedCache "go-pattern/internal/cache/distributed"
 	localCache "go-pattern/internal/cache/local"
+	"go-pattern/internal/logging"
 	"go-pattern/internal/model"
-	"log"
+	"go-pattern/internal/tenant"
+	"log/slog"
 	"time"
 )
 
@@ -18,51 +20,67 @@ type MultiLevelCache[T any] interface {
 	Del(ctx context.Context, key string) error
 }
 
+// tenantKey 上下文中有租户时为键加上租户前缀,不同租户的同名键互不可见
+func tenantKey(ctx context.Context, key string) string {
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		return fmt.Sprintf("tenant:%d:%s", tenantID, key)
+	}
+	return key
+}
+
 type multiLevelCache[T any] struct {
 	localCache       localCache.LocalCache[T]
 	distributedCache distributedCache.DistributedCache[T]
+	logger           *slog.Logger
 }
 
 func NewMultiLevelCache[T any, PT model.PointerModel[T]](
 	localCache localCache.LocalCache[T],
 	distributedCache distributedCache.DistributedCache[T],
+	logger *slog.Logger,
 ) MultiLevelCache[T] {
 	return &multiLevelCache[T]{
 		localCache:       localCache,
 		distributedCache: distributedCache,
+		logger:           logging.OrDefault(logger),
 	}
 }
 
 func (m *multiLevelCache[T]) SetWithTTL(ctx context.Context, key string, value T, l1Expiration time.Duration, l2Expiration time.Duration) error {
+	key = tenantKey(ctx, key)
 	err := m.distributedCache.SetWithTTL(ctx, key, value, l2Expiration)
 	if err != nil {
 		return fmt.Errorf("distributed cache set failed: %w", err)
 	}
 	isSuccess := m.localCache.SetWithTTL(ctx, key, value, l1Expiration)
 	if !isSuccess {
-		log.Printf("warning: local cache set failed, key: %s", key)
+		// 本地缓存写入失败不影响结果,下次读取会回源到分布式缓存
+		m.logger.WarnContext(ctx, "local cache set dropped", slog.String("key", key))
 	}
 	return nil
 }
 
 func (m *multiLevelCache[T]) SetWithDefaultTTL(ctx context.Context, key string, value T) error {
+	key = tenantKey(ctx, key)
 	err := m.distributedCache.SetWithDefaultTTL(ctx, key, value)
 	if err != nil {
 		return fmt.Errorf("distributed cache set failed: %w", err)
 	}
 	isSuccess := m.localCache.SetWithDefaultTTL(ctx, key, value)
 	if !isSuccess {
-		log.Printf("warning: local cache set failed, key: %s", key)
+		// 本地缓存写入失败不影响结果,下次读取会回源到分布式缓存
+		m.logger.WarnContext(ctx, "local cache set dropped", slog.String("key", key))
 	}
 	return nil
 }
 
 func (m *multiLevelCache[T]) Get(ctx context.Context, key string) (T, error) {
+	key = tenantKey(ctx, key)
 	value, isExist := m.localCache.Get(ctx, key)
 	if isExist {
 		return value, nil
 	}
-	log.Printf("local cache get failed, key: %s", key)
+	m.logger.DebugContext(ctx, "local cache miss", slog.String("key", key))
 	value, err := m.distributedCache.Get(ctx, key)
 	if err != nil {
 		var zeroValue T
@@ -70,17 +88,19 @@ func (m *multiLevelCache[T]) Get(ctx context.Context, key string) (T, error) {
 	}
 	isSuccess := m.localCache.SetWithDefaultTTL(ctx, key, value)
 	if !isSuccess {
-		log.Printf("warning: local cache set failed, key: %s", key)
+		// 本地缓存写入失败不影响结果,下次读取会回源到分布式缓存
+		m.logger.WarnContext(ctx, "local cache set dropped", slog.String("key", key))
 	}
 	return value, nil
 }
 
 func (m *multiLevelCache[T]) GetPointer(ctx context.Context, key string) (*T, error) {
+	key = tenantKey(ctx, key)
 	value, isExist := m.localCache.GetPointer(ctx, key)
 	if isExist {
 		return value, nil
 	}
-	log.Printf("local cache get failed, key: %s", key)
+	m.logger.DebugContext(ctx, "local cache miss", slog.String("key", key))
 	value, err := m.distributedCache.GetPointer(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("distributed cache get failed: %w", err)
@@ -89,6 +109,7 @@ func (m *multiLevelCache[T]) GetPointer(ctx context.Context, key string) (*T, er
 }
 
 func (m *multiLevelCache[T]) Del(ctx context.Context, key string) error {
+	key = tenantKey(ctx, key)
 	err := m.distributedCache.Del(ctx, key)
 	if err != nil {
 		return fmt.Errorf("distributed cache del failed: %w", err)
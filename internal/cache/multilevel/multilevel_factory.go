@@ -1,57 +1,55 @@
 package cache
 
 import (
+	"fmt"
 	distributedCache "go-pattern/internal/cache/distributed"
 	localCache "go-pattern/internal/cache/local"
 	"go-pattern/internal/config"
 	"go-pattern/internal/model"
+	"go-pattern/internal/registry"
+	"log/slog"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-type multiLevelCacheFactory struct {
+// MultiLevelCacheFactory 基于同一个 Redis 客户端为各模型创建多级缓存
+type MultiLevelCacheFactory struct {
 	redisClient *redis.Client
+	logger      *slog.Logger
 }
 
-func NewMultiLevelCacheFactory(redisClient *redis.Client) *multiLevelCacheFactory {
-	return &multiLevelCacheFactory{
+func NewMultiLevelCacheFactory(redisClient *redis.Client, logger *slog.Logger) *MultiLevelCacheFactory {
+	return &MultiLevelCacheFactory{
 		redisClient: redisClient,
+		logger:      logger,
 	}
 }
 
-func (f *multiLevelCacheFactory) User(localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) MultiLevelCache[model.User] {
-	distributedCache := distributedCache.NewRedisCache[model.User](f.redisClient, defaultTTLDistributedCache)
-	ristrettoCache, err := localCache.NewRistrettoCache[model.User](localCacheConfig)
+// For 为已注册模型 T 创建多级缓存:本地缓存按 localCacheConfig 创建,
+// 分布式缓存的默认过期时间为 defaultTTLDistributedCache;模型未注册时 panic
+func For[T any, PT model.PointerModel[T]](f *MultiLevelCacheFactory, localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) (MultiLevelCache[T], error) {
+	registry.MustLookup[T, PT]()
+	distributedCache := distributedCache.NewRedisCache[T](f.redisClient, defaultTTLDistributedCache, f.logger)
+	ristrettoCache, err := localCache.NewRistrettoCache[T](localCacheConfig)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("create %s local cache failed: %w", PT(new(T)).TableName(), err)
 	}
-	return NewMultiLevelCache(
+	return NewMultiLevelCache[T, PT](
 		ristrettoCache,
 		distributedCache,
-	)
+		f.logger,
+	), nil
 }
 
-func (f *multiLevelCacheFactory) Order(localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) MultiLevelCache[model.Order] {
-	distributedCache := distributedCache.NewRedisCache[model.Order](f.redisClient, defaultTTLDistributedCache)
-	ristrettoCache, err := localCache.NewRistrettoCache[model.Order](localCacheConfig)
-	if err != nil {
-		panic(err)
-	}
-	return NewMultiLevelCache(
-		ristrettoCache,
-		distributedCache,
-	)
+func (f *MultiLevelCacheFactory) User(localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) (MultiLevelCache[model.User], error) {
+	return For[model.User](f, localCacheConfig, defaultTTLDistributedCache)
 }
 
-func (f *multiLevelCacheFactory) Product(localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) MultiLevelCache[model.Product] {
-	distributedCache := distributedCache.NewRedisCache[model.Product](f.redisClient, defaultTTLDistributedCache)
-	ristrettoCache, err := localCache.NewRistrettoCache[model.Product](localCacheConfig)
-	if err != nil {
-		panic(err)
-	}
-	return NewMultiLevelCache(
-		ristrettoCache,
-		distributedCache,
-	)
+func (f *MultiLevelCacheFactory) Order(localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) (MultiLevelCache[model.Order], error) {
+	return For[model.Order](f, localCacheConfig, defaultTTLDistributedCache)
+}
+
+func (f *MultiLevelCacheFactory) Product(localCacheConfig *config.LocalCacheConfig, defaultTTLDistributedCache time.Duration) (MultiLevelCache[model.Product], error) {
+	return For[model.Product](f, localCacheConfig, defaultTTLDistributedCache)
 }
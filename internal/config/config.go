@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -14,9 +14,16 @@ type Config struct {
 	Database   DatabaseConfig   `mapstructure:"database"`
 	Redis      RedisConfig      `mapstructure:"redis"`
 	LocalCache LocalCacheConfig `mapstructure:"local_cache"`
+	Pagination PaginationConfig `mapstructure:"pagination"`
+	Outbox     OutboxConfig     `mapstructure:"outbox"`
+	Log        LogConfig        `mapstructure:"log"`
 }
 
 type DatabaseConfig struct {
+	// 数据库驱动: postgres (默认) 或 sqlite
+	Driver string `mapstructure:"driver"`
+	// SQLite 数据库文件路径,为空或 ":memory:" 时使用内存库;只在 driver 为 sqlite 时使用
+	Path            string `mapstructure:"path"`
 	Host            string `mapstructure:"host"`
 	Port            int    `mapstructure:"port"`
 	User            string `mapstructure:"user"`
@@ -29,6 +36,20 @@ type DatabaseConfig struct {
 	//Silent=1, Warn=2, Error=3, Info=4 (默认)
 	LogLevel int `mapstructure:"log_level"` // 日志级别 (示例: "info")
 	//SchemaFile      string `mapstructure:"schema_file"`       // schema.sql 文件路径
+	// 只读副本,未填写的连接字段沿用主库配置
+	Replicas []ReplicaConfig `mapstructure:"replicas"`
+	// 副本健康检查间隔 (建议值: 10s)
+	ReplicaHealthCheckInterval string `mapstructure:"replica_health_check_interval"`
+	// 仓储调用的慢查询告警阈值 (建议值: 200ms)
+	SlowQueryThreshold string `mapstructure:"slow_query_threshold"`
+}
+
+type ReplicaConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	DBName   string `mapstructure:"dbname"`
 }
 
 type RedisConfig struct {
@@ -49,6 +70,33 @@ type LocalCacheConfig struct {
 	DefaultTTL  int64 `mapstructure:"default_ttl"`
 }
 
+type PaginationConfig struct {
+	// 键集分页游标的签名密钥,多实例部署时必须一致
+	CursorSecret string `mapstructure:"cursor_secret"`
+}
+
+type OutboxConfig struct {
+	// 事件投递目标: redis (Redis Stream) 或 memory (进程内总线)
+	Publisher string `mapstructure:"publisher"`
+	// Redis Stream 名称前缀,完整名称为 前缀+主题
+	StreamPrefix string `mapstructure:"stream_prefix"`
+	// Stream 近似最大长度,0 表示不裁剪
+	StreamMaxLen int64 `mapstructure:"stream_max_len"`
+	// 无事件时的轮询间隔 (建议值: 1s)
+	PollInterval string `mapstructure:"poll_interval"`
+	BatchSize    int    `mapstructure:"batch_size"`
+	MaxAttempts  int    `mapstructure:"max_attempts"`
+}
+
+type LogConfig struct {
+	// 日志级别: debug、info (默认)、warn、error
+	Level string `mapstructure:"level"`
+	// 输出格式: json (默认) 或 text
+	Format string `mapstructure:"format"`
+	// 是否记录调用日志的源码位置
+	AddSource bool `mapstructure:"add_source"`
+}
+
 type JWTConfig struct {
 	SecretKey string `mapstructure:"secret_key"`
 	// 令牌过期时间(单位:小时)
@@ -78,7 +126,7 @@ func InitConfig() (*Config, error) {
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			fmt.Println("No config file found, using defaults")
+			slog.Warn("no config file found, using defaults")
 		} else {
 			return nil, fmt.Errorf("error reading config file: %w", err)
 		}
@@ -89,15 +137,11 @@ func InitConfig() (*Config, error) {
 
 	// 监控配置变化
 	viper.OnConfigChange(func(e fsnotify.Event) {
-		fmt.Println("Config file changed:", e.Name)
+		slog.Info("config file changed", slog.String("file", e.Name))
 	})
 	viper.WatchConfig()
 
-	cfg, err := parseConfig()
-	if err != nil {
-		log.Fatalf("解析配置文件失败: %v", err)
-	}
-	return cfg, nil
+	return parseConfig()
 }
 
 func parseConfig() (*Config, error) {
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"go-pattern/internal/errs"
+	service "go-pattern/internal/service/audit"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	auditService service.AuditService
+}
+
+func NewAuditController(auditService service.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// RegisterRoutes 审计接口只面向管理员,调用方负责在 group 上挂载鉴权与 Tenant 中间件,
+// 审计日志按租户隔离,请求上下文没有租户时返回 400
+func (ac *AuditController) RegisterRoutes(router *gin.Engine, handlers ...gin.HandlerFunc) {
+	group := router.Group("/api/admin/audit", handlers...)
+	{
+		group.GET("/:table/:entityId", ac.GetEntityHistory)
+	}
+}
+
+func (ac *AuditController) GetEntityHistory(c *gin.Context) {
+	entityID, err := strconv.ParseUint(c.Param("entityId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logs, err := ac.auditService.GetEntityHistory(c.Request.Context(), c.Param("table"), entityID)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": logs})
+}
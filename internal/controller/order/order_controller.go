@@ -1,10 +1,15 @@
 package controller
 
 import (
+	"encoding/json"
+	"go-pattern/internal/errs"
 	"go-pattern/internal/model"
 	service "go-pattern/internal/service/order"
+	"go-pattern/pkg/utils/etag"
+	"go-pattern/pkg/utils/fieldmask"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,8 +29,13 @@ func (oc *OrderController) RegisterRoutes(router *gin.Engine) {
 	{
 		group.POST("", oc.CreateOrder)
 		group.GET("/:orderId", oc.GetOrderByID)
+		group.GET("/:orderId/detail", oc.GetOrderDetail)
 		group.GET("", oc.GetOrdersByPage)
-		group.PATCH("/:orderId", oc.UpdateOrder)
+		group.GET("/latest", oc.GetLatestOrders)
+		group.GET("/stats/users", oc.CountOrdersByUser)
+		group.GET("/stats/daily", oc.GetDailyOrderTotals)
+		group.PUT("/:orderId", oc.UpdateOrder)
+		group.PATCH("/:orderId", oc.PatchOrder)
 		group.DELETE("/:orderId", oc.DeleteOrder)
 	}
 }
@@ -45,7 +55,8 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 		UserID:    req.UserID,
 		ProductID: req.ProductID,
 	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"code": http.StatusCreated, "msg": "success", "data": nil})
@@ -60,9 +71,28 @@ func (oc *OrderController) GetOrderByID(c *gin.Context) {
 	}
 	order, err := oc.orderService.GetOrder(c.Request.Context(), oid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", etag.Format(order.Version))
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": order})
+}
+
+// GetOrderDetail 返回订单及其用户与商品,共三次查询
+func (oc *OrderController) GetOrderDetail(c *gin.Context) {
+	oid, err := strconv.ParseUint(c.Param("orderId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	order, err := oc.orderService.GetOrderDetail(c.Request.Context(), oid)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag.Format(order.Version))
 	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": order})
 }
 
@@ -77,34 +107,158 @@ func (oc *OrderController) GetOrdersByPage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	orders, err := oc.orderService.GetOrdersByPage(c.Request.Context(), req.Page, req.Size)
+	page, err := oc.orderService.GetOrdersByPage(c.Request.Context(), req.Page, req.Size)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": page})
+}
+
+type CountOrdersByUserReq struct {
+	MinOrders int64 `form:"min_orders"`
+}
+
+func (oc *OrderController) CountOrdersByUser(c *gin.Context) {
+	var req CountOrdersByUserReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	counts, err := oc.orderService.CountOrdersByUser(c.Request.Context(), req.MinOrders)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": counts})
+}
+
+type GetDailyOrderTotalsReq struct {
+	Days int `form:"days" binding:"required,min=1,max=366"`
+}
+
+func (oc *OrderController) GetDailyOrderTotals(c *gin.Context) {
+	var req GetDailyOrderTotalsReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	since := time.Now().AddDate(0, 0, -req.Days)
+	totals, err := oc.orderService.GetDailyOrderTotals(c.Request.Context(), since)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": totals})
+}
+
+type GetLatestOrdersReq struct {
+	Cursor string `form:"cursor"`
+	Size   int    `form:"size" binding:"required,min=1,max=100"`
+}
+
+func (oc *OrderController) GetLatestOrders(c *gin.Context) {
+	var req GetLatestOrdersReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := oc.orderService.GetLatestOrders(c.Request.Context(), req.Cursor, req.Size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": orders})
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": page})
 }
 
-type UpdateUserReq struct {
-	OrderID   uint64 `json:"order_id"`
+type UpdateOrderReq struct {
 	UserID    uint64 `json:"user_id"`
 	ProductID uint64 `json:"product_id"`
+	// 期望的版本号,If-Match 请求头优先
+	Version uint64 `json:"version"`
 }
 
 func (oc *OrderController) UpdateOrder(c *gin.Context) {
-	var req UpdateUserReq
+	id, err := strconv.ParseUint(c.Param("orderId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var req UpdateOrderReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := oc.orderService.UpdateOrder(c.Request.Context(), &model.Order{
-		ID:        req.OrderID,
+	version, ok, err := etag.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		version = req.Version
+	}
+	// 整体更新必须带上期望的版本号,否则无法判断是否覆盖了他人的修改
+	if version == 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header or version is required"})
+		return
+	}
+	order := &model.Order{
+		ID:        id,
 		UserID:    req.UserID,
 		ProductID: req.ProductID,
-	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		Version:   version,
+	}
+	if err := oc.orderService.UpdateOrder(c.Request.Context(), order); err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag.Format(order.Version))
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": nil})
+}
+
+// orderPatchFields PATCH 请求中允许出现的JSON键及其对应的列
+var orderPatchFields = map[string]fieldmask.Field{
+	"user_id":    fieldmask.As[uint64]("user_id"),
+	"product_id": fieldmask.As[uint64]("product_id"),
+}
+
+// PatchOrder 只更新请求体中实际出现的字段,可以将字段置为零值
+func (oc *OrderController) PatchOrder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("orderId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var body map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mask, values, err := fieldmask.Parse(body, orderPatchFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok, err := etag.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		values["version"] = version
+	}
+	order, err := oc.orderService.UpdateOrderFields(c.Request.Context(), id, mask, values)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", etag.Format(order.Version))
 	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": nil})
 }
 
@@ -116,7 +270,8 @@ func (oc *OrderController) DeleteOrder(c *gin.Context) {
 		return
 	}
 	if err := oc.orderService.DeleteOrder(c.Request.Context(), oid); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": nil})
@@ -1,8 +1,12 @@
 package controller
 
 import (
+	"encoding/json"
+	"go-pattern/internal/errs"
 	"go-pattern/internal/model"
 	service "go-pattern/internal/service/user"
+	"go-pattern/pkg/utils/etag"
+	"go-pattern/pkg/utils/fieldmask"
 	"net/http"
 	"strconv"
 
@@ -25,7 +29,8 @@ func (uc *UserController) RegisterRoutes(router *gin.Engine) {
 		group.POST("", uc.CreateUser)
 		group.GET("/:userId", uc.GetUserByID)
 		group.GET("", uc.GetUsersByPage)
-		group.PATCH("/:userId", uc.UpdateUser)
+		group.PUT("/:userId", uc.UpdateUser)
+		group.PATCH("/:userId", uc.PatchUser)
 		group.DELETE("/:userId", uc.DeleteUser)
 	}
 }
@@ -45,7 +50,8 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		Name:  req.Username,
 		Email: req.Email,
 	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"code": http.StatusCreated, "msg": "success", "data": nil})
@@ -60,9 +66,11 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 	}
 	user, err := uc.userService.GetUser(c.Request.Context(), uid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", etag.Format(user.Version))
 	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": user})
 }
 
@@ -77,34 +85,99 @@ func (uc *UserController) GetUsersByPage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	users, err := uc.userService.GetUsersByPage(c.Request.Context(), req.Page, req.Size)
+	page, err := uc.userService.GetUsersByPage(c.Request.Context(), req.Page, req.Size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": users})
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": page})
 }
 
 type UpdateUserReq struct {
-	UserID   uint64 `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	// 期望的版本号,If-Match 请求头优先
+	Version uint64 `json:"version"`
 }
 
 func (uc *UserController) UpdateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	var req UpdateUserReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := uc.userService.UpdateUser(c.Request.Context(), &model.User{
-		ID:    req.UserID,
-		Name:  req.Username,
-		Email: req.Email,
-	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version, ok, err := etag.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		version = req.Version
+	}
+	// 整体更新必须带上期望的版本号,否则无法判断是否覆盖了他人的修改
+	if version == 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header or version is required"})
+		return
+	}
+	user := &model.User{
+		ID:      id,
+		Name:    req.Username,
+		Email:   req.Email,
+		Version: version,
+	}
+	if err := uc.userService.UpdateUser(c.Request.Context(), user); err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag.Format(user.Version))
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": nil})
+}
+
+// userPatchFields PATCH 请求中允许出现的JSON键及其对应的列
+var userPatchFields = map[string]fieldmask.Field{
+	"username": fieldmask.As[string]("name"),
+	"email":    fieldmask.As[string]("email"),
+}
+
+// PatchUser 只更新请求体中实际出现的字段,可以将字段置为零值
+func (uc *UserController) PatchUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var body map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mask, values, err := fieldmask.Parse(body, userPatchFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok, err := etag.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		values["version"] = version
+	}
+	user, err := uc.userService.UpdateUserFields(c.Request.Context(), id, mask, values)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", etag.Format(user.Version))
 	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": nil})
 }
 
@@ -116,7 +189,8 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 	if err := uc.userService.DeleteUser(c.Request.Context(), uid); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errs.HTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "msg": "success", "data": nil})
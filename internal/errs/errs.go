@@ -0,0 +1,39 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 跨层共享的哨兵错误,repo/cache/lock 返回的错误都会包装其中之一,
+// 上层通过 errors.Is 判断错误类别而不依赖具体实现
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrCacheMiss       = errors.New("cache miss")
+	ErrLockNotHeld     = errors.New("lock not held")
+	// ErrVersionConflict 乐观锁版本不一致,同时满足 errors.Is(err, ErrConflict)
+	ErrVersionConflict = fmt.Errorf("version mismatch: %w", ErrConflict)
+	// ErrPreconditionFailed 请求携带的前置条件无法满足,如 If-Match 中列出了多个版本
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// HTTPStatus 将错误映射为HTTP状态码,未归类的错误视为服务端错误
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrCacheMiss):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrLockNotHeld):
+		return http.StatusConflict
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
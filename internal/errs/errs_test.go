@@ -0,0 +1,34 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"invalid argument", fmt.Errorf("bad input: %w", ErrInvalidArgument), http.StatusBadRequest},
+		{"not found", fmt.Errorf("get user: %w", ErrNotFound), http.StatusNotFound},
+		{"cache miss", ErrCacheMiss, http.StatusNotFound},
+		{"conflict", ErrConflict, http.StatusConflict},
+		{"version conflict", fmt.Errorf("update: %w", ErrVersionConflict), http.StatusConflict},
+		{"lock not held", ErrLockNotHeld, http.StatusConflict},
+		{"precondition failed", fmt.Errorf("if-match: %w", ErrPreconditionFailed), http.StatusPreconditionFailed},
+		{"joined", errors.Join(errors.New("other"), ErrNotFound), http.StatusNotFound},
+		{"unclassified", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.want {
+				t.Fatalf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,18 +3,63 @@ package initializer
 import (
 	"fmt"
 	"go-pattern/internal/config"
+	"go-pattern/internal/logging"
+	"log/slog"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormLogger "gorm.io/gorm/logger"
 )
 
-func GormDB(config *config.DatabaseConfig) (*gorm.DB, error) {
+// GormDB 连接主库,gorm 的 SQL 日志同样写入 logger
+func GormDB(config *config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
 	if config == nil {
 		return nil, fmt.Errorf("数据库配置不能为空")
 	}
+	switch config.Driver {
+	case "", "postgres":
+		return openGormDB(config, postgresDialector(config, config.Host, config.Port, config.User, config.Password, config.DBName),
+			fmt.Sprintf("%s:%d", config.Host, config.Port), logger)
+	case "sqlite":
+		return openSQLite(config, logger)
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", config.Driver)
+	}
+}
 
+// GormReplicas 连接配置中的所有只读副本,未填写的连接字段沿用主库配置
+func GormReplicas(config *config.DatabaseConfig, logger *slog.Logger) ([]*gorm.DB, error) {
+	if config == nil {
+		return nil, fmt.Errorf("数据库配置不能为空")
+	}
+	if config.Driver == "sqlite" && len(config.Replicas) > 0 {
+		return nil, fmt.Errorf("只读副本只支持 postgres")
+	}
+	replicas := make([]*gorm.DB, 0, len(config.Replicas))
+	for i, replica := range config.Replicas {
+		user, password, dbName := replica.User, replica.Password, replica.DBName
+		if user == "" {
+			user, password = config.User, config.Password
+		}
+		if dbName == "" {
+			dbName = config.DBName
+		}
+		port := replica.Port
+		if port == 0 {
+			port = config.Port
+		}
+		db, err := openGormDB(config, postgresDialector(config, replica.Host, port, user, password, dbName),
+			fmt.Sprintf("%s:%d", replica.Host, port), logger)
+		if err != nil {
+			return nil, fmt.Errorf("无法连接到第%d个只读副本: %w", i+1, err)
+		}
+		replicas = append(replicas, db)
+	}
+	return replicas, nil
+}
+
+func postgresDialector(config *config.DatabaseConfig, host string, port int, user, password, dbName string) gorm.Dialector {
 	// 构建时区参数（默认Local）
 	timeZone := config.TimeZone
 	if timeZone == "" {
@@ -24,17 +69,33 @@ func GormDB(config *config.DatabaseConfig) (*gorm.DB, error) {
 	// 构建DSN连接字符串
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=%s",
-		config.Host,
-		config.User,
-		config.Password,
-		config.DBName,
-		config.Port,
+		host,
+		user,
+		password,
+		dbName,
+		port,
 		timeZone,
 	)
+	return postgres.Open(dsn)
+}
 
+func openGormDB(config *config.DatabaseConfig, dialector gorm.Dialector, name string, logger *slog.Logger) (*gorm.DB, error) {
+	logger = logging.OrDefault(logger)
+	// 慢查询阈值与仓储的慢调用告警共用配置
+	slowThreshold, err := time.ParseDuration(config.SlowQueryThreshold)
+	if err != nil || slowThreshold <= 0 {
+		slowThreshold = 200 * time.Millisecond
+	}
 	// 初始化 GORM 数据库连接
-	gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.LogLevel(config.LogLevel)), // 设置日志模式为 Info（可选 Silent、Warn、Error）
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		// 日志级别为 gorm 的 Silent(1)、Error(2)、Warn(3)、Info(4),Info 时记录每条 SQL
+		Logger: gormLogger.NewSlogLogger(logger, gormLogger.Config{
+			LogLevel:                  gormLogger.LogLevel(config.LogLevel),
+			SlowThreshold:             slowThreshold,
+			IgnoreRecordNotFoundError: true,
+		}),
+		// 将驱动错误(如唯一键冲突)转换为gorm统一错误,便于repo层归类
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("无法连接到数据库: %w", err)
@@ -86,6 +147,6 @@ func GormDB(config *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("数据库不可用: %w", err)
 	}
 
-	fmt.Println("ConnectGormDB successfully. 成功连接到数据库。")
+	logger.Info("database connected", slog.String("driver", dialector.Name()), slog.String("target", name))
 	return gormDB, nil
 }
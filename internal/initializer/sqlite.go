@@ -0,0 +1,81 @@
+package initializer
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"go-pattern/internal/config"
+	"log/slog"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqliteDriverName 写入时把时间统一转换为 UTC 的 SQLite 驱动
+const sqliteDriverName = "sqlite3_utc"
+
+func init() {
+	sql.Register(sqliteDriverName, &utcDriver{})
+}
+
+// utcDriver SQLite 没有时间类型,驱动把 time.Time 按其自身时区格式化为文本写入,
+// 读取时却把不带时区的文本(如列默认值)当作 UTC。写入前统一转换为 UTC,
+// 使库中的时间文本可以直接比较,也与数据库时钟 strftime('now') 一致
+type utcDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *utcDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &utcConn{SQLiteConn: conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type utcConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// CheckNamedValue 先按默认规则转换参数(包括 driver.Valuer,如 gorm.DeletedAt),再将时间转换为 UTC
+func (c *utcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC()
+	}
+	nv.Value = value
+	return nil
+}
+
+// openSQLite 打开 SQLite 数据库,适用于本地开发与测试。
+// 写事务以 BEGIN IMMEDIATE 开始,直接取得写锁,避免读后升级写锁时的死锁;
+// 时间以 UTC 保存,_loc=auto 在读取时转换为本地时间
+func openSQLite(config *config.DatabaseConfig, logger *slog.Logger) (*gorm.DB, error) {
+	path := config.Path
+	if path == "" || path == ":memory:" {
+		// 内存库随连接存在,只能使用一个永不关闭的连接,同一时刻只有一个事务
+		db, err := openGormDB(config, sqliteDialector("file::memory:?_loc=auto"), ":memory:", logger)
+		if err != nil {
+			return nil, err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("无法获取底层数据库实例: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		return db, nil
+	}
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate&_loc=auto", path)
+	return openGormDB(config, sqliteDialector(dsn), path, logger)
+}
+
+func sqliteDialector(dsn string) gorm.Dialector {
+	return sqlite.New(sqlite.Config{DriverName: sqliteDriverName, DSN: dsn})
+}
@@ -3,7 +3,9 @@ package lock
 import (
 	"context"
 	"fmt"
-	"log"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,11 +14,13 @@ import (
 
 type redisLock struct {
 	client *redis.Client
+	logger *slog.Logger
 }
 
-func NewRedisLock(client *redis.Client) Lock {
+func NewRedisLock(client *redis.Client, logger *slog.Logger) Lock {
 	return &redisLock{
 		client: client,
+		logger: logging.OrDefault(logger),
 	}
 }
 
@@ -38,10 +42,14 @@ func (r *redisLock) Release(ctx context.Context, key string, lockID string) erro
     end
     `
 	script := redis.NewScript(luaScript)
-	_, err := script.Run(ctx, r.client, []string{key}, lockID).Result()
+	deleted, err := script.Run(ctx, r.client, []string{key}, lockID).Int64()
 	if err != nil {
-		log.Printf("redis unlock error: %v", err)
+		r.logger.ErrorContext(ctx, "redis unlock error", slog.String("key", key), slog.Any("error", err))
 		return fmt.Errorf("redis unlock error: %w", err)
 	}
+	// 锁已过期或被其他持有者获取
+	if deleted == 0 {
+		return fmt.Errorf("redis unlock key %s: %w", key, errs.ErrLockNotHeld)
+	}
 	return nil
 }
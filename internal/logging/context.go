@@ -0,0 +1,29 @@
+package logging
+
+import "context"
+
+type requestIDKey struct{}
+
+type traceIDKey struct{}
+
+// WithRequestID 将请求ID写入上下文,之后以该上下文记录的日志都带有 request_id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFrom 读取上下文中的请求ID,不存在时返回空串
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithTraceID 将链路追踪ID(W3C traceparent 中的 trace-id)写入上下文
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFrom 读取上下文中的链路追踪ID,不存在时返回空串
+func TraceIDFrom(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
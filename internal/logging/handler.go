@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"go-pattern/internal/tenant"
+)
+
+// contextHandler 为每条带上下文的日志附加请求ID、链路ID与租户
+type contextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler 包装 handler,从记录的 ctx 中读取请求ID、链路ID与租户并作为属性输出
+func NewContextHandler(handler slog.Handler) slog.Handler {
+	return &contextHandler{Handler: handler}
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestID := RequestIDFrom(ctx); requestID != "" {
+			record.AddAttrs(slog.String("request_id", requestID))
+		}
+		if traceID := TraceIDFrom(ctx); traceID != "" {
+			record.AddAttrs(slog.String("trace_id", traceID))
+		}
+		if tenantID, ok := tenant.FromContext(ctx); ok {
+			record.AddAttrs(slog.Uint64("tenant_id", tenantID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"fmt"
+	"go-pattern/internal/config"
+	"io"
+	"log/slog"
+)
+
+// New 按配置创建日志器:格式为 json (默认) 或 text,级别为 debug/info/warn/error,默认 info。
+// 以 *Context 方法记录的日志会带上上下文中的请求ID、链路ID与租户
+func New(config *config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	if config == nil {
+		return nil, fmt.Errorf("日志配置不能为空")
+	}
+	var level slog.Level
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return nil, fmt.Errorf("无效的日志级别 %q: %w", config.Level, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level, AddSource: config.AddSource}
+
+	var handler slog.Handler
+	switch config.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("不支持的日志格式: %s", config.Format)
+	}
+	return slog.New(NewContextHandler(handler)), nil
+}
+
+// OrDefault logger 为 nil 时返回 slog.Default(),供可选注入日志器的组件使用
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"fmt"
+	"go-pattern/internal/audit"
+	"go-pattern/pkg/jwt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnonymousActor 请求未携带有效令牌时记录的操作者
+const AnonymousActor = "anonymous"
+
+// Actor 从 Bearer 令牌中解析操作者写入请求上下文,供仓储记录审计日志。
+// 令牌缺失或无效时记为 anonymous,鉴权由其他中间件负责
+func Actor(auth *jwt.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := AnonymousActor
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			if claims, err := auth.ParseToken(token); err == nil {
+				actor = fmt.Sprintf("user:%d", claims.UserID)
+			}
+		}
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"go-pattern/internal/logging"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求ID的请求头与响应头
+const RequestIDHeader = "X-Request-ID"
+
+// traceparentHeader W3C Trace Context 请求头,格式为 version-traceid-parentid-flags
+const traceparentHeader = "traceparent"
+
+// RequestID 为请求分配请求ID并写入请求上下文与响应头:沿用上游传入的 X-Request-ID,没有时生成一个。
+// 请求携带 traceparent 时同时记录其中的 trace-id,日志据此与链路追踪关联
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		if traceID, ok := parseTraceparent(c.GetHeader(traceparentHeader)); ok {
+			ctx = logging.WithTraceID(ctx, traceID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// parseTraceparent 取出 traceparent 中的 trace-id,格式不合法或全零时返回 false
+func parseTraceparent(header string) (string, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 {
+		return "", false
+	}
+	traceID := strings.ToLower(parts[1])
+	if strings.Trim(traceID, "0123456789abcdef") != "" || strings.Trim(traceID, "0") == "" {
+		return "", false
+	}
+	return traceID, true
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"go-pattern/internal/tenant"
+	"go-pattern/pkg/jwt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantHeader 受信任的调用方通过该请求头指定租户
+const TenantHeader = "X-Tenant-ID"
+
+type tenantOptions struct {
+	trustHeader func(c *gin.Context, claims *jwt.CustomClaims) bool
+}
+
+type TenantOption func(*tenantOptions)
+
+// WithTrustedHeader 允许 trusted 返回 true 的请求通过 X-Tenant-ID 指定租户,
+// 如内网服务的调用或不带租户声明的管理员令牌;claims 为令牌声明,请求未携带令牌时为 nil
+func WithTrustedHeader(trusted func(c *gin.Context, claims *jwt.CustomClaims) bool) TenantOption {
+	return func(o *tenantOptions) {
+		o.trustHeader = trusted
+	}
+}
+
+// Tenant 解析请求所属租户并写入请求上下文:Bearer 令牌无效或过期时返回 401,
+// 租户取自令牌中的 tenant_id 声明。X-Tenant-ID 请求头默认不被接受,
+// 只有 WithTrustedHeader 放行的请求可以用它指定租户;与令牌声明不一致时返回 403,
+// 两者都没有时返回 400
+func Tenant(auth *jwt.AuthService, opts ...TenantOption) gin.HandlerFunc {
+	var o tenantOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(c *gin.Context) {
+		var claims *jwt.CustomClaims
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			parsed, err := auth.ParseToken(token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+				return
+			}
+			claims = parsed
+		}
+
+		var tenantID uint64
+		if claims != nil {
+			tenantID = claims.TenantID
+		}
+		if header := c.GetHeader(TenantHeader); header != "" {
+			id, err := strconv.ParseUint(header, 10, 64)
+			if err != nil || id == 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + TenantHeader})
+				return
+			}
+			switch {
+			case tenantID != 0 && id != tenantID:
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "tenant does not match token"})
+				return
+			case tenantID == 0 && (o.trustHeader == nil || !o.trustHeader(c, claims)):
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": TenantHeader + " is not allowed"})
+				return
+			}
+			tenantID = id
+		}
+		if tenantID == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "tenant is required"})
+			return
+		}
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"go-pattern/internal/config"
+	"go-pattern/internal/tenant"
+	"go-pattern/pkg/jwt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := jwt.NewAuthService(config.JWTConfig{SecretKey: "secret", TokenExpire: 1}, "test", nil)
+	expired := jwt.NewAuthService(config.JWTConfig{SecretKey: "secret", TokenExpire: -1}, "test", nil)
+	token := func(a *jwt.AuthService, userID, tenantID uint64) string {
+		t.Helper()
+		s, err := a.GenerateTenantToken(userID, tenantID, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + s
+	}
+	// 测试中用户 1 视为管理员,可以通过请求头指定租户
+	admin := WithTrustedHeader(func(c *gin.Context, claims *jwt.CustomClaims) bool {
+		return claims != nil && claims.UserID == 1
+	})
+
+	tests := []struct {
+		name          string
+		opts          []TenantOption
+		authorization string
+		header        string
+		wantStatus    int
+		wantTenant    uint64
+	}{
+		{"tenant from token", nil, token(auth, 2, 7), "", http.StatusOK, 7},
+		{"header matches token", nil, token(auth, 2, 7), "7", http.StatusOK, 7},
+		{"header mismatches token", nil, token(auth, 2, 7), "8", http.StatusForbidden, 0},
+		{"expired token", nil, token(expired, 2, 7), "", http.StatusUnauthorized, 0},
+		{"malformed token", nil, "Bearer abc", "7", http.StatusUnauthorized, 0},
+		{"wrong secret", nil, token(jwt.NewAuthService(config.JWTConfig{SecretKey: "other", TokenExpire: 1}, "test", nil), 2, 7), "", http.StatusUnauthorized, 0},
+		{"header without token", nil, "", "7", http.StatusForbidden, 0},
+		{"header with tenantless token", nil, token(auth, 2, 0), "7", http.StatusForbidden, 0},
+		{"trusted header", []TenantOption{admin}, token(auth, 1, 0), "7", http.StatusOK, 7},
+		{"untrusted header", []TenantOption{admin}, token(auth, 2, 0), "7", http.StatusForbidden, 0},
+		{"trusted header mismatches token", []TenantOption{admin}, token(auth, 1, 8), "7", http.StatusForbidden, 0},
+		{"invalid header", []TenantOption{admin}, token(auth, 1, 0), "x", http.StatusBadRequest, 0},
+		{"no tenant", nil, token(auth, 2, 0), "", http.StatusBadRequest, 0},
+		{"anonymous", nil, "", "", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", Tenant(auth, tt.opts...), func(c *gin.Context) {
+				tenantID, _ := tenant.FromContext(c.Request.Context())
+				c.String(http.StatusOK, strconv.FormatUint(tenantID, 10))
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != strconv.FormatUint(tt.wantTenant, 10) {
+				t.Fatalf("tenant = %s, want %d", w.Body.String(), tt.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+	AuditActionUpsert  = "upsert"
+)
+
+// AuditLog 一次写操作对单条记录的审计快照,TenantID 为被修改记录所属的租户
+type AuditLog struct {
+	ID          uint64    `gorm:"primaryKey"`
+	TenantID    uint64    `gorm:"not null;default:0;index"`
+	EntityTable string    `gorm:"column:table_name;not null"`
+	EntityID    uint64    `gorm:"not null"`
+	Action      string    `gorm:"not null"`
+	Actor       string    `gorm:"not null"`
+	Before      JSON      `gorm:"column:before_data;type:jsonb"`
+	After       JSON      `gorm:"column:after_data;type:jsonb"`
+	CreatedAt   time.Time `gorm:"not null;default:current_timestamp"`
+}
+
+func (a *AuditLog) GetID() uint64 {
+	return a.ID
+}
+
+func (a *AuditLog) GetPrimaryKey() string {
+	return "id"
+}
+
+func (a *AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// JSON 以原样保存的JSON文档,空值对应数据库 NULL
+type JSON []byte
+
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *JSON) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("unsupported JSON source type %T", src)
+	}
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
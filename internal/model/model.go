@@ -6,6 +6,13 @@ type Model interface {
 	TableName() string
 }
 
+// VersionedModel 声明了 version 列的模型,Update 时启用乐观锁:
+// 仅当数据库中的版本与模型版本一致时才更新,并将版本号加一
+type VersionedModel interface {
+	GetVersion() uint64
+	SetVersion(version uint64)
+}
+
 // PointerModel 定义了一个指针类型的模型接口
 // PointerModel defines a pointer type model interface.
 // 它要求T必须是一个指针类型,并嵌入了Model接口
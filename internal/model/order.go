@@ -1,13 +1,25 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Order struct {
 	ID        uint64    `gorm:"primaryKey" redis:"id"`
+	TenantID  uint64    `gorm:"not null;default:0;index" redis:"tenant_id"`
 	UserID    uint64    `gorm:"not null" redis:"user_id"`
 	ProductID uint64    `gorm:"not null" redis:"product_id"`
+	Version   uint64    `gorm:"not null;default:1" redis:"version"`
 	CreatedAt time.Time `gorm:"not null;default:current_timestamp"`
 	UpdatedAt time.Time `gorm:"not null;default:current_timestamp"`
+	// 订单需要保留用于对账,使用软删除
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	// 关联只在 WithPreload 时加载,写操作不会级联保存
+	User    *User    `gorm:"foreignKey:UserID" json:"User,omitempty"`
+	Product *Product `gorm:"foreignKey:ProductID" json:"Product,omitempty"`
 }
 
 func (o *Order) GetID() uint64 {
@@ -21,3 +33,11 @@ func (o *Order) GetPrimaryKey() string {
 func (o *Order) TableName() string {
 	return "orders"
 }
+
+func (o *Order) GetVersion() uint64 {
+	return o.Version
+}
+
+func (o *Order) SetVersion(version uint64) {
+	o.Version = version
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	// OutboxStatusDead 超过最大重试次数,不再投递,需要人工处理
+	OutboxStatusDead = "dead"
+)
+
+// OutboxEvent 与业务写操作在同一事务中写入的领域事件,由中继进程在提交后投递
+type OutboxEvent struct {
+	ID            uint64     `gorm:"primaryKey" json:"id"`
+	Topic         string     `gorm:"not null" json:"topic"`
+	AggregateID   uint64     `gorm:"not null" json:"aggregate_id"`
+	Payload       JSON       `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string     `gorm:"not null;default:pending" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;default:current_timestamp" json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null;default:current_timestamp" json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+func (o *OutboxEvent) GetID() uint64 {
+	return o.ID
+}
+
+func (o *OutboxEvent) GetPrimaryKey() string {
+	return "id"
+}
+
+func (o *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
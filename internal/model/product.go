@@ -4,12 +4,17 @@ import "time"
 
 type Product struct {
 	ID          uint64    `gorm:"primaryKey" redis:"id"`
+	TenantID    uint64    `gorm:"not null;default:0;index" redis:"tenant_id"`
 	Name        string    `gorm:"not null" redis:"name"`
 	Description string    `gorm:"type:text" redis:"description"`
 	Price       float64   `gorm:"not null" redis:"price"`
 	Quantity    uint64    `gorm:"not null" redis:"quantity"`
+	Version     uint64    `gorm:"not null;default:1" redis:"version"`
 	CreatedAt   time.Time `gorm:"not null;default:current_timestamp"`
 	UpdatedAt   time.Time `gorm:"not null;default:current_timestamp"`
+
+	// Orders 只在 WithPreload("Orders") 时加载
+	Orders []*Order `gorm:"foreignKey:ProductID" json:"Orders,omitempty"`
 }
 
 func (p *Product) GetID() uint64 {
@@ -23,3 +28,11 @@ func (p *Product) GetPrimaryKey() string {
 func (p *Product) TableName() string {
 	return "products"
 }
+
+func (p *Product) GetVersion() uint64 {
+	return p.Version
+}
+
+func (p *Product) SetVersion(version uint64) {
+	p.Version = version
+}
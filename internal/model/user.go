@@ -4,10 +4,15 @@ import "time"
 
 type User struct {
 	ID        uint64    `gorm:"primaryKey" redis:"id"`
+	TenantID  uint64    `gorm:"not null;default:0;uniqueIndex:idx_users_tenant_email" redis:"tenant_id"`
 	Name      string    `gorm:"not null" redis:"name"`
-	Email     string    `gorm:"not null;unique" redis:"email"`
+	Email     string    `gorm:"not null;uniqueIndex:idx_users_tenant_email" redis:"email"`
+	Version   uint64    `gorm:"not null;default:1" redis:"version"`
 	CreatedAt time.Time `gorm:"not null;default:current_timestamp"`
 	UpdatedAt time.Time `gorm:"not null;default:current_timestamp"`
+
+	// Orders 只在 WithPreload("Orders") 时加载
+	Orders []*Order `gorm:"foreignKey:UserID" json:"Orders,omitempty"`
 }
 
 func (u *User) GetID() uint64 {
@@ -21,3 +26,11 @@ func (u *User) GetPrimaryKey() string {
 func (u *User) TableName() string {
 	return "users"
 }
+
+func (u *User) GetVersion() uint64 {
+	return u.Version
+}
+
+func (u *User) SetVersion(version uint64) {
+	u.Version = version
+}
@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-pattern/internal/model"
+	"sync"
+)
+
+// Publisher 事件投递目标。中继保证至少投递一次,消费方需按事件ID去重
+type Publisher interface {
+	Publish(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// Handler 进程内事件处理函数
+type Handler func(ctx context.Context, event *model.OutboxEvent) error
+
+// Bus 进程内事件总线,按主题同步调用订阅者,任一订阅者失败时整条事件重试
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+func (b *Bus) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	b.mu.RLock()
+	handlers := b.handlers[event.Topic]
+	b.mu.RUnlock()
+
+	var errList []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	if err := errors.Join(errList...); err != nil {
+		return fmt.Errorf("publish event %d to topic %s failed: %w", event.ID, event.Topic, err)
+	}
+	return nil
+}
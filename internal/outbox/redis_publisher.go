@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamPublisher 将事件追加到 Redis Stream,每个主题对应一个 stream: prefix+topic
+type RedisStreamPublisher struct {
+	client *redis.Client
+	prefix string
+	// maxLen 大于0时近似裁剪 stream 长度
+	maxLen int64
+}
+
+func NewRedisStreamPublisher(client *redis.Client, prefix string, maxLen int64) *RedisStreamPublisher {
+	return &RedisStreamPublisher{client: client, prefix: prefix, maxLen: maxLen}
+}
+
+func (p *RedisStreamPublisher) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	args := &redis.XAddArgs{
+		Stream: p.prefix + event.Topic,
+		Values: map[string]any{
+			"event_id":     event.ID,
+			"topic":        event.Topic,
+			"aggregate_id": event.AggregateID,
+			"payload":      string(event.Payload),
+		},
+	}
+	if p.maxLen > 0 {
+		args.MaxLen = p.maxLen
+		args.Approx = true
+	}
+	if err := p.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("publish event %d to stream %s failed: %w", event.ID, args.Stream, err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-pattern/internal/logging"
+	"go-pattern/internal/model"
+	repo "go-pattern/internal/repo/factory"
+	outboxRepo "go-pattern/internal/repo/outbox"
+)
+
+type relayOptions struct {
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	lease        time.Duration
+	logger       *slog.Logger
+}
+
+type RelayOption func(*relayOptions)
+
+// WithPollInterval 没有待投递事件时的轮询间隔
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithBatchSize 每次领取并投递的最大事件数
+func WithBatchSize(size int) RelayOption {
+	return func(o *relayOptions) {
+		o.batchSize = size
+	}
+}
+
+// WithMaxAttempts 超过该投递次数的事件标记为 dead
+func WithMaxAttempts(attempts int) RelayOption {
+	return func(o *relayOptions) {
+		o.maxAttempts = attempts
+	}
+}
+
+// WithBackoff 失败重试的指数退避:第n次失败后等待 base*2^(n-1),不超过 max
+func WithBackoff(base, max time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		o.baseBackoff = base
+		o.maxBackoff = max
+	}
+}
+
+// WithLease 领取事件的租约时长,需大于投递一批事件的耗时。
+// 中继在租约内崩溃或未能记录投递结果时,事件在租约到期后被重新投递
+func WithLease(lease time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		o.lease = lease
+	}
+}
+
+// WithLogger 记录投递失败的 logger,默认使用 slog 默认 logger
+func WithLogger(logger *slog.Logger) RelayOption {
+	return func(o *relayOptions) {
+		o.logger = logger
+	}
+}
+
+// Relay 轮询发件箱并投递事件。多个中继实例可以并行运行,
+// 领取时 FOR UPDATE SKIP LOCKED 并写入租约,保证租约内同一事件只被一个实例处理。
+// 投递保证至少一次:投递成功但记录结果失败、或租约到期前未记录结果的事件会被再次投递
+type Relay struct {
+	factory   repo.RepoFactory
+	publisher Publisher
+	opts      relayOptions
+}
+
+func NewRelay(factory repo.RepoFactory, publisher Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		factory:   factory,
+		publisher: publisher,
+		opts: relayOptions{
+			pollInterval: time.Second,
+			batchSize:    100,
+			maxAttempts:  10,
+			baseBackoff:  time.Second,
+			maxBackoff:   5 * time.Minute,
+			lease:        time.Minute,
+		},
+	}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	r.opts.logger = logging.OrDefault(r.opts.logger)
+	return r
+}
+
+// Run 持续投递直到 ctx 取消;一批取满时立即处理下一批
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		delivered, err := r.RunOnce(ctx)
+		if err != nil {
+			r.opts.logger.ErrorContext(ctx, "outbox relay failed", slog.Any("error", err))
+		}
+		if err == nil && delivered == r.opts.batchSize {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.opts.pollInterval):
+		}
+	}
+}
+
+// RunOnce 在一个短事务中领取一批到期事件并写入租约,提交后在事务外逐条投递,返回处理的事件数。
+// 每条事件的投递结果单独记录,一条记录失败不影响同批其他事件;
+// 投递失败的事件按退避时间重新排期,达到最大次数后标记为 dead
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	store := r.factory.Outbox()
+	var events []*model.OutboxEvent
+	err := r.factory.RunInTx(ctx, func(ctx context.Context) error {
+		var err error
+		if events, err = store.ClaimBatch(ctx, r.opts.batchSize); err != nil {
+			return err
+		}
+		return store.Lease(ctx, r.opts.lease, eventIDs(events)...)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("relay outbox events failed: %w", err)
+	}
+
+	for _, event := range events {
+		if err := r.deliver(ctx, store, event); err != nil {
+			// 租约到期后事件会被再次投递
+			r.opts.logger.ErrorContext(ctx, "outbox event mark failed",
+				slog.Uint64("event_id", event.ID),
+				slog.String("topic", event.Topic),
+				slog.Any("error", err))
+		}
+	}
+	return len(events), nil
+}
+
+// deliver 投递一条事件并记录结果,返回记录结果时的错误
+func (r *Relay) deliver(ctx context.Context, store outboxRepo.OutboxRepo, event *model.OutboxEvent) error {
+	publishErr := r.publisher.Publish(ctx, event)
+	if publishErr == nil {
+		return store.MarkDelivered(ctx, event.ID)
+	}
+	attempts := event.Attempts + 1
+	level := slog.LevelWarn
+	if attempts >= r.opts.maxAttempts {
+		level = slog.LevelError
+	}
+	r.opts.logger.Log(ctx, level, "outbox event publish failed",
+		slog.Uint64("event_id", event.ID),
+		slog.String("topic", event.Topic),
+		slog.Int("attempt", attempts),
+		slog.Any("error", publishErr))
+	if attempts >= r.opts.maxAttempts {
+		return store.MarkDead(ctx, event.ID, publishErr)
+	}
+	return store.MarkFailed(ctx, event.ID, publishErr, r.backoff(attempts))
+}
+
+func eventIDs(events []*model.OutboxEvent) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.opts.baseBackoff
+	for i := 1; i < attempts && backoff < r.opts.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, r.opts.maxBackoff)
+}
@@ -0,0 +1,16 @@
+package registry
+
+import (
+	"go-pattern/internal/model"
+	"go-pattern/internal/table"
+)
+
+// 新增模型只需在这里注册一次:Migrate 会为其建表,
+// repo.For 与 cache.For 即可为其创建仓储与多级缓存
+func init() {
+	Register[model.User]("用户", table.NewUserTable)
+	Register[model.Product]("商品", table.NewProductTable)
+	Register[model.Order]("订单", table.NewOrderTable)
+	Register[model.AuditLog]("审计日志", table.NewAuditLogTable)
+	Register[model.OutboxEvent]("事件发件箱", table.NewOutboxEventTable)
+}
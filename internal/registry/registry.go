@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"fmt"
+	"go-pattern/internal/logging"
+	"go-pattern/internal/model"
+	"go-pattern/internal/table"
+	"log/slog"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Model 注册的模型:表名、用于错误信息的名称与建表函数
+type Model struct {
+	// Name 模型名称,如 "用户"
+	Name  string
+	Table string
+	// Migrate 创建或升级模型的表,须可重复执行
+	Migrate func(db *gorm.DB) error
+}
+
+var (
+	mu      sync.RWMutex
+	models  []Model
+	byTable = map[string]int{}
+)
+
+// Register 注册模型 T,同一张表重复注册时 panic。
+// 建表按注册顺序执行,被其他表引用的表须先注册
+func Register[T any, PT model.PointerModel[T]](name string, migrate func(db *gorm.DB) error) {
+	tableName := PT(new(T)).TableName()
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := byTable[tableName]; ok {
+		panic(fmt.Sprintf("registry: model for table %s registered twice", tableName))
+	}
+	byTable[tableName] = len(models)
+	models = append(models, Model{Name: name, Table: tableName, Migrate: migrate})
+}
+
+// Lookup 返回模型 T 的注册信息
+func Lookup[T any, PT model.PointerModel[T]]() (Model, bool) {
+	tableName := PT(new(T)).TableName()
+	mu.RLock()
+	defer mu.RUnlock()
+	i, ok := byTable[tableName]
+	if !ok {
+		return Model{}, false
+	}
+	return models[i], true
+}
+
+// MustLookup 同 Lookup,模型未注册时 panic,供泛型访问器在构建仓储与缓存时校验
+func MustLookup[T any, PT model.PointerModel[T]]() Model {
+	m, ok := Lookup[T, PT]()
+	if !ok {
+		panic(fmt.Sprintf("registry: model %T is not registered", PT(new(T))))
+	}
+	return m
+}
+
+// Models 按注册顺序返回全部模型
+func Models() []Model {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Model(nil), models...)
+}
+
+// Migrate 创建公共的触发器函数后按注册顺序为每个模型建表
+func Migrate(db *gorm.DB, logger *slog.Logger) error {
+	if err := table.NewUpdateAtTrigger(db); err != nil {
+		return fmt.Errorf("创建触发器函数错误: %w", err)
+	}
+	all := Models()
+	for _, m := range all {
+		if err := m.Migrate(db); err != nil {
+			return fmt.Errorf("创建%s表错误: %w", m.Name, err)
+		}
+	}
+	logging.OrDefault(logger).Info("tables initialized", slog.String("dialect", db.Dialector.Name()), slog.Int("models", len(all)))
+	return nil
+}
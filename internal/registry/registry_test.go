@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"go-pattern/internal/model"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID uint64
+}
+
+func (w *widget) GetID() uint64         { return w.ID }
+func (w *widget) GetPrimaryKey() string { return "id" }
+func (w *widget) TableName() string     { return "registry_test_widgets" }
+
+type gadget struct{ widget }
+
+func (g *gadget) TableName() string { return "registry_test_gadgets" }
+
+func TestRegister(t *testing.T) {
+	migrate := func(db *gorm.DB) error { return nil }
+	Register[widget]("部件", migrate)
+
+	tests := []struct {
+		name      string
+		lookup    func() (Model, bool)
+		wantOK    bool
+		wantTable string
+	}{
+		{"registered", Lookup[widget], true, "registry_test_widgets"},
+		{"built-in", Lookup[model.User], true, "users"},
+		{"unregistered", Lookup[gadget], false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := tt.lookup()
+			if ok != tt.wantOK || m.Table != tt.wantTable {
+				t.Fatalf("Lookup() = %+v, %v, want table %q, %v", m, ok, tt.wantTable, tt.wantOK)
+			}
+		})
+	}
+
+	models := Models()
+	if last := models[len(models)-1]; last.Table != "registry_test_widgets" || last.Name != "部件" {
+		t.Fatalf("Models() must keep registration order, last = %+v", last)
+	}
+	expectPanic(t, "register twice", func() { Register[widget]("部件", migrate) })
+	expectPanic(t, "must lookup unregistered", func() { MustLookup[gadget]() })
+}
+
+func expectPanic(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("%s: expected panic", name)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,13 @@
+package repo
+
+import (
+	"go-pattern/internal/model"
+
+	genericRepo "go-pattern/internal/repo/generic"
+)
+
+// NewMemoryAuditRepo 内存实现的审计日志仓储,读取内存仓储在开启审计时写入的日志
+func NewMemoryAuditRepo(db *genericRepo.MemoryDB, opts ...genericRepo.Option) AuditRepo {
+	opts = append(opts, genericRepo.WithAudit(false))
+	return NewAuditRepoFrom(genericRepo.NewMemoryRepo[model.AuditLog](db, opts...))
+}
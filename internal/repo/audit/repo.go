@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"go-pattern/internal/model"
+
+	genericRepo "go-pattern/internal/repo/generic"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepo interface {
+	genericRepo.GenericRepo[model.AuditLog, *model.AuditLog]
+	// ListByEntity 查询单条记录的审计历史,按时间倒序;只返回上下文中租户的审计日志
+	ListByEntity(ctx context.Context, table string, entityID uint64) ([]*model.AuditLog, error)
+}
+
+type auditRepo struct {
+	genericRepo.GenericRepo[model.AuditLog, *model.AuditLog]
+}
+
+// NewAuditRepo 审计日志本身的写入不再记录审计
+func NewAuditRepo(db *gorm.DB, opts ...genericRepo.Option) AuditRepo {
+	opts = append(opts, genericRepo.WithAudit(false))
+	return NewAuditRepoFrom(genericRepo.NewGenericRepo[model.AuditLog](db, opts...))
+}
+
+// NewAuditRepoFrom 基于已构建的通用仓储创建仓储,repo 须关闭审计
+func NewAuditRepoFrom(repo genericRepo.GenericRepo[model.AuditLog, *model.AuditLog]) AuditRepo {
+	return &auditRepo{
+		GenericRepo: repo,
+	}
+}
+
+func (a *auditRepo) ListByEntity(ctx context.Context, table string, entityID uint64) ([]*model.AuditLog, error) {
+	spec := genericRepo.NewSpec(
+		genericRepo.Eq("table_name", table),
+		genericRepo.Eq("entity_id", entityID),
+	).OrderBy(genericRepo.Desc("created_at"), genericRepo.Desc("id"))
+	return a.Find(ctx, spec)
+}
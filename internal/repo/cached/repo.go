@@ -0,0 +1,227 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cache "go-pattern/internal/cache/multilevel"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
+	"go-pattern/internal/model"
+	genericRepo "go-pattern/internal/repo/generic"
+	"go-pattern/internal/tenant"
+	"log/slog"
+	"slices"
+)
+
+// CachedRepo 为任意 GenericRepo 增加多级缓存的装饰器:
+// GetByID/GetByIDs 优先读缓存并在未命中时回填,写操作后删除受影响的缓存
+type CachedRepo[T any, PT model.PointerModel[T]] interface {
+	genericRepo.GenericRepo[T, PT]
+	// Invalidate 删除指定ID的缓存,供仓储之外的写路径使用;在事务中时于最外层事务提交后删除
+	Invalidate(ctx context.Context, ids ...uint64) error
+}
+
+type cachedRepo[T any, PT model.PointerModel[T]] struct {
+	genericRepo.GenericRepo[T, PT]
+	cache cache.MultiLevelCache[T]
+	// readThrough 为 false 时读操作绕过缓存,只做失效
+	readThrough bool
+	// hooks 绑定在事务上时事务的提交回调,为 nil 时从上下文读取
+	hooks *genericRepo.TxHooks
+	// logger 记录被吞掉的缓存故障,缓存故障不影响读写结果
+	logger *slog.Logger
+}
+
+func NewCachedRepo[T any, PT model.PointerModel[T]](repo genericRepo.GenericRepo[T, PT], cache cache.MultiLevelCache[T], logger *slog.Logger) CachedRepo[T, PT] {
+	return &cachedRepo[T, PT]{GenericRepo: repo, cache: cache, readThrough: true, logger: logging.OrDefault(logger)}
+}
+
+// NewInvalidatingRepo 只在写操作后失效缓存、读操作直接访问数据库的装饰器,
+// 用于事务内:避免把尚未提交的数据写入缓存;失效在 hooks 所属的事务提交后执行
+func NewInvalidatingRepo[T any, PT model.PointerModel[T]](repo genericRepo.GenericRepo[T, PT], cache cache.MultiLevelCache[T], hooks *genericRepo.TxHooks, logger *slog.Logger) CachedRepo[T, PT] {
+	return &cachedRepo[T, PT]{GenericRepo: repo, cache: cache, hooks: hooks, logger: logging.OrDefault(logger)}
+}
+
+func (c *cachedRepo[T, PT]) key(id uint64) string {
+	var model T
+	return fmt.Sprintf("%s:%d", PT(&model).TableName(), id)
+}
+
+// Invalidate 在事务中时推迟到提交后执行:提交前删除的缓存可能被并发读取以旧数据回填,直到过期前都是脏数据。
+// 推迟执行时删除失败只记录日志
+func (c *cachedRepo[T, PT]) Invalidate(ctx context.Context, ids ...uint64) error {
+	hooks := c.hooks
+	if hooks == nil {
+		hooks, _ = genericRepo.TxHooksFrom(ctx)
+	}
+	if hooks == nil {
+		return c.invalidate(ctx, ids...)
+	}
+	ids = slices.Clone(ids)
+	ctx = context.WithoutCancel(ctx)
+	hooks.Add(func() { c.invalidate(ctx, ids...) })
+	return nil
+}
+
+func (c *cachedRepo[T, PT]) invalidate(ctx context.Context, ids ...uint64) error {
+	var errList []error
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+		if err := c.cache.Del(ctx, c.key(id)); err != nil {
+			c.logger.ErrorContext(ctx, "invalidate cache failed", slog.String("key", c.key(id)), slog.Any("error", err))
+			errList = append(errList, err)
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// lookup 读取缓存,未命中或缓存故障都视为未命中
+func (c *cachedRepo[T, PT]) lookup(ctx context.Context, id uint64) (PT, bool) {
+	value, err := c.cache.GetPointer(ctx, c.key(id))
+	if err != nil {
+		if !errors.Is(err, errs.ErrCacheMiss) {
+			c.logger.WarnContext(ctx, "get cache failed", slog.String("key", c.key(id)), slog.Any("error", err))
+		}
+		return nil, false
+	}
+	return PT(value), true
+}
+
+func (c *cachedRepo[T, PT]) populate(ctx context.Context, ptrModel PT) {
+	if err := c.cache.SetWithDefaultTTL(ctx, c.key(ptrModel.GetID()), *ptrModel); err != nil {
+		c.logger.WarnContext(ctx, "set cache failed", slog.String("key", c.key(ptrModel.GetID())), slog.Any("error", err))
+	}
+}
+
+// readsCache 上下文中有事务时读操作绕过缓存,避免把未提交的数据写入缓存;
+// 跳过租户隔离的上下文没有租户前缀,同样绕过缓存,避免跨租户共享缓存项
+func (c *cachedRepo[T, PT]) readsCache(ctx context.Context) bool {
+	return c.readThrough && !genericRepo.InTx(ctx) && !tenant.Bypassed(ctx)
+}
+
+// 带查询选项(如预加载关联)的读取绕过缓存,缓存中只保存模型本身
+func (c *cachedRepo[T, PT]) GetByID(ctx context.Context, id uint64, opts ...genericRepo.QueryOption) (PT, error) {
+	if !c.readsCache(ctx) || len(opts) > 0 {
+		return c.GenericRepo.GetByID(ctx, id, opts...)
+	}
+	if ptrModel, ok := c.lookup(ctx, id); ok {
+		return ptrModel, nil
+	}
+	ptrModel, err := c.GenericRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.populate(ctx, ptrModel)
+	return ptrModel, nil
+}
+
+func (c *cachedRepo[T, PT]) GetByIDs(ctx context.Context, ids []uint64, opts ...genericRepo.QueryOption) ([]PT, error) {
+	if !c.readsCache(ctx) || len(ids) == 0 || len(opts) > 0 {
+		return c.GenericRepo.GetByIDs(ctx, ids, opts...)
+	}
+	found := make(map[uint64]PT, len(ids))
+	missing := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if ptrModel, ok := c.lookup(ctx, id); ok {
+			found[id] = ptrModel
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		ptrModels, err := c.GenericRepo.GetByIDs(ctx, missing)
+		if err != nil && !(errors.Is(err, errs.ErrNotFound) && len(found) > 0) {
+			return nil, err
+		}
+		for _, ptrModel := range ptrModels {
+			found[ptrModel.GetID()] = ptrModel
+			c.populate(ctx, ptrModel)
+		}
+	}
+	// 按入参顺序返回,不存在的ID被跳过
+	ptrModels := make([]PT, 0, len(found))
+	for _, id := range ids {
+		if ptrModel, ok := found[id]; ok {
+			ptrModels = append(ptrModels, ptrModel)
+			delete(found, id)
+		}
+	}
+	return ptrModels, nil
+}
+
+func (c *cachedRepo[T, PT]) Update(ctx context.Context, ptrModel PT) error {
+	err := c.GenericRepo.Update(ctx, ptrModel)
+	if ptrModel != nil {
+		c.Invalidate(ctx, ptrModel.GetID())
+	}
+	return err
+}
+
+func (c *cachedRepo[T, PT]) UpdateFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) error {
+	err := c.GenericRepo.UpdateFields(ctx, id, fieldMask, values)
+	c.Invalidate(ctx, id)
+	return err
+}
+
+func (c *cachedRepo[T, PT]) Upsert(ctx context.Context, ptrModel PT, conflictColumns []string, updateColumns []string) (genericRepo.UpsertResult, error) {
+	result, err := c.GenericRepo.Upsert(ctx, ptrModel, conflictColumns, updateColumns)
+	if ptrModel != nil {
+		c.invalidateUpserted(ctx, []PT{ptrModel}, updateColumns)
+	}
+	return result, err
+}
+
+func (c *cachedRepo[T, PT]) UpsertInBatches(ctx context.Context, ptrModels []PT, batchSize int, conflictColumns []string, updateColumns []string) (genericRepo.UpsertResult, error) {
+	result, err := c.GenericRepo.UpsertInBatches(ctx, ptrModels, batchSize, conflictColumns, updateColumns)
+	c.invalidateUpserted(ctx, ptrModels, updateColumns)
+	return result, err
+}
+
+// invalidateUpserted DO NOTHING(updateColumns 为空)不会改动已存在的行,不需要失效,
+// 此时被跳过的行也不会回填主键;DO UPDATE 时仓储为每一行回填主键,包括冲突后被更新的行
+func (c *cachedRepo[T, PT]) invalidateUpserted(ctx context.Context, ptrModels []PT, updateColumns []string) {
+	if len(updateColumns) == 0 {
+		return
+	}
+	ids := make([]uint64, 0, len(ptrModels))
+	for _, ptrModel := range ptrModels {
+		ids = append(ids, ptrModel.GetID())
+	}
+	c.Invalidate(ctx, ids...)
+}
+
+func (c *cachedRepo[T, PT]) DeleteByID(ctx context.Context, id uint64) error {
+	err := c.GenericRepo.DeleteByID(ctx, id)
+	c.Invalidate(ctx, id)
+	return err
+}
+
+func (c *cachedRepo[T, PT]) DeleteByIDs(ctx context.Context, ids []uint64) error {
+	err := c.GenericRepo.DeleteByIDs(ctx, ids)
+	c.Invalidate(ctx, ids...)
+	return err
+}
+
+// 批量写由仓储回报命中记录的ID后失效缓存
+func (c *cachedRepo[T, PT]) UpdateWhere(ctx context.Context, filter *genericRepo.Spec, changes map[string]any, opts ...genericRepo.BulkOption) (int64, error) {
+	return c.GenericRepo.UpdateWhere(ctx, filter, changes, append(opts, c.invalidateAffected(ctx))...)
+}
+
+func (c *cachedRepo[T, PT]) DeleteWhere(ctx context.Context, filter *genericRepo.Spec, opts ...genericRepo.BulkOption) (int64, error) {
+	return c.GenericRepo.DeleteWhere(ctx, filter, append(opts, c.invalidateAffected(ctx))...)
+}
+
+func (c *cachedRepo[T, PT]) invalidateAffected(ctx context.Context) genericRepo.BulkOption {
+	return genericRepo.WithAffectedIDs(func(ids []uint64) {
+		c.Invalidate(ctx, ids...)
+	})
+}
+
+func (c *cachedRepo[T, PT]) Restore(ctx context.Context, id uint64) error {
+	err := c.GenericRepo.Restore(ctx, id)
+	c.Invalidate(ctx, id)
+	return err
+}
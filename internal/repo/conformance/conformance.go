@@ -0,0 +1,120 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	repoFactory "go-pattern/internal/repo/factory"
+	"go-pattern/internal/tenant"
+)
+
+// Run 对 newFactory 创建的 RepoFactory 运行一致性测试,GORM 实现与内存实现都必须通过,
+// 保证服务层在内存实现上的单元测试结论对生产环境同样成立。
+// 每个子测试都会调用 newFactory,返回的工厂必须指向一个已完成迁移的空库,且未开启缓存
+func Run(t *testing.T, newFactory func(t *testing.T) repoFactory.RepoFactory, opts ...Option) {
+	o := options{concurrentTx: true, uniqueIDs: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, f repoFactory.RepoFactory)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"UniqueConstraint", testUniqueConstraint},
+		{"OptimisticLock", testOptimisticLock},
+		{"UpdateFields", testUpdateFields},
+		{"SoftDelete", testSoftDelete},
+		{"Upsert", testUpsert},
+		{"FindAndCount", testFindAndCount},
+		{"PageAndCursor", testPageAndCursor},
+		{"Keyset", testKeyset},
+		{"Iterate", testIterate},
+		{"Aggregate", testAggregate},
+		{"Bulk", testBulk},
+		{"TenantIsolation", testTenantIsolation},
+		{"Preload", testPreload},
+		{"Transaction", func(t *testing.T, f repoFactory.RepoFactory) { testTransaction(t, f, o.uniqueIDs) }},
+		{"RunInTx", testRunInTx},
+		{"ReduceQuantity", testReduceQuantity},
+		{"Outbox", func(t *testing.T, f repoFactory.RepoFactory) { testOutbox(t, f, o.concurrentTx) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newFactory(t))
+		})
+	}
+}
+
+// Option 调整一致性测试覆盖的范围
+type Option func(*options)
+
+type options struct {
+	concurrentTx bool
+	uniqueIDs    bool
+}
+
+// WithoutConcurrentTx 后端同一时刻只能有一个写事务(如 SQLite)时,需要两个事务并发的用例改为依次执行
+func WithoutConcurrentTx() Option {
+	return func(o *options) { o.concurrentTx = false }
+}
+
+// WithReusedIDs 后端的自增计数随事务回滚(如 SQLite 的 AUTOINCREMENT),回滚后自增ID可能被复用
+func WithReusedIDs() Option {
+	return func(o *options) { o.uniqueIDs = false }
+}
+
+// tenantCtx 子测试默认使用的租户上下文
+func tenantCtx(tenantID uint64) context.Context {
+	return tenant.WithTenant(context.Background(), tenantID)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func expectErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected error %v, got %v", target, err)
+	}
+}
+
+func expectEqual[V comparable](t *testing.T, name string, got, want V) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s: got %v, want %v", name, got, want)
+	}
+}
+
+// ids 按顺序取出模型的主键
+func ids[PT interface{ GetID() uint64 }](items []PT) []uint64 {
+	result := make([]uint64, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.GetID())
+	}
+	return result
+}
+
+func expectIDs(t *testing.T, name string, got, want []uint64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got ids %v, want %v", name, got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s: got ids %v, want %v", name, got, want)
+		}
+	}
+}
+
+// expectIDSet 比较时忽略顺序,用于没有指定排序的查询
+func expectIDSet(t *testing.T, name string, got, want []uint64) {
+	t.Helper()
+	expectIDs(t, name, slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(want)))
+}
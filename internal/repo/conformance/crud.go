@@ -0,0 +1,245 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	repoFactory "go-pattern/internal/repo/factory"
+	genericRepo "go-pattern/internal/repo/generic"
+)
+
+func createUser(t *testing.T, ctx context.Context, f repoFactory.RepoFactory, name string) *model.User {
+	t.Helper()
+	user := &model.User{Name: name, Email: name + "@example.com"}
+	must(t, f.User().Create(ctx, user))
+	return user
+}
+
+func createProduct(t *testing.T, ctx context.Context, f repoFactory.RepoFactory, name string, price float64, quantity uint64) *model.Product {
+	t.Helper()
+	product := &model.Product{Name: name, Price: price, Quantity: quantity}
+	must(t, f.Product().Create(ctx, product))
+	return product
+}
+
+func createOrder(t *testing.T, ctx context.Context, f repoFactory.RepoFactory, userID, productID uint64) *model.Order {
+	t.Helper()
+	order := &model.Order{UserID: userID, ProductID: productID}
+	must(t, f.Order().Create(ctx, order))
+	return order
+}
+
+func testCreateAndGet(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+
+	var prev uint64
+	for i := range 3 {
+		user := createUser(t, ctx, f, fmt.Sprintf("user%d", i))
+		if user.ID <= prev {
+			t.Fatalf("ids must increase: got %d after %d", user.ID, prev)
+		}
+		prev = user.ID
+		expectEqual(t, "tenant_id", user.TenantID, uint64(1))
+		expectEqual(t, "version", user.Version, uint64(1))
+	}
+
+	got, err := f.User().GetByID(ctx, prev)
+	must(t, err)
+	expectEqual(t, "name", got.Name, "user2")
+	if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Fatalf("timestamps must be set: %+v", got)
+	}
+
+	// 返回的是副本,修改不影响存储
+	got.Name = "changed"
+	again, err := f.User().GetByID(ctx, prev)
+	must(t, err)
+	expectEqual(t, "name", again.Name, "user2")
+
+	_, err = f.User().GetByID(ctx, prev+100)
+	expectErr(t, err, errs.ErrNotFound)
+	_, err = f.User().GetByID(ctx, 0)
+	expectErr(t, err, errs.ErrInvalidArgument)
+
+	users, err := f.User().GetByIDs(ctx, []uint64{prev, prev + 100})
+	must(t, err)
+	expectIDs(t, "GetByIDs", ids(users), []uint64{prev})
+
+	batch := []*model.User{
+		{Name: "batch0", Email: "batch0@example.com"},
+		{Name: "batch1", Email: "batch1@example.com"},
+		{Name: "batch2", Email: "batch2@example.com"},
+	}
+	must(t, f.User().CreateInBatches(ctx, batch, 2))
+	for _, user := range batch {
+		if user.ID <= prev {
+			t.Fatalf("batch ids must increase: got %d after %d", user.ID, prev)
+		}
+		prev = user.ID
+	}
+
+	byStruct, err := f.User().GetByStructFields(ctx, &model.User{Name: "batch1"})
+	must(t, err)
+	expectIDs(t, "GetByStructFields", ids(byStruct), []uint64{batch[1].ID})
+
+	byMap, err := f.User().GetByMapFields(ctx, map[string]any{"name": []string{"batch0", "batch2"}})
+	must(t, err)
+	expectIDSet(t, "GetByMapFields", ids(byMap), []uint64{batch[0].ID, batch[2].ID})
+	_, err = f.User().GetByMapFields(ctx, map[string]any{"unknown": 1})
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testUniqueConstraint(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	createUser(t, ctx, f, "alice")
+
+	err := f.User().Create(ctx, &model.User{Name: "other", Email: "alice@example.com"})
+	expectErr(t, err, errs.ErrConflict)
+
+	// 唯一约束以租户为前缀,其他租户可以使用同一邮箱
+	createUser(t, tenantCtx(2), f, "alice")
+
+	bob := createUser(t, ctx, f, "bob")
+	bob.Email = "alice@example.com"
+	expectErr(t, f.User().Update(ctx, bob), errs.ErrConflict)
+}
+
+func testOptimisticLock(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	user := createUser(t, ctx, f, "alice")
+
+	first, err := f.User().GetByID(ctx, user.ID)
+	must(t, err)
+	stale, err := f.User().GetByID(ctx, user.ID)
+	must(t, err)
+
+	first.Name = "first"
+	must(t, f.User().Update(ctx, first))
+	expectEqual(t, "version after update", first.Version, uint64(2))
+
+	stale.Name = "stale"
+	expectErr(t, f.User().Update(ctx, stale), errs.ErrVersionConflict)
+	expectEqual(t, "version after conflict", stale.Version, uint64(1))
+
+	got, err := f.User().GetByID(ctx, user.ID)
+	must(t, err)
+	expectEqual(t, "name", got.Name, "first")
+	expectEqual(t, "version", got.Version, uint64(2))
+	if got.UpdatedAt.Before(user.UpdatedAt) {
+		t.Fatalf("updated_at must not go backwards: %v < %v", got.UpdatedAt, user.UpdatedAt)
+	}
+}
+
+func testUpdateFields(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	product := createProduct(t, ctx, f, "apple", 1.5, 10)
+
+	// 字段掩码可以写入零值
+	must(t, f.Product().UpdateFields(ctx, product.ID, []string{"quantity", "description"}, map[string]any{
+		"quantity":    0,
+		"description": "",
+		"version":     1,
+	}))
+	got, err := f.Product().GetByID(ctx, product.ID)
+	must(t, err)
+	expectEqual(t, "quantity", got.Quantity, uint64(0))
+	expectEqual(t, "version", got.Version, uint64(2))
+
+	err = f.Product().UpdateFields(ctx, product.ID, []string{"quantity"}, map[string]any{"quantity": 1, "version": 1})
+	expectErr(t, err, errs.ErrVersionConflict)
+	err = f.Product().UpdateFields(ctx, product.ID+100, []string{"quantity"}, map[string]any{"quantity": 1})
+	expectErr(t, err, errs.ErrNotFound)
+	err = f.Product().UpdateFields(ctx, product.ID, []string{"tenant_id"}, map[string]any{"tenant_id": 2})
+	expectErr(t, err, errs.ErrInvalidArgument)
+	err = f.Product().UpdateFields(ctx, product.ID, []string{"quantity"}, map[string]any{})
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testSoftDelete(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	user := createUser(t, ctx, f, "alice")
+	product := createProduct(t, ctx, f, "apple", 1, 1)
+	order := createOrder(t, ctx, f, user.ID, product.ID)
+
+	must(t, f.Order().DeleteByID(ctx, order.ID))
+	_, err := f.Order().GetByID(ctx, order.ID)
+	expectErr(t, err, errs.ErrNotFound)
+	expectErr(t, f.Order().DeleteByID(ctx, order.ID), errs.ErrNotFound)
+
+	deleted, err := f.Order().GetWithDeleted(ctx, order.ID)
+	must(t, err)
+	if !deleted.DeletedAt.Valid {
+		t.Fatalf("deleted_at must be set: %+v", deleted)
+	}
+
+	must(t, f.Order().Restore(ctx, order.ID))
+	expectErr(t, f.Order().Restore(ctx, order.ID), errs.ErrNotFound)
+	_, err = f.Order().GetByID(ctx, order.ID)
+	must(t, err)
+
+	must(t, f.Order().DeleteByIDs(ctx, []uint64{order.ID}))
+	purged, err := f.Order().Purge(ctx, 0)
+	must(t, err)
+	expectEqual(t, "purged", purged, int64(1))
+	_, err = f.Order().GetWithDeleted(ctx, order.ID)
+	expectErr(t, err, errs.ErrNotFound)
+
+	// 没有软删除字段的模型直接物理删除,不支持恢复
+	must(t, f.Product().DeleteByID(ctx, product.ID))
+	_, err = f.Product().GetByID(ctx, product.ID)
+	expectErr(t, err, errs.ErrNotFound)
+	expectErr(t, f.Product().Restore(ctx, product.ID), errs.ErrInvalidArgument)
+}
+
+func testUpsert(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+
+	user := &model.User{Name: "alice", Email: "alice@example.com"}
+	result, err := f.User().Upsert(ctx, user, []string{"email"}, []string{"name"})
+	must(t, err)
+	expectEqual(t, "inserted", result, genericRepo.UpsertResult{Inserted: 1})
+	if user.ID == 0 {
+		t.Fatal("upsert must back-fill the id of inserted rows")
+	}
+
+	renamed := &model.User{Name: "alice2", Email: "alice@example.com"}
+	result, err = f.User().Upsert(ctx, renamed, []string{"email"}, []string{"name"})
+	must(t, err)
+	expectEqual(t, "updated", result, genericRepo.UpsertResult{Updated: 1})
+	expectEqual(t, "id", renamed.ID, user.ID)
+
+	got, err := f.User().GetByID(ctx, user.ID)
+	must(t, err)
+	expectEqual(t, "name", got.Name, "alice2")
+	expectEqual(t, "version", got.Version, uint64(2))
+
+	result, err = f.User().Upsert(ctx, &model.User{Name: "ignored", Email: "alice@example.com"}, []string{"email"}, nil)
+	must(t, err)
+	expectEqual(t, "skipped", result, genericRepo.UpsertResult{Skipped: 1})
+
+	batch := []*model.User{
+		{Name: "alice3", Email: "alice@example.com"},
+		{Name: "bob", Email: "bob@example.com"},
+		{Name: "carol", Email: "carol@example.com"},
+	}
+	result, err = f.User().UpsertInBatches(ctx, batch, 2, []string{"email"}, []string{"name"})
+	must(t, err)
+	expectEqual(t, "batch", result, genericRepo.UpsertResult{Inserted: 2, Updated: 1})
+	// DO UPDATE 时冲突后被更新的行同样回填主键,缓存装饰器据此失效缓存
+	expectEqual(t, "batch updated id", batch[0].ID, user.ID)
+	for _, u := range batch[1:] {
+		if u.ID == 0 {
+			t.Fatal("upsert in batches must back-fill the id of inserted rows")
+		}
+	}
+
+	count, err := f.User().Count(ctx, nil)
+	must(t, err)
+	expectEqual(t, "count", count, int64(3))
+
+	_, err = f.User().Upsert(ctx, &model.User{Name: "x", Email: "x@example.com"}, []string{"email"}, []string{"version"})
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
@@ -0,0 +1,14 @@
+package conformance
+
+import (
+	"testing"
+
+	repoFactory "go-pattern/internal/repo/factory"
+	genericRepo "go-pattern/internal/repo/generic"
+)
+
+func TestMemory(t *testing.T) {
+	Run(t, func(t *testing.T) repoFactory.RepoFactory {
+		return repoFactory.NewMemoryRepoFactory(genericRepo.NewMemoryDB())
+	})
+}
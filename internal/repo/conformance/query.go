@@ -0,0 +1,381 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	repoFactory "go-pattern/internal/repo/factory"
+	genericRepo "go-pattern/internal/repo/generic"
+	"go-pattern/internal/tenant"
+)
+
+// createProducts 按价格依次创建商品,名称为 p0、p1……
+func createProducts(t *testing.T, ctx context.Context, f repoFactory.RepoFactory, prices ...float64) []*model.Product {
+	t.Helper()
+	products := make([]*model.Product, 0, len(prices))
+	for i, price := range prices {
+		products = append(products, createProduct(t, ctx, f, fmt.Sprintf("p%d", i), price, uint64(i+1)))
+	}
+	return products
+}
+
+func testFindAndCount(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	p := createProducts(t, ctx, f, 10, 20, 30, 40, 50)
+
+	spec := genericRepo.NewSpec(genericRepo.Gte("price", 20)).
+		OrderBy(genericRepo.Desc("price")).
+		Limit(2).
+		Offset(1)
+	found, err := f.Product().Find(ctx, spec)
+	must(t, err)
+	expectIDs(t, "Find", ids(found), []uint64{p[3].ID, p[2].ID})
+
+	count, err := f.Product().Count(ctx, spec)
+	must(t, err)
+	expectEqual(t, "Count ignores paging", count, int64(4))
+
+	found, err = f.Product().Find(ctx, genericRepo.NewSpec(
+		genericRepo.Or(genericRepo.Eq("name", "p0"), genericRepo.Between("price", 35, 45)),
+		genericRepo.NotIn("id", []uint64{p[1].ID}),
+	))
+	must(t, err)
+	expectIDSet(t, "Find with Or", ids(found), []uint64{p[0].ID, p[3].ID})
+
+	found, err = f.Product().Find(ctx, genericRepo.NewSpec(genericRepo.Like("name", "p%"), genericRepo.Lt("quantity", 3)))
+	must(t, err)
+	expectIDSet(t, "Find with Like", ids(found), []uint64{p[0].ID, p[1].ID})
+
+	found, err = f.Product().Find(ctx, genericRepo.NewSpec(genericRepo.Gt("price", 100)))
+	must(t, err)
+	if found == nil || len(found) != 0 {
+		t.Fatalf("Find without matches must return an empty slice, got %#v", found)
+	}
+
+	_, err = f.Product().Find(ctx, genericRepo.NewSpec(genericRepo.Eq("unknown", 1)))
+	expectErr(t, err, errs.ErrInvalidArgument)
+	_, err = f.Product().Find(ctx, genericRepo.NewSpec().OrderBy(genericRepo.Asc("unknown")))
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testPageAndCursor(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	p := createProducts(t, ctx, f, 1, 2, 3, 4, 5)
+
+	page, err := f.Product().GetPage(ctx, nil, 2, 2, false)
+	must(t, err)
+	expectIDs(t, "GetPage", ids(page.Items), []uint64{p[2].ID, p[3].ID})
+	expectEqual(t, "Total", page.Total, int64(5))
+	expectEqual(t, "TotalPages", page.TotalPages, uint64(3))
+	expectEqual(t, "HasNext", page.HasNext, true)
+	expectEqual(t, "HasPrev", page.HasPrev, true)
+
+	page, err = f.Product().GetPage(ctx, genericRepo.NewSpec(genericRepo.Gt("price", 1)).OrderBy(genericRepo.Desc("price")), 1, 3, false)
+	must(t, err)
+	expectIDs(t, "GetPage with spec", ids(page.Items), []uint64{p[4].ID, p[3].ID, p[2].ID})
+	expectEqual(t, "Total with spec", page.Total, int64(4))
+
+	page, err = f.Product().GetPage(ctx, nil, 4, 2, false)
+	must(t, err)
+	expectEqual(t, "items beyond the last page", len(page.Items), 0)
+	expectEqual(t, "HasNext beyond the last page", page.HasNext, false)
+
+	items, err := f.Product().GetByPage(ctx, 3, 2)
+	must(t, err)
+	expectIDs(t, "GetByPage", ids(items), []uint64{p[4].ID})
+	_, err = f.Product().GetByPage(ctx, 4, 2)
+	expectErr(t, err, errs.ErrNotFound)
+	_, err = f.Product().GetByPage(ctx, 0, 2)
+	expectErr(t, err, errs.ErrInvalidArgument)
+
+	var (
+		all    []uint64
+		cursor uint64
+	)
+	for {
+		items, next, hasMore, err := f.Product().GetByCursor(ctx, cursor, 2)
+		must(t, err)
+		all = append(all, ids(items)...)
+		expectEqual(t, "cursor", next, items[len(items)-1].ID)
+		cursor = next
+		if !hasMore {
+			break
+		}
+	}
+	expectIDs(t, "GetByCursor", all, ids(p))
+	_, _, _, err = f.Product().GetByCursor(ctx, cursor, 2)
+	expectErr(t, err, errs.ErrNotFound)
+}
+
+func testKeyset(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	p := createProducts(t, ctx, f, 10, 20, 20, 30, 40)
+	query := genericRepo.KeysetQuery{
+		Spec:  genericRepo.NewSpec(genericRepo.Gte("price", 10)),
+		Sorts: []genericRepo.Sort{genericRepo.Desc("price")},
+		Limit: 2,
+	}
+
+	first, err := f.Product().GetByKeyset(ctx, query)
+	must(t, err)
+	expectIDs(t, "first page", ids(first.Items), []uint64{p[4].ID, p[3].ID})
+	expectEqual(t, "first HasNext", first.HasNext, true)
+	expectEqual(t, "first HasPrev", first.HasPrev, false)
+
+	// 价格相同的行以主键决胜
+	query.Cursor = first.NextCursor
+	second, err := f.Product().GetByKeyset(ctx, query)
+	must(t, err)
+	expectIDs(t, "second page", ids(second.Items), []uint64{p[1].ID, p[2].ID})
+	expectEqual(t, "second HasPrev", second.HasPrev, true)
+
+	query.Cursor = second.NextCursor
+	last, err := f.Product().GetByKeyset(ctx, query)
+	must(t, err)
+	expectIDs(t, "last page", ids(last.Items), []uint64{p[0].ID})
+	expectEqual(t, "last HasNext", last.HasNext, false)
+
+	query.Cursor = last.PrevCursor
+	back, err := f.Product().GetByKeyset(ctx, query)
+	must(t, err)
+	expectIDs(t, "previous page", ids(back.Items), []uint64{p[1].ID, p[2].ID})
+	expectEqual(t, "previous HasPrev", back.HasPrev, true)
+
+	query.Cursor = back.PrevCursor
+	back, err = f.Product().GetByKeyset(ctx, query)
+	must(t, err)
+	expectIDs(t, "back to first page", ids(back.Items), []uint64{p[4].ID, p[3].ID})
+	expectEqual(t, "back HasPrev", back.HasPrev, false)
+
+	tampered := []byte(first.NextCursor)
+	if i := len(tampered) / 2; tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+	query.Cursor = string(tampered)
+	_, err = f.Product().GetByKeyset(ctx, query)
+	expectErr(t, err, errs.ErrInvalidArgument)
+
+	// 游标与排序绑定,换一种排序时拒绝旧游标
+	query.Cursor, query.Sorts = first.NextCursor, []genericRepo.Sort{genericRepo.Asc("price")}
+	_, err = f.Product().GetByKeyset(ctx, query)
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testIterate(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	p := createProducts(t, ctx, f, 7, 6, 5, 4, 3, 2, 1)
+
+	var got []uint64
+	for product, err := range f.Product().Iterate(ctx, nil, 3) {
+		must(t, err)
+		got = append(got, product.ID)
+	}
+	expectIDs(t, "Iterate", got, ids(p))
+
+	got = got[:0]
+	spec := genericRepo.NewSpec(genericRepo.Lte("price", 5)).OrderBy(genericRepo.Asc("price")).Limit(4)
+	for product, err := range f.Product().Iterate(ctx, spec, 2) {
+		must(t, err)
+		got = append(got, product.ID)
+	}
+	expectIDs(t, "Iterate with spec", got, []uint64{p[6].ID, p[5].ID, p[4].ID, p[3].ID})
+
+	var failed bool
+	for _, err := range f.Product().Iterate(ctx, nil, 0) {
+		expectErr(t, err, errs.ErrInvalidArgument)
+		failed = true
+	}
+	expectEqual(t, "Iterate with invalid batch size yields an error", failed, true)
+}
+
+func testAggregate(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	alice := createUser(t, ctx, f, "alice")
+	bob := createUser(t, ctx, f, "bob")
+	apple := createProduct(t, ctx, f, "apple", 2.5, 4)
+	pear := createProduct(t, ctx, f, "pear", 1, 3)
+	for range 3 {
+		createOrder(t, ctx, f, alice.ID, apple.ID)
+	}
+	createOrder(t, ctx, f, bob.ID, pear.ID)
+
+	rows, err := f.Order().Aggregate(ctx, genericRepo.AggregateQuery{
+		GroupBy: []genericRepo.Group{genericRepo.GroupBy("user_id")},
+		Metrics: []genericRepo.Metric{genericRepo.CountAll("orders")},
+		OrderBy: []genericRepo.Sort{genericRepo.Desc("orders")},
+	})
+	must(t, err)
+	expectEqual(t, "groups", len(rows), 2)
+	expectEqual(t, "top user", rows[0].Uint("user_id"), alice.ID)
+	expectEqual(t, "top orders", rows[0].Int("orders"), int64(3))
+	expectEqual(t, "second orders", rows[1].Int("orders"), int64(1))
+
+	rows, err = f.Order().Aggregate(ctx, genericRepo.AggregateQuery{
+		GroupBy: []genericRepo.Group{genericRepo.GroupBy("user_id")},
+		Metrics: []genericRepo.Metric{genericRepo.CountAll("orders")},
+		Having:  []genericRepo.Cond{genericRepo.Gt("orders", 1)},
+	})
+	must(t, err)
+	expectEqual(t, "groups with having", len(rows), 1)
+	expectEqual(t, "user with having", rows[0].Uint("user_id"), alice.ID)
+
+	rows, err = f.Product().Aggregate(ctx, genericRepo.AggregateQuery{
+		Metrics: []genericRepo.Metric{
+			genericRepo.SumOf("stock_value", "price", "quantity"),
+			genericRepo.AvgOf("avg_price", "price"),
+			genericRepo.MaxOf("max_quantity", "quantity"),
+		},
+	})
+	must(t, err)
+	expectEqual(t, "rows without group", len(rows), 1)
+	expectEqual(t, "stock_value", rows[0].Float("stock_value"), 13.0)
+	expectEqual(t, "avg_price", rows[0].Float("avg_price"), 1.75)
+	expectEqual(t, "max_quantity", rows[0].Int("max_quantity"), int64(4))
+
+	// 没有分组时即使没有匹配行也返回一行,SUM 为 NULL
+	rows, err = f.Product().Aggregate(ctx, genericRepo.AggregateQuery{
+		Spec:    genericRepo.NewSpec(genericRepo.Gt("price", 100)),
+		Metrics: []genericRepo.Metric{genericRepo.CountAll("total"), genericRepo.SumOf("quantity", "quantity")},
+	})
+	must(t, err)
+	expectEqual(t, "rows without matches", len(rows), 1)
+	expectEqual(t, "count without matches", rows[0].Int("total"), int64(0))
+	if rows[0]["quantity"] != nil {
+		t.Fatalf("sum without matches must be nil, got %v", rows[0]["quantity"])
+	}
+
+	_, err = f.Product().Aggregate(ctx, genericRepo.AggregateQuery{
+		Metrics: []genericRepo.Metric{genericRepo.SumOf("total", "unknown")},
+	})
+	expectErr(t, err, errs.ErrInvalidArgument)
+	_, err = f.Product().Aggregate(ctx, genericRepo.AggregateQuery{
+		Metrics: []genericRepo.Metric{genericRepo.CountAll("total")},
+		OrderBy: []genericRepo.Sort{genericRepo.Asc("price")},
+	})
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testBulk(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	p := createProducts(t, ctx, f, 1, 2, 3, 4)
+
+	var affected []uint64
+	n, err := f.Product().UpdateWhere(ctx,
+		genericRepo.NewSpec(genericRepo.Gte("price", 3)),
+		map[string]any{"description": "expensive"},
+		genericRepo.WithAffectedIDs(func(ids []uint64) { affected = append(affected, ids...) }),
+	)
+	must(t, err)
+	expectEqual(t, "updated", n, int64(2))
+	expectIDSet(t, "affected ids", affected, []uint64{p[2].ID, p[3].ID})
+
+	got, err := f.Product().GetByID(ctx, p[3].ID)
+	must(t, err)
+	expectEqual(t, "description", got.Description, "expensive")
+	expectEqual(t, "version", got.Version, uint64(2))
+
+	_, err = f.Product().UpdateWhere(ctx, nil, map[string]any{"description": "all"})
+	expectErr(t, err, errs.ErrInvalidArgument)
+	n, err = f.Product().UpdateWhere(ctx, nil, map[string]any{"description": "all"}, genericRepo.ForceAll())
+	must(t, err)
+	expectEqual(t, "updated with ForceAll", n, int64(4))
+	_, err = f.Product().UpdateWhere(ctx, genericRepo.NewSpec(genericRepo.Eq("id", p[0].ID)), map[string]any{"tenant_id": 2})
+	expectErr(t, err, errs.ErrInvalidArgument)
+
+	user := createUser(t, ctx, f, "alice")
+	for _, product := range p {
+		createOrder(t, ctx, f, user.ID, product.ID)
+	}
+	n, err = f.Order().DeleteWhere(ctx, genericRepo.NewSpec(genericRepo.In("product_id", []uint64{p[0].ID, p[1].ID})))
+	must(t, err)
+	expectEqual(t, "deleted", n, int64(2))
+	count, err := f.Order().Count(ctx, nil)
+	must(t, err)
+	expectEqual(t, "orders left", count, int64(2))
+
+	n, err = f.Order().DeleteWhere(ctx, genericRepo.NewSpec(genericRepo.Eq("user_id", user.ID+100)))
+	must(t, err)
+	expectEqual(t, "deleted without matches", n, int64(0))
+	_, err = f.Order().DeleteWhere(ctx, genericRepo.NewSpec())
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testTenantIsolation(t *testing.T, f repoFactory.RepoFactory) {
+	ctx, other := tenantCtx(1), tenantCtx(2)
+	mine := createProduct(t, ctx, f, "mine", 1, 1)
+	theirs := createProduct(t, other, f, "theirs", 1, 1)
+
+	_, err := f.Product().GetByID(other, mine.ID)
+	expectErr(t, err, errs.ErrNotFound)
+	found, err := f.Product().Find(other, nil)
+	must(t, err)
+	expectIDs(t, "Find in other tenant", ids(found), []uint64{theirs.ID})
+
+	err = f.Product().UpdateFields(other, mine.ID, []string{"name"}, map[string]any{"name": "stolen"})
+	expectErr(t, err, errs.ErrNotFound)
+	expectErr(t, f.Product().DeleteByID(other, mine.ID), errs.ErrNotFound)
+	got, err := f.Product().GetByID(ctx, mine.ID)
+	must(t, err)
+	expectEqual(t, "name", got.Name, "mine")
+
+	// 更新不能把记录转移到其他租户:模型属于其他租户时拒绝,Bypass 时租户列不写入
+	got.TenantID = 2
+	expectErr(t, f.Product().Update(ctx, got), errs.ErrInvalidArgument)
+	must(t, f.Product().Update(tenant.Bypass(context.Background()), got))
+	got, err = f.Product().GetByID(ctx, mine.ID)
+	must(t, err)
+	expectEqual(t, "tenant_id after update", got.TenantID, uint64(1))
+
+	_, err = f.Product().GetByID(context.Background(), mine.ID)
+	expectErr(t, err, errs.ErrInvalidArgument)
+	expectErr(t, f.Product().Create(context.Background(), &model.Product{Name: "orphan"}), errs.ErrInvalidArgument)
+
+	count, err := f.Product().Count(tenant.Bypass(context.Background()), nil)
+	must(t, err)
+	expectEqual(t, "count with bypass", count, int64(2))
+}
+
+func testPreload(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	alice := createUser(t, ctx, f, "alice")
+	apple := createProduct(t, ctx, f, "apple", 1, 1)
+	first := createOrder(t, ctx, f, alice.ID, apple.ID)
+	second := createOrder(t, ctx, f, alice.ID, apple.ID)
+	deleted := createOrder(t, ctx, f, alice.ID, apple.ID)
+	must(t, f.Order().DeleteByID(ctx, deleted.ID))
+
+	order, err := f.Order().GetByID(ctx, first.ID, genericRepo.WithPreload("User", "Product"))
+	must(t, err)
+	if order.User == nil || order.Product == nil {
+		t.Fatalf("relations must be loaded: %+v", order)
+	}
+	expectEqual(t, "user", order.User.Name, "alice")
+	expectEqual(t, "product", order.Product.Name, "apple")
+
+	order, err = f.Order().GetByID(ctx, first.ID)
+	must(t, err)
+	if order.User != nil {
+		t.Fatalf("relations must not be loaded without WithPreload: %+v", order.User)
+	}
+
+	user, err := f.User().GetByID(ctx, alice.ID, genericRepo.WithPreload("Orders.Product"))
+	must(t, err)
+	expectIDSet(t, "preloaded orders", ids(user.Orders), []uint64{first.ID, second.ID})
+	for _, order := range user.Orders {
+		if order.Product == nil || order.Product.ID != apple.ID {
+			t.Fatalf("nested relation must be loaded: %+v", order)
+		}
+	}
+
+	users, err := f.User().Find(ctx, nil, genericRepo.WithPreload("Orders"))
+	must(t, err)
+	expectEqual(t, "orders of Find", len(users[0].Orders), 2)
+
+	_, err = f.User().GetByID(ctx, alice.ID, genericRepo.WithPreload("Unknown"))
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
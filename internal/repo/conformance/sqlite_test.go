@@ -0,0 +1,29 @@
+package conformance
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go-pattern/internal/config"
+	"go-pattern/internal/initializer"
+	"go-pattern/internal/registry"
+	repoFactory "go-pattern/internal/repo/factory"
+)
+
+func TestSQLite(t *testing.T) {
+	// SQLite 同一时刻只有一个写事务,回滚后自增ID可能被复用
+	Run(t, func(t *testing.T) repoFactory.RepoFactory {
+		db, err := initializer.GormDB(&config.DatabaseConfig{
+			Driver:   "sqlite",
+			Path:     filepath.Join(t.TempDir(), "conformance.db"),
+			LogLevel: 1,
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := registry.Migrate(db, nil); err != nil {
+			t.Fatal(err)
+		}
+		return repoFactory.NewRepoFactory(db)
+	}, WithoutConcurrentTx(), WithReusedIDs())
+}
@@ -0,0 +1,227 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	repoFactory "go-pattern/internal/repo/factory"
+)
+
+var errRollback = errors.New("rollback")
+
+func testTransaction(t *testing.T, f repoFactory.RepoFactory, uniqueIDs bool) {
+	ctx := tenantCtx(1)
+
+	var rolledBack uint64
+	err := f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		user := createUser(t, ctx, tx, "rolled-back")
+		rolledBack = user.ID
+		// 事务内可以读到自己的写入
+		if _, err := tx.User().GetByID(ctx, user.ID); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	expectErr(t, err, errRollback)
+	_, err = f.User().GetByStructFields(ctx, &model.User{Name: "rolled-back"})
+	expectErr(t, err, errs.ErrNotFound)
+
+	var outer, inner uint64
+	err = f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		outer = createUser(t, ctx, tx, "outer").ID
+		// 嵌套事务为保存点,失败时只回滚保存点之后的写入
+		err := tx.Transaction(ctx, func(nested repoFactory.RepoFactory) error {
+			inner = createUser(t, ctx, nested, "inner").ID
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Errorf("nested transaction: expected %v, got %v", errRollback, err)
+		}
+		return tx.Transaction(ctx, func(nested repoFactory.RepoFactory) error {
+			createUser(t, ctx, nested, "committed")
+			return nil
+		})
+	})
+	must(t, err)
+	_, err = f.User().GetByID(ctx, outer)
+	must(t, err)
+	_, err = f.User().GetByStructFields(ctx, &model.User{Name: "inner"})
+	expectErr(t, err, errs.ErrNotFound)
+	count, err := f.User().Count(ctx, nil)
+	must(t, err)
+	expectEqual(t, "users", count, int64(2))
+
+	// 自增ID与数据库序列一样不随事务回滚
+	next := createUser(t, ctx, f, "next")
+	if uniqueIDs && next.ID <= max(inner, rolledBack) {
+		t.Fatalf("ids must not be reused after rollback: got %d after %d", next.ID, inner)
+	}
+
+	err = f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		return tx.Transaction(ctx, func(repoFactory.RepoFactory) error { return nil }, repoFactory.ReadOnly())
+	})
+	expectErr(t, err, errs.ErrInvalidArgument)
+}
+
+func testRunInTx(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	product := createProduct(t, ctx, f, "apple", 1, 10)
+
+	// ctx 携带事务,工厂直接取得的仓储同样参与事务
+	err := f.RunInTx(ctx, func(ctx context.Context) error {
+		must(t, f.Product().UpdateFields(ctx, product.ID, []string{"quantity"}, map[string]any{"quantity": 1}))
+		createUser(t, ctx, f, "alice")
+		return errRollback
+	})
+	expectErr(t, err, errRollback)
+	got, err := f.Product().GetByID(ctx, product.ID)
+	must(t, err)
+	expectEqual(t, "quantity after rollback", got.Quantity, uint64(10))
+	count, err := f.User().Count(ctx, nil)
+	must(t, err)
+	expectEqual(t, "users after rollback", count, int64(0))
+
+	err = f.RunInTx(ctx, func(ctx context.Context) error {
+		return f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+			return tx.Product().UpdateFields(ctx, product.ID, []string{"quantity"}, map[string]any{"quantity": 2})
+		})
+	})
+	must(t, err)
+	got, err = f.Product().GetByID(ctx, product.ID)
+	must(t, err)
+	expectEqual(t, "quantity after commit", got.Quantity, uint64(2))
+
+	err = f.RunInTx(ctx, func(ctx context.Context) error {
+		if _, err := f.Product().GetByID(ctx, product.ID); err != nil {
+			t.Errorf("read in read-only transaction: %v", err)
+		}
+		return f.Product().UpdateFields(ctx, product.ID, []string{"quantity"}, map[string]any{"quantity": 3})
+	}, repoFactory.ReadOnly())
+	if err == nil {
+		t.Fatal("writes in a read-only transaction must fail")
+	}
+}
+
+func testReduceQuantity(t *testing.T, f repoFactory.RepoFactory) {
+	ctx := tenantCtx(1)
+	product := createProduct(t, ctx, f, "apple", 1, 5)
+
+	must(t, f.Product().ReduceQuantity(ctx, product.ID, 3))
+	got, err := f.Product().GetByID(ctx, product.ID)
+	must(t, err)
+	expectEqual(t, "quantity", got.Quantity, uint64(2))
+	expectEqual(t, "version", got.Version, product.Version+1)
+
+	expectErr(t, f.Product().ReduceQuantity(ctx, product.ID, 3), errs.ErrConflict)
+	expectErr(t, f.Product().ReduceQuantity(ctx, product.ID+100, 1), errs.ErrNotFound)
+	expectErr(t, f.Product().ReduceQuantity(tenantCtx(2), product.ID, 1), errs.ErrNotFound)
+
+	err = f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		must(t, tx.Product().ReduceQuantity(ctx, product.ID, 2))
+		return errRollback
+	})
+	expectErr(t, err, errRollback)
+	got, err = f.Product().GetByID(ctx, product.ID)
+	must(t, err)
+	expectEqual(t, "quantity after rollback", got.Quantity, uint64(2))
+}
+
+func testOutbox(t *testing.T, f repoFactory.RepoFactory, concurrentTx bool) {
+	ctx := tenantCtx(1)
+
+	var enqueued []uint64
+	must(t, f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		for i := range 3 {
+			event, err := tx.Outbox().Enqueue(ctx, "order.created", uint64(i+1), map[string]any{"seq": i})
+			if err != nil {
+				return err
+			}
+			enqueued = append(enqueued, event.ID)
+		}
+		return nil
+	}))
+
+	var claimed, skipped []*model.OutboxEvent
+	claimOthers := func() error {
+		return f.Transaction(ctx, func(other repoFactory.RepoFactory) error {
+			var err error
+			if skipped, err = other.Outbox().ClaimBatch(ctx, 10); err != nil {
+				return err
+			}
+			for _, event := range skipped {
+				if err := other.Outbox().MarkFailed(ctx, event.ID, errRollback, time.Hour); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	must(t, f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		var err error
+		if claimed, err = tx.Outbox().ClaimBatch(ctx, 2); err != nil {
+			return err
+		}
+		if err := tx.Outbox().MarkDelivered(ctx, ids(claimed)...); err != nil {
+			return err
+		}
+		// 另一个事务跳过已被锁定的事件
+		if concurrentTx {
+			must(t, claimOthers())
+		}
+		return nil
+	}))
+	if !concurrentTx {
+		must(t, claimOthers())
+	}
+	expectIDs(t, "claimed", ids(claimed), enqueued[:2])
+	expectIDs(t, "claimed by other", ids(skipped), enqueued[2:])
+
+	delivered, err := f.Outbox().GetByID(ctx, enqueued[0])
+	must(t, err)
+	expectEqual(t, "status", delivered.Status, model.OutboxStatusDelivered)
+	if delivered.DeliveredAt == nil {
+		t.Fatal("delivered_at must be set")
+	}
+	failed, err := f.Outbox().GetByID(ctx, enqueued[2])
+	must(t, err)
+	expectEqual(t, "attempts", failed.Attempts, 1)
+	expectEqual(t, "last_error", failed.LastError, errRollback.Error())
+
+	// 失败的事件在 retryAfter 之前不会再被领取
+	must(t, f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		var err error
+		claimed, err = tx.Outbox().ClaimBatch(ctx, 10)
+		return err
+	}))
+	expectEqual(t, "claimed before retry", len(claimed), 0)
+
+	// 租约内的事件不会再被领取
+	leased, err := f.Outbox().Enqueue(ctx, "order.created", 4, nil)
+	must(t, err)
+	must(t, f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		var err error
+		if claimed, err = tx.Outbox().ClaimBatch(ctx, 10); err != nil {
+			return err
+		}
+		return tx.Outbox().Lease(ctx, time.Hour, ids(claimed)...)
+	}))
+	expectIDs(t, "leased", ids(claimed), []uint64{leased.ID})
+	must(t, f.Transaction(ctx, func(tx repoFactory.RepoFactory) error {
+		var err error
+		claimed, err = tx.Outbox().ClaimBatch(ctx, 10)
+		return err
+	}))
+	expectEqual(t, "claimed during lease", len(claimed), 0)
+
+	must(t, f.Outbox().MarkDead(ctx, enqueued[2], errRollback))
+	dead, err := f.Outbox().GetByID(ctx, enqueued[2])
+	must(t, err)
+	expectEqual(t, "status", dead.Status, model.OutboxStatusDead)
+	expectErr(t, f.Outbox().MarkDead(ctx, enqueued[2]+100, errRollback), errs.ErrNotFound)
+	// 没有乐观锁的模型更新不存在的记录同样返回 ErrNotFound
+	expectErr(t, f.Outbox().Update(ctx, &model.OutboxEvent{ID: enqueued[2] + 100, Topic: "order.created"}), errs.ErrNotFound)
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	genericRepo "go-pattern/internal/repo/generic"
+	"go-pattern/internal/tenant"
+)
+
+// recordingCache 只记录被删除的键,读取总是未命中
+type recordingCache struct {
+	deleted []string
+}
+
+func (c *recordingCache) SetWithTTL(context.Context, string, model.Product, time.Duration, time.Duration) error {
+	return nil
+}
+
+func (c *recordingCache) SetWithDefaultTTL(context.Context, string, model.Product) error {
+	return nil
+}
+
+func (c *recordingCache) Get(context.Context, string) (model.Product, error) {
+	return model.Product{}, errs.ErrCacheMiss
+}
+
+func (c *recordingCache) GetPointer(context.Context, string) (*model.Product, error) {
+	return nil, errs.ErrCacheMiss
+}
+
+func (c *recordingCache) Del(_ context.Context, key string) error {
+	c.deleted = append(c.deleted, key)
+	return nil
+}
+
+var errRollback = errors.New("rollback")
+
+func TestInvalidateAfterCommit(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), 1)
+	rename := func(f RepoFactory, id uint64) error {
+		return f.Product().UpdateFields(ctx, id, []string{"name"}, map[string]any{"name": "renamed"})
+	}
+
+	tests := []struct {
+		name string
+		run  func(f RepoFactory, c *recordingCache, id uint64) error
+		want []string
+	}{
+		{"outermost commit", func(f RepoFactory, c *recordingCache, id uint64) error {
+			return f.Transaction(ctx, func(tx RepoFactory) error {
+				if err := rename(tx, id); err != nil {
+					return err
+				}
+				if len(c.deleted) != 0 {
+					t.Errorf("invalidated before commit: %v", c.deleted)
+				}
+				return nil
+			})
+		}, []string{"products:1"}},
+		{"rollback", func(f RepoFactory, c *recordingCache, id uint64) error {
+			err := f.Transaction(ctx, func(tx RepoFactory) error {
+				if err := rename(tx, id); err != nil {
+					return err
+				}
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				return err
+			}
+			return nil
+		}, nil},
+		{"savepoint rollback", func(f RepoFactory, c *recordingCache, id uint64) error {
+			return f.Transaction(ctx, func(tx RepoFactory) error {
+				_ = tx.Transaction(ctx, func(nested RepoFactory) error {
+					if err := nested.Product().DeleteByID(ctx, id); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				return nil
+			})
+		}, nil},
+		{"bulk write in RunInTx", func(f RepoFactory, c *recordingCache, id uint64) error {
+			return f.RunInTx(ctx, func(ctx context.Context) error {
+				_, err := f.Product().UpdateWhere(ctx, genericRepo.NewSpec(genericRepo.Eq("id", id)), map[string]any{"name": "renamed"})
+				if len(c.deleted) != 0 {
+					t.Errorf("invalidated before commit: %v", c.deleted)
+				}
+				return err
+			})
+		}, []string{"products:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &recordingCache{}
+			f := NewMemoryRepoFactory(genericRepo.NewMemoryDB()).WithCaches(Cached[model.Product](c))
+			product := &model.Product{Name: "apple", Quantity: 1}
+			if err := f.Product().Create(ctx, product); err != nil {
+				t.Fatal(err)
+			}
+			c.deleted = nil
+
+			if err := tt.run(f, c, product.ID); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(c.deleted, tt.want) {
+				t.Fatalf("invalidated keys = %v, want %v", c.deleted, tt.want)
+			}
+		})
+	}
+}
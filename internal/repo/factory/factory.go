@@ -2,14 +2,26 @@ package repo
 
 import (
 	"context"
+	auditRepo "go-pattern/internal/repo/audit"
 	orderRepo "go-pattern/internal/repo/order"
+	outboxRepo "go-pattern/internal/repo/outbox"
 	productRepo "go-pattern/internal/repo/product"
 	userRepo "go-pattern/internal/repo/user"
 )
 
 type RepoFactory interface {
-	Transaction(ctx context.Context, fn func(factory RepoFactory) error) error
+	// Transaction 在事务中执行 fn。在已绑定事务的工厂上调用时创建保存点,
+	// fn 失败只回滚到该保存点,外层事务可以继续;opts 只能用于最外层事务
+	Transaction(ctx context.Context, fn func(factory RepoFactory) error, opts ...TxOption) error
+	// RunInTx 在事务中执行 fn,事务随 ctx 传递:任何使用该 ctx 的仓储方法(包括跨服务调用)
+	// 都自动加入事务;ctx 中已有事务时创建保存点
+	RunInTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+	// User、Order、Product 是 For 的类型化封装,带有模型专属的方法;
+	// 其他注册的模型直接通过 For 获取仓储
 	User() userRepo.UserRepo
 	Order() orderRepo.OrderRepo
 	Product() productRepo.ProductRepo
+	Audit() auditRepo.AuditRepo
+	// Outbox 在业务事务的工厂上写入事件,可保证事件与业务数据一起提交
+	Outbox() outboxRepo.OutboxRepo
 }
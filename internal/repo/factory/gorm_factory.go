@@ -2,8 +2,22 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
 
+	cache "go-pattern/internal/cache/multilevel"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
+	"go-pattern/internal/model"
+	auditRepo "go-pattern/internal/repo/audit"
+	cachedRepo "go-pattern/internal/repo/cached"
+	genericRepo "go-pattern/internal/repo/generic"
 	orderRepo "go-pattern/internal/repo/order"
+	outboxRepo "go-pattern/internal/repo/outbox"
 	productRepo "go-pattern/internal/repo/product"
 	userRepo "go-pattern/internal/repo/user"
 
@@ -12,40 +26,177 @@ import (
 
 type repoFactory struct {
 	db *gorm.DB
+	// repoOpts 传递给每个仓储的通用配置
+	repoOpts []genericRepo.Option
+	caches   caches
+	// inTx 表示工厂绑定在事务上
+	inTx bool
+	// hooks 绑定在事务上时最外层事务的提交回调
+	hooks *genericRepo.TxHooks
+	// metrics 事务重试统计,事务工厂与根工厂共享
+	metrics *retryMetrics
+	logger  *slog.Logger
 }
 
-func NewRepoFactory(db *gorm.DB) *repoFactory {
-	return &repoFactory{db: db}
+func NewRepoFactory(db *gorm.DB, repoOpts ...genericRepo.Option) *repoFactory {
+	return &repoFactory{db: db, repoOpts: repoOpts, metrics: &retryMetrics{}, logger: slog.Default()}
 }
 
-func (f *repoFactory) withTransaction(tx *gorm.DB) *repoFactory {
+// WithCaches 为指定模型的仓储启用缓存装饰器,缓存由 Cached 绑定到模型
+func (f *repoFactory) WithCaches(bindings ...CacheBinding) *repoFactory {
+	f.caches = newCaches(bindings)
+	return f
+}
+
+// WithLogger 设置事务重试与缓存装饰器使用的 logger
+func (f *repoFactory) WithLogger(logger *slog.Logger) *repoFactory {
+	f.logger = logging.OrDefault(logger)
+	return f
+}
+
+func (f *repoFactory) withTransaction(tx *gorm.DB, hooks *genericRepo.TxHooks) *repoFactory {
+	// 事务内的读写都必须落在同一个事务连接上,关闭副本路由
+	repoOpts := append(slices.Clone(f.repoOpts), genericRepo.WithReplicas(nil), genericRepo.WithAfterCommit(hooks))
 	return &repoFactory{
-		db: tx,
+		db:       tx,
+		repoOpts: repoOpts,
+		caches:   f.caches,
+		inTx:     true,
+		hooks:    hooks,
+		metrics:  f.metrics,
+		logger:   f.logger,
 	}
 }
 
 // Transaction 执行事务操作
-func (f *repoFactory) Transaction(ctx context.Context, fn func(factory RepoFactory) error) error {
-	// 使用gorm事务,自动控制事务提交和回滚
-	return f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 创建事务工厂
-		txFactory := f.withTransaction(tx)
-		// 执行用户逻辑
+func (f *repoFactory) Transaction(ctx context.Context, fn func(factory RepoFactory) error, opts ...TxOption) error {
+	return f.transaction(ctx, func(txFactory *repoFactory) error {
 		return fn(txFactory)
+	}, opts)
+}
+
+// RunInTx 在事务中执行 fn,事务通过 ctx 传递,使用该 ctx 的任何仓储方法都会加入事务
+func (f *repoFactory) RunInTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	return f.transaction(ctx, func(txFactory *repoFactory) error {
+		return fn(genericRepo.WithTxHooks(genericRepo.WithTx(ctx, txFactory.db), txFactory.hooks))
+	}, opts)
+}
+
+// transaction 开启事务,工厂已绑定事务或 ctx 中已有事务时改为创建保存点。
+// 提交回调只在最外层事务提交后执行
+func (f *repoFactory) transaction(ctx context.Context, fn func(txFactory *repoFactory) error, opts []TxOption) error {
+	if tx, ok := genericRepo.TxFrom(ctx); ok && !f.inTx {
+		hooks, _ := genericRepo.TxHooksFrom(ctx)
+		f = f.withTransaction(tx, hooks)
+	}
+	txOpts := newTxOptions(opts)
+	if f.inTx {
+		if txOpts.isSet() {
+			return fmt.Errorf("nested transaction can not change isolation level or read-only mode: %w", errs.ErrInvalidArgument)
+		}
+		return f.savepoint(ctx, fn)
+	}
+
+	var sqlOpts []*sql.TxOptions
+	if txOpts.isSet() {
+		sqlOpts = append(sqlOpts, txOpts.sqlOptions())
+	}
+	var hooks *genericRepo.TxHooks
+	err := f.retry(ctx, txOpts, func() error {
+		// 每次重试都是新的事务,上一次注册的回调随之丢弃
+		hooks = genericRepo.NewTxHooks()
+		// 使用gorm事务,自动控制事务提交和回滚
+		return f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+			// SQLite 驱动忽略 sql.TxOptions,只读改由连接级开关实现,隔离级别始终为串行
+			if txOpts.readOnly {
+				restore, beginErr := genericRepo.BeginReadOnly(tx)
+				if beginErr != nil {
+					return fmt.Errorf("begin read-only transaction failed: %w", beginErr)
+				}
+				defer func() {
+					if restoreErr := restore(); restoreErr != nil {
+						err = errors.Join(err, fmt.Errorf("restore read-only connection failed: %w", restoreErr))
+					}
+				}()
+			}
+			// 创建事务工厂
+			txFactory := f.withTransaction(tx, hooks)
+			// 执行用户逻辑
+			return fn(txFactory)
+		}, sqlOpts...)
 	})
+	if err != nil {
+		return err
+	}
+	hooks.Commit()
+	return nil
+}
+
+// savepointSeq 生成唯一的保存点名称
+var savepointSeq atomic.Uint64
+
+// savepoint 在当前事务中创建保存点执行 fn,失败或 panic 时回滚到保存点,成功时释放保存点
+func (f *repoFactory) savepoint(ctx context.Context, fn func(txFactory *repoFactory) error) (err error) {
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+	tx := f.db.WithContext(ctx)
+	if err := tx.SavePoint(name).Error; err != nil {
+		return fmt.Errorf("create savepoint %s failed: %w", name, err)
+	}
+
+	mark := f.hooks.Mark()
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			f.hooks.Discard(mark)
+			if rollbackErr := tx.RollbackTo(name).Error; rollbackErr != nil {
+				err = errors.Join(err, fmt.Errorf("rollback to savepoint %s failed: %w", name, rollbackErr))
+			}
+		}
+	}()
+	err = fn(f.withTransaction(tx, f.hooks))
+	panicked = false
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec("RELEASE SAVEPOINT " + name).Error; err != nil {
+		return fmt.Errorf("release savepoint %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// withCache 按配置为仓储套上缓存装饰器,事务内只失效不回填,且在 hooks 所属的事务提交后才失效
+func withCache[T any, PT model.PointerModel[T]](repo genericRepo.GenericRepo[T, PT], cache cache.MultiLevelCache[T], inTx bool, hooks *genericRepo.TxHooks, logger *slog.Logger) genericRepo.GenericRepo[T, PT] {
+	switch {
+	case cache == nil:
+		return repo
+	case inTx:
+		return cachedRepo.NewInvalidatingRepo(repo, cache, hooks, logger)
+	default:
+		return cachedRepo.NewCachedRepo(repo, cache, logger)
+	}
+}
+
+func (f *repoFactory) Source() Source {
+	return Source{db: f.db, repoOpts: f.repoOpts, caches: f.caches, inTx: f.inTx, hooks: f.hooks, logger: f.logger}
 }
 
 func (f *repoFactory) User() userRepo.UserRepo {
-	// 这里可以添加缓存，避免重复创建
-	return userRepo.NewUserRepo(f.db)
+	return userRepo.NewUserRepoFrom(For[model.User](f))
 }
 
 func (f *repoFactory) Order() orderRepo.OrderRepo {
-	// 这里可以添加缓存，避免重复创建
-	return orderRepo.NewOrderRepo(f.db)
+	return orderRepo.NewOrderRepoFrom(For[model.Order](f))
 }
 
 func (f *repoFactory) Product() productRepo.ProductRepo {
-	// 这里可以添加缓存，避免重复创建
-	return productRepo.NewProductRepo(f.db)
+	return productRepo.NewProductRepoFrom(For[model.Product](f))
+}
+
+// Audit 审计日志本身的写入不再记录审计
+func (f *repoFactory) Audit() auditRepo.AuditRepo {
+	return auditRepo.NewAuditRepoFrom(forModel[model.AuditLog](f, genericRepo.WithAudit(false)))
+}
+
+func (f *repoFactory) Outbox() outboxRepo.OutboxRepo {
+	return outboxRepo.NewOutboxRepoFrom(f.db, forModel[model.OutboxEvent](f, genericRepo.WithAudit(false)))
 }
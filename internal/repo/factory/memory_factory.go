@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
+	"go-pattern/internal/model"
+	auditRepo "go-pattern/internal/repo/audit"
+	genericRepo "go-pattern/internal/repo/generic"
+	orderRepo "go-pattern/internal/repo/order"
+	outboxRepo "go-pattern/internal/repo/outbox"
+	productRepo "go-pattern/internal/repo/product"
+	userRepo "go-pattern/internal/repo/user"
+)
+
+type memoryRepoFactory struct {
+	db *genericRepo.MemoryDB
+	// repoOpts 传递给每个仓储的通用配置
+	repoOpts []genericRepo.Option
+	caches   caches
+	// inTx 表示工厂绑定在事务上
+	inTx bool
+	// hooks 绑定在事务上时最外层事务的提交回调
+	hooks  *genericRepo.TxHooks
+	logger *slog.Logger
+}
+
+// NewMemoryRepoFactory 内存实现的 RepoFactory,用于不依赖数据库的服务层单元测试。
+// 事务基于快照,嵌套事务为保存点;内存事务不会出现序列化失败与死锁,WithRetry 与隔离级别不生效
+func NewMemoryRepoFactory(db *genericRepo.MemoryDB, repoOpts ...genericRepo.Option) *memoryRepoFactory {
+	return &memoryRepoFactory{db: db, repoOpts: repoOpts, logger: slog.Default()}
+}
+
+// WithCaches 为指定模型的仓储启用缓存装饰器,缓存由 Cached 绑定到模型
+func (f *memoryRepoFactory) WithCaches(bindings ...CacheBinding) *memoryRepoFactory {
+	f.caches = newCaches(bindings)
+	return f
+}
+
+// WithLogger 设置缓存装饰器使用的 logger
+func (f *memoryRepoFactory) WithLogger(logger *slog.Logger) *memoryRepoFactory {
+	f.logger = logging.OrDefault(logger)
+	return f
+}
+
+func (f *memoryRepoFactory) withTransaction(tx *genericRepo.MemoryDB, hooks *genericRepo.TxHooks) *memoryRepoFactory {
+	return &memoryRepoFactory{
+		db:       tx,
+		repoOpts: append(slices.Clone(f.repoOpts), genericRepo.WithAfterCommit(hooks)),
+		caches:   f.caches,
+		inTx:     true,
+		hooks:    hooks,
+		logger:   f.logger,
+	}
+}
+
+func (f *memoryRepoFactory) Transaction(ctx context.Context, fn func(factory RepoFactory) error, opts ...TxOption) error {
+	return f.transaction(ctx, func(txFactory *memoryRepoFactory) error {
+		return fn(txFactory)
+	}, opts)
+}
+
+func (f *memoryRepoFactory) RunInTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	return f.transaction(ctx, func(txFactory *memoryRepoFactory) error {
+		return fn(genericRepo.WithTxHooks(genericRepo.WithMemoryTx(ctx, txFactory.db), txFactory.hooks))
+	}, opts)
+}
+
+// transaction 开启事务,工厂已绑定事务或 ctx 中已有事务时改为创建保存点。
+// 提交回调只在最外层事务提交后执行
+func (f *memoryRepoFactory) transaction(ctx context.Context, fn func(txFactory *memoryRepoFactory) error, opts []TxOption) (err error) {
+	if tx, ok := genericRepo.MemoryTxFrom(ctx); ok && !f.inTx {
+		hooks, _ := genericRepo.TxHooksFrom(ctx)
+		f = f.withTransaction(tx, hooks)
+	}
+	txOpts := newTxOptions(opts)
+	if f.inTx && txOpts.isSet() {
+		return fmt.Errorf("nested transaction can not change isolation level or read-only mode: %w", errs.ErrInvalidArgument)
+	}
+
+	var sqlOpts []*sql.TxOptions
+	if txOpts.isSet() {
+		sqlOpts = append(sqlOpts, txOpts.sqlOptions())
+	}
+	hooks := f.hooks
+	if f.inTx {
+		// 保存点失败或 panic 时丢弃其中注册的回调
+		mark := hooks.Mark()
+		panicked := true
+		defer func() {
+			if panicked || err != nil {
+				hooks.Discard(mark)
+			}
+		}()
+		err = f.db.Transaction(func(tx *genericRepo.MemoryDB) error {
+			return fn(f.withTransaction(tx, hooks))
+		}, sqlOpts...)
+		panicked = false
+		return err
+	}
+
+	hooks = genericRepo.NewTxHooks()
+	if err := f.db.Transaction(func(tx *genericRepo.MemoryDB) error {
+		return fn(f.withTransaction(tx, hooks))
+	}, sqlOpts...); err != nil {
+		return err
+	}
+	hooks.Commit()
+	return nil
+}
+
+func (f *memoryRepoFactory) Source() Source {
+	return Source{memoryDB: f.db, repoOpts: f.repoOpts, caches: f.caches, inTx: f.inTx, hooks: f.hooks, logger: f.logger}
+}
+
+func (f *memoryRepoFactory) User() userRepo.UserRepo {
+	return userRepo.NewUserRepoFrom(For[model.User](f))
+}
+
+func (f *memoryRepoFactory) Order() orderRepo.OrderRepo {
+	return orderRepo.NewOrderRepoFrom(For[model.Order](f))
+}
+
+func (f *memoryRepoFactory) Product() productRepo.ProductRepo {
+	return productRepo.NewMemoryProductRepoFrom(For[model.Product](f))
+}
+
+// Audit 审计日志本身的写入不再记录审计
+func (f *memoryRepoFactory) Audit() auditRepo.AuditRepo {
+	return auditRepo.NewAuditRepoFrom(forModel[model.AuditLog](f, genericRepo.WithAudit(false)))
+}
+
+func (f *memoryRepoFactory) Outbox() outboxRepo.OutboxRepo {
+	return outboxRepo.NewMemoryOutboxRepoFrom(f.db, forModel[model.OutboxEvent](f, genericRepo.WithAudit(false)))
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"log/slog"
+	"slices"
+
+	cache "go-pattern/internal/cache/multilevel"
+	"go-pattern/internal/model"
+	"go-pattern/internal/registry"
+	genericRepo "go-pattern/internal/repo/generic"
+
+	"gorm.io/gorm"
+)
+
+// Source For 构造仓储所需的依赖,由本包的工厂提供
+type Source struct {
+	// db 与 memoryDB 只设置其一,决定构造 GORM 还是内存实现
+	db       *gorm.DB
+	memoryDB *genericRepo.MemoryDB
+	repoOpts []genericRepo.Option
+	caches   caches
+	// inTx 工厂绑定在事务上,缓存只失效不回填
+	inTx   bool
+	hooks  *genericRepo.TxHooks
+	logger *slog.Logger
+}
+
+// RepoSource 能为 For 提供依赖的工厂,本包的 RepoFactory 实现都满足该接口;
+// 其他包的工厂或装饰器可以嵌入它们来使用 For
+type RepoSource interface {
+	Source() Source
+}
+
+// For 返回已注册模型 T 的通用仓储。在事务工厂上调用时仓储绑定该事务,
+// 通过 WithCaches 启用了缓存的模型套上缓存装饰器(事务内只失效不回填);模型未注册时 panic
+func For[T any, PT model.PointerModel[T]](factory RepoSource) genericRepo.GenericRepo[T, PT] {
+	return forModel[T, PT](factory)
+}
+
+// forModel 同 For,extra 追加在工厂的仓储选项之后
+func forModel[T any, PT model.PointerModel[T]](factory RepoSource, extra ...genericRepo.Option) genericRepo.GenericRepo[T, PT] {
+	registry.MustLookup[T, PT]()
+	src := factory.Source()
+	opts := append(slices.Clone(src.repoOpts), extra...)
+	var repo genericRepo.GenericRepo[T, PT]
+	if src.memoryDB != nil {
+		repo = genericRepo.NewMemoryRepo[T, PT](src.memoryDB, opts...)
+	} else {
+		repo = genericRepo.NewGenericRepo[T, PT](src.db, opts...)
+	}
+	return withCache(repo, cacheOf[T, PT](src.caches), src.inTx, src.hooks, src.logger)
+}
+
+// CacheBinding 为一个模型启用的多级缓存,由 Cached 创建后传给 WithCaches
+type CacheBinding struct {
+	table string
+	cache any
+}
+
+// Cached 为已注册模型 T 的仓储启用多级缓存
+func Cached[T any, PT model.PointerModel[T]](c cache.MultiLevelCache[T]) CacheBinding {
+	return CacheBinding{table: registry.MustLookup[T, PT]().Table, cache: c}
+}
+
+// caches 按表名索引的多级缓存,未启用缓存的模型不在其中
+type caches map[string]any
+
+func newCaches(bindings []CacheBinding) caches {
+	c := make(caches, len(bindings))
+	for _, binding := range bindings {
+		c[binding.table] = binding.cache
+	}
+	return c
+}
+
+// cacheOf 返回模型 T 的多级缓存,未启用时返回 nil
+func cacheOf[T any, PT model.PointerModel[T]](c caches) cache.MultiLevelCache[T] {
+	mlc, _ := c[PT(new(T)).TableName()].(cache.MultiLevelCache[T])
+	return mlc
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"go-pattern/internal/model"
+	genericRepo "go-pattern/internal/repo/generic"
+	"go-pattern/internal/tenant"
+)
+
+// wrappedFactory 其他包中的工厂装饰器,同时嵌入 RepoSource 后 For 对其同样可用
+type wrappedFactory struct {
+	RepoFactory
+	RepoSource
+}
+
+type unregistered struct {
+	ID uint64
+}
+
+func (u *unregistered) GetID() uint64         { return u.ID }
+func (u *unregistered) GetPrimaryKey() string { return "id" }
+func (u *unregistered) TableName() string     { return "factory_test_unregistered" }
+
+func TestFor(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), 1)
+	memory := NewMemoryRepoFactory(genericRepo.NewMemoryDB())
+
+	tests := []struct {
+		name    string
+		factory interface {
+			RepoFactory
+			RepoSource
+		}
+	}{
+		{"memory factory", memory},
+		{"wrapped factory", wrappedFactory{memory, memory}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := For[model.Product](tt.factory)
+			product := &model.Product{Name: "apple", Quantity: 1}
+			if err := products.Create(ctx, product); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tt.factory.Product().GetByID(ctx, product.ID); err != nil {
+				t.Fatalf("product created through For is not visible to Product(): %v", err)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("For must panic for an unregistered model")
+		}
+	}()
+	For[unregistered](memory)
+}
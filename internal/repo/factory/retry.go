@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// pgSerializationFailure 可串行化隔离级别下的读写冲突
+	pgSerializationFailure = "40001"
+	// pgDeadlockDetected 死锁,Postgres 会中止其中一个事务
+	pgDeadlockDetected = "40P01"
+)
+
+// retryableCode 返回可以通过重试整个事务解决的错误码
+func retryableCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected) {
+		return pgErr.Code, true
+	}
+	return "", false
+}
+
+// RetryStats 事务重试统计
+type RetryStats struct {
+	// SerializationFailures 遇到的 40001 次数
+	SerializationFailures uint64
+	// Deadlocks 遇到的 40P01 次数
+	Deadlocks uint64
+	// Retries 实际发起的重试次数
+	Retries uint64
+	// Exhausted 达到最大次数后仍然失败的事务数
+	Exhausted uint64
+}
+
+type retryMetrics struct {
+	serializationFailures atomic.Uint64
+	deadlocks             atomic.Uint64
+	retries               atomic.Uint64
+	exhausted             atomic.Uint64
+}
+
+func (m *retryMetrics) snapshot() RetryStats {
+	return RetryStats{
+		SerializationFailures: m.serializationFailures.Load(),
+		Deadlocks:             m.deadlocks.Load(),
+		Retries:               m.retries.Load(),
+		Exhausted:             m.exhausted.Load(),
+	}
+}
+
+// RetryStats 返回工厂创建以来的事务重试统计
+func (f *repoFactory) RetryStats() RetryStats {
+	return f.metrics.snapshot()
+}
+
+// retry 按 opts 重试 fn,只有序列化失败与死锁会触发重试,
+// 等待时间为 [0, min(max, base*2^n)) 内的随机值
+func (f *repoFactory) retry(ctx context.Context, opts txOptions, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		code, ok := retryableCode(err)
+		if !ok {
+			return err
+		}
+		switch code {
+		case pgSerializationFailure:
+			f.metrics.serializationFailures.Add(1)
+		case pgDeadlockDetected:
+			f.metrics.deadlocks.Add(1)
+		}
+		if attempt >= opts.maxAttempts {
+			if opts.maxAttempts > 1 {
+				f.metrics.exhausted.Add(1)
+			}
+			return err
+		}
+
+		backoff := opts.maxBackoff
+		if shift := attempt - 1; shift < 30 && opts.baseBackoff<<shift < opts.maxBackoff {
+			backoff = opts.baseBackoff << shift
+		}
+		wait := time.Duration(rand.Int64N(int64(backoff) + 1))
+		f.logger.WarnContext(ctx, "transaction retry",
+			slog.String("code", code),
+			slog.Int("attempt", attempt),
+			slog.Int("max_retries", opts.maxAttempts-1),
+			slog.Duration("wait", wait),
+			slog.Any("error", err))
+		f.metrics.retries.Add(1)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
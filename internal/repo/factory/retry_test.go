@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	errSerialization = fmt.Errorf("commit: %w", &pgconn.PgError{Code: pgSerializationFailure})
+	errDeadlock      = fmt.Errorf("update: %w", &pgconn.PgError{Code: pgDeadlockDetected})
+	errUniqueKey     = &pgconn.PgError{Code: "23505"}
+)
+
+func TestRetryableCode(t *testing.T) {
+	if code, ok := retryableCode(errSerialization); !ok || code != pgSerializationFailure {
+		t.Errorf("retryableCode(serialization) = %q, %v", code, ok)
+	}
+	if code, ok := retryableCode(errDeadlock); !ok || code != pgDeadlockDetected {
+		t.Errorf("retryableCode(deadlock) = %q, %v", code, ok)
+	}
+	// 只识别 PgError 中的错误码,错误信息里出现 40001 不算
+	for _, err := range []error{nil, errUniqueKey, errors.New("40001")} {
+		if code, ok := retryableCode(err); ok {
+			t.Errorf("retryableCode(%v) = %q, must not retry", err, code)
+		}
+	}
+}
+
+// runRetry 依次返回 results 中的错误,返回实际调用次数
+func runRetry(f *repoFactory, maxAttempts int, results ...error) (int, error) {
+	opts := newTxOptions([]TxOption{WithRetry(maxAttempts), WithRetryBackoff(0, 0)})
+	calls := 0
+	err := f.retry(context.Background(), opts, func() error {
+		calls++
+		return results[calls-1]
+	})
+	return calls, err
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("retried then success", func(t *testing.T) {
+		f := NewRepoFactory(nil)
+		calls, err := runRetry(f, 3, errSerialization, errDeadlock, nil)
+		if err != nil || calls != 3 {
+			t.Fatalf("retry() = %d calls, %v", calls, err)
+		}
+		want := RetryStats{SerializationFailures: 1, Deadlocks: 1, Retries: 2}
+		if got := f.RetryStats(); got != want {
+			t.Fatalf("RetryStats() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		f := NewRepoFactory(nil)
+		calls, err := runRetry(f, 2, errDeadlock, errDeadlock)
+		if !errors.Is(err, errDeadlock) || calls != 2 {
+			t.Fatalf("retry() = %d calls, %v", calls, err)
+		}
+		want := RetryStats{Deadlocks: 2, Retries: 1, Exhausted: 1}
+		if got := f.RetryStats(); got != want {
+			t.Fatalf("RetryStats() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("retry disabled", func(t *testing.T) {
+		f := NewRepoFactory(nil)
+		calls, err := runRetry(f, 1, errSerialization)
+		if !errors.Is(err, errSerialization) || calls != 1 {
+			t.Fatalf("retry() = %d calls, %v", calls, err)
+		}
+		// 未开启重试时不计入 Exhausted
+		if got := f.RetryStats(); got != (RetryStats{SerializationFailures: 1}) {
+			t.Fatalf("RetryStats() = %+v", got)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		f := NewRepoFactory(nil)
+		calls, err := runRetry(f, 3, errUniqueKey)
+		if !errors.Is(err, errUniqueKey) || calls != 1 || f.RetryStats() != (RetryStats{}) {
+			t.Fatalf("retry() = %d calls, %v, stats %+v", calls, err, f.RetryStats())
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TxOption 事务选项,只对最外层事务生效
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	isolation sql.IsolationLevel
+	readOnly  bool
+	// maxAttempts 包含首次执行在内的最大执行次数,1 表示不重试
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+// WithIsolation 设置事务隔离级别,如 sql.LevelSerializable
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.isolation = level
+	}
+}
+
+// ReadOnly 开启只读事务
+func ReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.readOnly = true
+	}
+}
+
+// WithRetry 事务因序列化失败(40001)或死锁(40P01)失败时,以带抖动的指数退避重新执行整个函数,
+// 最多执行 maxAttempts 次。函数必须可以安全地重复执行,且只在最外层事务生效
+func WithRetry(maxAttempts int) TxOption {
+	return func(o *txOptions) {
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetryBackoff 设置重试退避:第n次重试前随机等待 [0, min(max, base*2^(n-1))]
+func WithRetryBackoff(base, max time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.baseBackoff = base
+		o.maxBackoff = max
+	}
+}
+
+func newTxOptions(opts []TxOption) txOptions {
+	o := txOptions{
+		maxAttempts: 1,
+		baseBackoff: 10 * time.Millisecond,
+		maxBackoff:  time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o txOptions) isSet() bool {
+	return o.isolation != sql.LevelDefault || o.readOnly
+}
+
+func (o txOptions) sqlOptions() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: o.isolation, ReadOnly: o.readOnly}
+}
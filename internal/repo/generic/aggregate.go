@@ -0,0 +1,357 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// AggregateFunc 聚合函数
+type AggregateFunc string
+
+const (
+	AggCount AggregateFunc = "COUNT"
+	AggSum   AggregateFunc = "SUM"
+	AggAvg   AggregateFunc = "AVG"
+	AggMin   AggregateFunc = "MIN"
+	AggMax   AggregateFunc = "MAX"
+)
+
+// Metric 一个聚合指标,结果以 alias 为键返回
+type Metric struct {
+	fn     AggregateFunc
+	fields []string
+	alias  string
+}
+
+// CountAll COUNT(*)
+func CountAll(alias string) Metric { return Metric{fn: AggCount, alias: alias} }
+
+// CountOf 统计 field 非空的行数
+func CountOf(alias, field string) Metric {
+	return Metric{fn: AggCount, fields: []string{field}, alias: alias}
+}
+
+// SumOf 求和,传入多个字段时对它们的乘积求和,如 SumOf("stock_value", "price", "quantity")
+func SumOf(alias string, fields ...string) Metric {
+	return Metric{fn: AggSum, fields: fields, alias: alias}
+}
+
+// AvgOf 求平均值,多个字段时对乘积求平均
+func AvgOf(alias string, fields ...string) Metric {
+	return Metric{fn: AggAvg, fields: fields, alias: alias}
+}
+
+func MinOf(alias, field string) Metric {
+	return Metric{fn: AggMin, fields: []string{field}, alias: alias}
+}
+
+func MaxOf(alias, field string) Metric {
+	return Metric{fn: AggMax, fields: []string{field}, alias: alias}
+}
+
+// TimeBucket 时间分组粒度
+type TimeBucket string
+
+const (
+	BucketHour  TimeBucket = "hour"
+	BucketDay   TimeBucket = "day"
+	BucketWeek  TimeBucket = "week"
+	BucketMonth TimeBucket = "month"
+)
+
+// Group 分组键:列本身,或时间列按粒度截断后的值
+type Group struct {
+	field  string
+	bucket TimeBucket
+	alias  string
+}
+
+// GroupBy 按列分组,结果以列名为键
+func GroupBy(field string) Group { return Group{field: field, alias: field} }
+
+// GroupByTime 按时间列截断到 bucket 后分组,如按天统计
+func GroupByTime(field string, bucket TimeBucket, alias string) Group {
+	return Group{field: field, bucket: bucket, alias: alias}
+}
+
+// AggregateQuery 聚合查询
+type AggregateQuery struct {
+	// Spec 只使用其过滤条件
+	Spec    *Spec
+	GroupBy []Group
+	Metrics []Metric
+	// Having 对聚合结果过滤,条件的字段为指标别名
+	Having []Cond
+	// OrderBy 按分组或指标的别名排序
+	OrderBy []Sort
+	Limit   int
+}
+
+// AggregateRow 一行聚合结果,以分组与指标的别名为键
+type AggregateRow map[string]any
+
+// Int 读取整数结果,NULL 或无法转换时返回 0
+func (r AggregateRow) Int(key string) int64 {
+	switch v := r[key].(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case []byte:
+		n, _ := strconv.ParseInt(string(v), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// Uint 读取无符号整数结果,如ID分组键
+func (r AggregateRow) Uint(key string) uint64 {
+	return uint64(r.Int(key))
+}
+
+// Float 读取数值结果,numeric 类型以字符串返回时同样可以解析
+func (r AggregateRow) Float(key string) float64 {
+	switch v := r[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int64, int32, int, uint64:
+		return float64(r.Int(key))
+	case []byte:
+		f, _ := strconv.ParseFloat(string(v), 64)
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+// Time 读取时间结果,SQLite 的时间分组键为本地时间文本
+func (r AggregateRow) Time(key string) time.Time {
+	switch v := r[key].(type) {
+	case time.Time:
+		return v
+	case string:
+		t, _ := time.ParseInLocation(time.DateTime, v, time.Local)
+		return t
+	}
+	return time.Time{}
+}
+
+func (r AggregateRow) String(key string) string {
+	switch v := r[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// aliasPattern 别名直接拼入SQL,只允许小写标识符
+var aliasPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+var aggregateFuncs = map[AggregateFunc]bool{AggCount: true, AggSum: true, AggAvg: true, AggMin: true, AggMax: true}
+
+var timeBuckets = map[TimeBucket]bool{BucketHour: true, BucketDay: true, BucketWeek: true, BucketMonth: true}
+
+func (g Group) expr(columns map[string]*schema.Field, dialect Dialect) (string, error) {
+	field, ok := columns[g.field]
+	if !ok {
+		return "", fmt.Errorf("unknown group column %q", g.field)
+	}
+	if g.bucket == "" {
+		return g.field, nil
+	}
+	if !timeBuckets[g.bucket] {
+		return "", fmt.Errorf("unsupported time bucket %q", g.bucket)
+	}
+	if field.DataType != schema.Time {
+		return "", fmt.Errorf("column %q is not a time column", g.field)
+	}
+	return dialect.truncTime(g.field, g.bucket), nil
+}
+
+func (m Metric) expr(columns map[string]*schema.Field) (string, error) {
+	if !aggregateFuncs[m.fn] {
+		return "", fmt.Errorf("unsupported aggregate function %q", m.fn)
+	}
+	if len(m.fields) == 0 {
+		if m.fn != AggCount {
+			return "", fmt.Errorf("%s requires a column", m.fn)
+		}
+		return "COUNT(*)", nil
+	}
+	if len(m.fields) > 1 && m.fn != AggSum && m.fn != AggAvg {
+		return "", fmt.Errorf("%s accepts a single column", m.fn)
+	}
+	for _, field := range m.fields {
+		if _, ok := columns[field]; !ok {
+			return "", fmt.Errorf("unknown aggregate column %q", field)
+		}
+	}
+	return fmt.Sprintf("%s(%s)", m.fn, strings.Join(m.fields, " * ")), nil
+}
+
+func (r *genericRepo[T, PT]) Aggregate(ctx context.Context, query AggregateQuery) ([]AggregateRow, error) {
+	pt := PT(new(T))
+
+	rows, err := r.aggregate(ctx, pt, query)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate %s failed: %w", pt.TableName(), err)
+	}
+	return rows, nil
+}
+
+// aggregatePlan 校验后的聚合查询:SELECT 列表、分组表达式,以及别名到表达式的映射
+type aggregatePlan struct {
+	selects []string
+	groups  []string
+	aliases map[string]string
+	metrics map[string]string
+}
+
+// planAggregate 按白名单校验分组、指标、HAVING 与排序,HAVING 的字段只能是指标别名
+func planAggregate(columns map[string]*schema.Field, query AggregateQuery, dialect Dialect) (*aggregatePlan, error) {
+	if len(query.Metrics) == 0 {
+		return nil, fmt.Errorf("at least one metric is required: %w", errs.ErrInvalidArgument)
+	}
+	if query.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative: %w", errs.ErrInvalidArgument)
+	}
+
+	plan := &aggregatePlan{
+		selects: make([]string, 0, len(query.GroupBy)+len(query.Metrics)),
+		groups:  make([]string, 0, len(query.GroupBy)),
+		aliases: make(map[string]string, len(query.GroupBy)+len(query.Metrics)),
+		metrics: make(map[string]string, len(query.Metrics)),
+	}
+	addAlias := func(alias, expr string) error {
+		if !aliasPattern.MatchString(alias) {
+			return fmt.Errorf("invalid alias %q: %w", alias, errs.ErrInvalidArgument)
+		}
+		if _, ok := plan.aliases[alias]; ok {
+			return fmt.Errorf("duplicate alias %q: %w", alias, errs.ErrInvalidArgument)
+		}
+		plan.aliases[alias] = expr
+		plan.selects = append(plan.selects, fmt.Sprintf("%s AS %s", expr, alias))
+		return nil
+	}
+	for _, group := range query.GroupBy {
+		expr, err := group.expr(columns, dialect)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+		}
+		if err := addAlias(group.alias, expr); err != nil {
+			return nil, err
+		}
+		plan.groups = append(plan.groups, expr)
+	}
+	for _, metric := range query.Metrics {
+		expr, err := metric.expr(columns)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+		}
+		if err := addAlias(metric.alias, expr); err != nil {
+			return nil, err
+		}
+		plan.metrics[metric.alias] = expr
+	}
+	if err := query.Spec.checkWhere(columns); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w: %w", errs.ErrInvalidArgument, err)
+	}
+	for _, cond := range query.Having {
+		if _, _, err := plan.having(cond); err != nil {
+			return nil, fmt.Errorf("invalid having: %w: %w", errs.ErrInvalidArgument, err)
+		}
+	}
+	for _, sort := range query.OrderBy {
+		if _, ok := plan.aliases[sort.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort alias %q: %w", sort.Field, errs.ErrInvalidArgument)
+		}
+	}
+	return plan, nil
+}
+
+// having 将 HAVING 条件中的指标别名替换为聚合表达式
+func (p *aggregatePlan) having(cond Cond) (string, []any, error) {
+	return cond.buildWith(func(alias string) (string, bool) {
+		expr, ok := p.metrics[alias]
+		return expr, ok
+	})
+}
+
+func (r *genericRepo[T, PT]) aggregate(ctx context.Context, pt PT, query AggregateQuery) ([]AggregateRow, error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("parse schema error: %w", err)
+	}
+	columns := sch.FieldsByDBName
+	plan, err := planAggregate(columns, query, DialectOf(r.db))
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := query.Spec.applyWhere(r.reader(ctx).Model(pt), columns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec: %w: %w", errs.ErrInvalidArgument, err)
+	}
+	db = db.Select(strings.Join(plan.selects, ", "))
+	if len(plan.groups) > 0 {
+		db = db.Group(strings.Join(plan.groups, ", "))
+	}
+	for _, cond := range query.Having {
+		sql, vars, err := plan.having(cond)
+		if err != nil {
+			return nil, fmt.Errorf("invalid having: %w: %w", errs.ErrInvalidArgument, err)
+		}
+		db = db.Having(sql, vars...)
+	}
+	for _, sort := range query.OrderBy {
+		if sort.Desc {
+			db = db.Order(sort.Field + " DESC")
+		} else {
+			db = db.Order(sort.Field + " ASC")
+		}
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	results := make([]map[string]any, 0)
+	if err := db.Scan(&results).Error; err != nil {
+		return nil, translateError(err)
+	}
+	rows := make([]AggregateRow, len(results))
+	for i, result := range results {
+		// SQLite 的表达式列没有声明类型,gorm 以 *interface{} 返回
+		for key, value := range result {
+			if p, ok := value.(*any); ok {
+				result[key] = *p
+			}
+		}
+		rows[i] = result
+	}
+	return rows, nil
+}
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-pattern/internal/audit"
+	"go-pattern/internal/model"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// mutate 执行一次写操作。开启审计时,写操作与审计日志在同一事务中完成:
+// targets 给出受影响的记录ID用于写前快照,fn 返回写后需要快照的ID(为空时沿用 targets)。
+// 未开启审计时 targets 不会被调用
+// 上下文中已有事务时在其中执行,审计的事务退化为保存点
+func (r *genericRepo[T, PT]) mutate(ctx context.Context, action string, targets func(db *gorm.DB) ([]uint64, error), fn func(db *gorm.DB) ([]uint64, error)) error {
+	if !r.opts.audit {
+		_, err := fn(r.conn(ctx))
+		return err
+	}
+	return r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var (
+			ids     []uint64
+			before  map[uint64]model.JSON
+			tenants = make(map[uint64]uint64)
+			err     error
+		)
+		if targets != nil {
+			if ids, err = targets(tx); err != nil {
+				return err
+			}
+			if before, err = r.snapshot(ctx, tx, ids, tenants); err != nil {
+				return err
+			}
+		}
+		changed, err := fn(tx)
+		if err != nil {
+			return err
+		}
+		if changed != nil {
+			ids = changed
+		}
+		after, err := r.snapshot(ctx, tx, ids, tenants)
+		if err != nil {
+			return err
+		}
+		return r.record(ctx, tx, action, ids, before, after, tenants)
+	})
+}
+
+// snapshot 以JSON保存记录当前状态,包含已软删除的记录;不存在或其他租户的记录没有快照。
+// 记录所属的租户写入 tenants
+func (r *genericRepo[T, PT]) snapshot(ctx context.Context, db *gorm.DB, ids []uint64, tenants map[uint64]uint64) (map[uint64]model.JSON, error) {
+	snapshots := make(map[uint64]model.JSON, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+	var model T
+	sch, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	ptrModels := make([]PT, 0, len(ids))
+	err = db.Unscoped().
+		Scopes(r.tenantScope(ctx)).
+		Where(fmt.Sprintf("%s IN ?", PT(&model).GetPrimaryKey()), ids).
+		Find(&ptrModels).Error
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s failed: %w", PT(&model).TableName(), err)
+	}
+	for _, ptrModel := range ptrModels {
+		tenants[ptrModel.GetID()] = tenantOf(ctx, sch, reflect.ValueOf(ptrModel))
+		data, err := json.Marshal(ptrModel)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s failed, marshal error: %w", ptrModel.TableName(), err)
+		}
+		snapshots[ptrModel.GetID()] = data
+	}
+	return snapshots, nil
+}
+
+func (r *genericRepo[T, PT]) record(ctx context.Context, db *gorm.DB, action string, ids []uint64, before, after map[uint64]model.JSON, tenants map[uint64]uint64) error {
+	table := PT(new(T)).TableName()
+	logs := auditLogs(ctx, table, action, ids, before, after, tenants)
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		return fmt.Errorf("record audit log for %s failed: %w", table, err)
+	}
+	return nil
+}
+
+// auditLogs 为每条有快照的记录生成一条审计日志,不存在的记录写前写后都没有快照,不记录。
+// 审计日志归属被修改记录的租户,Bypass 下跨租户的修改也记在各自租户下
+func auditLogs(ctx context.Context, table, action string, ids []uint64, before, after map[uint64]model.JSON, tenants map[uint64]uint64) []*model.AuditLog {
+	actor := audit.ActorFrom(ctx)
+	logs := make([]*model.AuditLog, 0, len(ids))
+	for _, id := range ids {
+		if before[id] == nil && after[id] == nil {
+			continue
+		}
+		logs = append(logs, &model.AuditLog{
+			TenantID:    tenants[id],
+			EntityTable: table,
+			EntityID:    id,
+			Action:      action,
+			Actor:       actor,
+			Before:      before[id],
+			After:       after[id],
+		})
+	}
+	return logs
+}
+
+// staticIDs 返回固定ID列表的 targets
+func staticIDs(ids ...uint64) func(db *gorm.DB) ([]uint64, error) {
+	return func(db *gorm.DB) ([]uint64, error) {
+		return ids, nil
+	}
+}
@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// bulkChunkSize 按ID分批执行批量写,避免超出驱动的占位符数量上限
+const bulkChunkSize = 1000
+
+// BulkOption 批量更新与删除的可选项
+type BulkOption func(*bulkOptions)
+
+type bulkOptions struct {
+	force      bool
+	onAffected []func(ids []uint64)
+}
+
+// ForceAll 允许过滤条件为空,此时作用于(当前租户的)全部记录
+func ForceAll() BulkOption {
+	return func(o *bulkOptions) {
+		o.force = true
+	}
+}
+
+// WithAffectedIDs 写操作成功后以命中记录的ID回调 fn,供缓存失效等场景使用;可多次传入。
+// 在事务中时 fn 在最外层事务提交后执行,事务回滚时不执行
+func WithAffectedIDs(fn func(ids []uint64)) BulkOption {
+	return func(o *bulkOptions) {
+		o.onAffected = append(o.onAffected, fn)
+	}
+}
+
+func newBulkOptions(opts []BulkOption) bulkOptions {
+	var o bulkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// checkFilter 空过滤条件只有 ForceAll 时允许
+func (o bulkOptions) checkFilter(filter *Spec) error {
+	if !o.force && (filter == nil || len(filter.conds) == 0) {
+		return fmt.Errorf("empty filter requires ForceAll: %w", errs.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// bulkUpdates 校验批量更新的列,乐观锁模型同时递增版本号
+func bulkUpdates(sch *schema.Schema, pt model.Model, changes map[string]any) (map[string]any, error) {
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes provided: %w", errs.ErrInvalidArgument)
+	}
+	writable := writableColumns(sch)
+	updates := make(map[string]any, len(changes)+1)
+	for column, value := range changes {
+		if !writable[column] {
+			return nil, fmt.Errorf("column %q is not writable: %w", column, errs.ErrInvalidArgument)
+		}
+		updates[column] = value
+	}
+	// 乐观锁模型递增版本号,持有旧版本的并发修改会得到冲突错误
+	if _, isVersioned := pt.(model.VersionedModel); isVersioned {
+		updates["version"] = gorm.Expr("version + 1")
+	}
+	return updates, nil
+}
+
+// bulk 先按过滤条件查出命中记录的ID,再分批执行 write;
+// write 的条件同时包含过滤条件与ID,查询与写入之间不再满足条件的记录不会被改动
+func (r *genericRepo[T, PT]) bulk(ctx context.Context, action, verb string, filter *Spec, opts []BulkOption, write func(db *gorm.DB) (int64, error)) (int64, error) {
+	pt := PT(new(T))
+
+	o := newBulkOptions(opts)
+	if err := o.checkFilter(filter); err != nil {
+		return 0, fmt.Errorf("%s %s where failed, %w", verb, pt.TableName(), err)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("%s %s where failed, parse schema error: %w", verb, pt.TableName(), err)
+	}
+	where := func(db *gorm.DB) (*gorm.DB, error) {
+		query, err := filter.applyWhere(db.Scopes(r.tenantScope(ctx)).Model(pt), sch.FieldsByDBName)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s where failed, invalid filter: %w: %w", verb, pt.TableName(), errs.ErrInvalidArgument, err)
+		}
+		return query, nil
+	}
+	// 校验过滤条件,非法时不进入事务
+	if _, err := where(r.db); err != nil {
+		return 0, err
+	}
+
+	var (
+		ids       []uint64
+		collected bool
+	)
+	collect := func(db *gorm.DB) ([]uint64, error) {
+		collected, ids = true, nil
+		query, err := where(db)
+		if err != nil {
+			return nil, err
+		}
+		if err := query.Pluck(pt.GetPrimaryKey(), &ids).Error; err != nil {
+			return nil, fmt.Errorf("%s %s where failed: %w", verb, pt.TableName(), translateError(err))
+		}
+		return ids, nil
+	}
+
+	var affected int64
+	err = r.mutate(ctx, action, collect, func(db *gorm.DB) ([]uint64, error) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if !collected {
+				if _, err := collect(tx); err != nil {
+					return err
+				}
+			}
+			for start := 0; start < len(ids); start += bulkChunkSize {
+				chunk := ids[start:min(start+bulkChunkSize, len(ids))]
+				query, err := where(tx)
+				if err != nil {
+					return err
+				}
+				rows, err := write(query.Where(fmt.Sprintf("%s IN ?", pt.GetPrimaryKey()), chunk))
+				if err != nil {
+					return fmt.Errorf("%s %s where failed: %w", verb, pt.TableName(), translateError(err))
+				}
+				affected += rows
+			}
+			return nil
+		})
+		return ids, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	// 在事务中时提交后才回调,回调中的缓存失效不会被并发读取以未提交前的数据回填
+	if len(ids) > 0 && len(o.onAffected) > 0 {
+		AfterCommit(ctx, r.opts.txHooks, func() {
+			for _, fn := range o.onAffected {
+				fn(ids)
+			}
+		})
+	}
+	return affected, nil
+}
+
+func (r *genericRepo[T, PT]) UpdateWhere(ctx context.Context, filter *Spec, changes map[string]any, opts ...BulkOption) (int64, error) {
+	pt := PT(new(T))
+
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("update %s where failed, parse schema error: %w", pt.TableName(), err)
+	}
+	updates, err := bulkUpdates(sch, pt, changes)
+	if err != nil {
+		return 0, fmt.Errorf("update %s where failed, %w", pt.TableName(), err)
+	}
+
+	return r.bulk(ctx, model.AuditActionUpdate, "update", filter, opts, func(db *gorm.DB) (int64, error) {
+		result := db.Updates(updates)
+		return result.RowsAffected, result.Error
+	})
+}
+
+func (r *genericRepo[T, PT]) DeleteWhere(ctx context.Context, filter *Spec, opts ...BulkOption) (int64, error) {
+	pt := PT(new(T))
+
+	return r.bulk(ctx, model.AuditActionDelete, "delete", filter, opts, func(db *gorm.DB) (int64, error) {
+		result := db.Delete(pt)
+		return result.RowsAffected, result.Error
+	})
+}
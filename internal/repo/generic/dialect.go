@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dialect 仓储依赖的数据库方言,只覆盖 postgres 与 sqlite 之间不可移植的部分
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// sqliteNow SQLite 中时间以 UTC 文本保存(驱动写入前转换为 UTC),按文本比较即可
+const sqliteNow = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
+
+// DialectOf 返回连接使用的方言,未知驱动按 postgres 处理
+func DialectOf(db *gorm.DB) Dialect {
+	if db.Dialector.Name() == string(DialectSQLite) {
+		return DialectSQLite
+	}
+	return DialectPostgres
+}
+
+// Now 数据库当前时间
+func (d Dialect) Now() clause.Expr {
+	if d == DialectSQLite {
+		return clause.Expr{SQL: sqliteNow}
+	}
+	return clause.Expr{SQL: "CURRENT_TIMESTAMP"}
+}
+
+// NowPlus 数据库当前时间加上 delay
+func (d Dialect) NowPlus(delay time.Duration) clause.Expr {
+	if d == DialectSQLite {
+		return clause.Expr{
+			SQL:  "strftime('%Y-%m-%d %H:%M:%f', 'now', ?)",
+			Vars: []any{fmt.Sprintf("%+.3f seconds", delay.Seconds())},
+		}
+	}
+	return clause.Expr{SQL: "CURRENT_TIMESTAMP + ? * INTERVAL '1 millisecond'", Vars: []any{delay.Milliseconds()}}
+}
+
+// RowLocking 是否支持 SELECT ... FOR UPDATE。SQLite 的写事务持有库级写锁,不需要也不支持行锁
+func (d Dialect) RowLocking() bool {
+	return d == DialectPostgres
+}
+
+// truncTime 将时间列截断到 bucket
+func (d Dialect) truncTime(column string, bucket TimeBucket) string {
+	if d != DialectSQLite {
+		return fmt.Sprintf("date_trunc('%s', %s)", bucket, column)
+	}
+	// 库中为 UTC 时间,与 postgres 一样按本地时间分桶;周以周一为起点与 date_trunc 一致
+	switch bucket {
+	case BucketHour:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s, 'localtime')", column)
+	case BucketWeek:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, 'localtime', 'weekday 0', '-6 days')", column)
+	case BucketMonth:
+		return fmt.Sprintf("strftime('%%Y-%%m-01 00:00:00', %s, 'localtime')", column)
+	default:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, 'localtime')", column)
+	}
+}
+
+// BeginReadOnly 让事务只读。SQLite 驱动忽略 sql.TxOptions.ReadOnly,改用连接级的 PRAGMA query_only,
+// 调用方须在提交或回滚前调用返回的函数恢复连接,恢复失败时连接仍为只读,应让事务失败;
+// postgres 的只读由事务选项保证,这里什么都不做
+func BeginReadOnly(tx *gorm.DB) (func() error, error) {
+	if DialectOf(tx) != DialectSQLite {
+		return func() error { return nil }, nil
+	}
+	if err := tx.Exec("PRAGMA query_only = ON").Error; err != nil {
+		return nil, err
+	}
+	return func() error { return tx.Exec("PRAGMA query_only = OFF").Error }, nil
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// readonlyColumns 由数据库或仓储维护,不允许通过字段掩码修改
+var readonlyColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	"version":    true,
+	"tenant_id":  true,
+}
+
+// writableColumns 模型中可以通过 UpdateFields 修改的列
+func writableColumns(sch *schema.Schema) map[string]bool {
+	columns := make(map[string]bool, len(sch.DBNames))
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.PrimaryKey || !field.Updatable || readonlyColumns[field.DBName] {
+			continue
+		}
+		columns[field.DBName] = true
+	}
+	return columns
+}
+
+// fieldUpdates 校验字段掩码并生成更新内容;乐观锁模型的 values["version"] 为期望版本,
+// 无论是否提供都递增版本号
+func fieldUpdates(sch *schema.Schema, pt model.Model, fieldMask []string, values map[string]any) (updates map[string]any, expected any, hasExpected bool, err error) {
+	_, isVersioned := pt.(model.VersionedModel)
+	writable := writableColumns(sch)
+	updates = make(map[string]any, len(fieldMask)+1)
+	for _, column := range fieldMask {
+		if !writable[column] {
+			return nil, nil, false, fmt.Errorf("column %q is not writable: %w", column, errs.ErrInvalidArgument)
+		}
+		value, ok := values[column]
+		if !ok {
+			return nil, nil, false, fmt.Errorf("missing value for column %q: %w", column, errs.ErrInvalidArgument)
+		}
+		updates[column] = value
+	}
+	for column := range values {
+		if _, ok := updates[column]; !ok && !(isVersioned && column == "version") {
+			return nil, nil, false, fmt.Errorf("column %q is not in fieldMask: %w", column, errs.ErrInvalidArgument)
+		}
+	}
+	expected, hasExpected = values["version"]
+	if isVersioned {
+		updates["version"] = gorm.Expr("version + 1")
+	}
+	return updates, expected, hasExpected, nil
+}
+
+func (r *genericRepo[T, PT]) UpdateFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) error {
+	pt := PT(new(T))
+
+	if id == 0 || len(fieldMask) == 0 {
+		return fmt.Errorf("update %s fields failed, id and fieldMask are required: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("update %s fields failed, parse schema error: %w", pt.TableName(), err)
+	}
+
+	updates, expected, hasExpected, err := fieldUpdates(sch, pt, fieldMask, values)
+	if err != nil {
+		return fmt.Errorf("update %s fields failed, %w", pt.TableName(), err)
+	}
+	_, isVersioned := any(pt).(model.VersionedModel)
+
+	return r.mutate(ctx, model.AuditActionUpdate, staticIDs(id), func(db *gorm.DB) ([]uint64, error) {
+		query := db.
+			Scopes(r.tenantScope(ctx)).
+			Model(pt).
+			Where(fmt.Sprintf("%s = ?", pt.GetPrimaryKey()), id)
+		if isVersioned && hasExpected {
+			query = query.Where("version = ?", expected)
+		}
+		result := query.Updates(updates)
+		if result.Error != nil {
+			return nil, fmt.Errorf("update %s fields %v by id %d failed: %w", pt.TableName(), fieldMask, id, translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			if isVersioned && hasExpected {
+				var count int64
+				if err := db.Scopes(r.tenantScope(ctx)).Model(pt).Where(fmt.Sprintf("%s = ?", pt.GetPrimaryKey()), id).Count(&count).Error; err != nil {
+					return nil, fmt.Errorf("update %s fields by id %d failed, check version error: %w", pt.TableName(), id, translateError(err))
+				}
+				if count > 0 {
+					return nil, fmt.Errorf("update %s fields by id %d with version %v failed: %w", pt.TableName(), id, expected, errs.ErrVersionConflict)
+				}
+			}
+			return nil, fmt.Errorf("update %s fields by id %d failed: %w", pt.TableName(), id, errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+}
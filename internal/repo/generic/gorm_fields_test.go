@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+)
+
+func TestFieldUpdates(t *testing.T) {
+	sch, err := memorySchema[model.Product]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 版本化模型每次部分更新都会递增 version
+	updates, _, hasExpected, err := fieldUpdates(sch, &model.Product{}, []string{"name", "quantity"}, map[string]any{"name": "a", "quantity": 0})
+	if err != nil || hasExpected {
+		t.Fatalf("fieldUpdates() = %v, %v", hasExpected, err)
+	}
+	if got := slices.Sorted(maps.Keys(updates)); !slices.Equal(got, []string{"name", "quantity", "version"}) {
+		t.Fatalf("fieldUpdates() columns = %v", got)
+	}
+
+	// values 中的 version 是期望的旧版本,不在掩码中也允许传入
+	_, expected, hasExpected, err := fieldUpdates(sch, &model.Product{}, []string{"name"}, map[string]any{"name": "a", "version": 2})
+	if err != nil || !hasExpected || expected != 2 {
+		t.Fatalf("fieldUpdates() expected = %v, %v, %v", expected, hasExpected, err)
+	}
+
+	rejected := map[string]struct {
+		mask   []string
+		values map[string]any
+	}{
+		"readonly tenant":    {[]string{"tenant_id"}, map[string]any{"tenant_id": 2}},
+		"readonly version":   {[]string{"version"}, map[string]any{"version": 2}},
+		"primary key":        {[]string{"id"}, map[string]any{"id": 2}},
+		"unknown column":     {[]string{"nope"}, map[string]any{"nope": 1}},
+		"missing value":      {[]string{"name"}, map[string]any{}},
+		"value outside mask": {[]string{"name"}, map[string]any{"name": "a", "price": 1}},
+	}
+	for name, c := range rejected {
+		if _, _, _, err := fieldUpdates(sch, &model.Product{}, c.mask, c.values); !errors.Is(err, errs.ErrInvalidArgument) {
+			t.Errorf("%s: fieldUpdates() error = %v, want ErrInvalidArgument", name, err)
+		}
+	}
+}
@@ -2,126 +2,197 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go-pattern/internal/errs"
 	"go-pattern/internal/model"
-	"log"
+	"reflect"
+	"sync"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
+// schemaCache 缓存模型解析结果,所有genericRepo实例共享
+var schemaCache = &sync.Map{}
+
 type genericRepo[T any, PT model.PointerModel[T]] struct {
-	db *gorm.DB
+	db   *gorm.DB
+	opts options
+}
+
+func NewGenericRepo[T any, PT model.PointerModel[T]](db *gorm.DB, opts ...Option) GenericRepo[T, PT] {
+	r := &genericRepo[T, PT]{db: db}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if len(r.opts.interceptors) > 0 {
+		return newInterceptedRepo[T, PT](r, r.opts.interceptors)
+	}
+	return r
 }
 
-func NewGenericRepo[T any, PT model.PointerModel[T]](db *gorm.DB) GenericRepo[T, PT] {
-	return &genericRepo[T, PT]{db: db}
+// schema 解析模型结构,其列集合作为Spec的字段白名单
+func (r *genericRepo[T, PT]) schema() (*schema.Schema, error) {
+	var model T
+	return schema.Parse(PT(&model), schemaCache, r.db.NamingStrategy)
+}
+
+// query 返回应用了查询选项的读连接,选项不合法时返回 ErrInvalidArgument
+func (r *genericRepo[T, PT]) query(ctx context.Context, opts []QueryOption) (*gorm.DB, error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("parse schema error: %w", err)
+	}
+	db, err := applyQueryOptions(ctx, r.reader(ctx), sch, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+	}
+	return db, nil
+}
+
+// softDeletable 模型是否声明了 gorm.DeletedAt 字段
+func (r *genericRepo[T, PT]) softDeletable() (bool, error) {
+	sch, err := r.schema()
+	if err != nil {
+		return false, err
+	}
+	field := sch.LookUpField("deleted_at")
+	return field != nil && field.FieldType == reflect.TypeOf(gorm.DeletedAt{}), nil
+}
+
+// translateError 将gorm错误归类为errs中的哨兵错误,同时保留原始错误链
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", errs.ErrNotFound, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %w", errs.ErrConflict, err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated), errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+	default:
+		return err
+	}
 }
 
 func (r *genericRepo[T, PT]) Create(ctx context.Context, ptrModel PT) error {
 	if ptrModel == nil {
-		return fmt.Errorf("create %s failed, ptrModel is nil", ptrModel.TableName())
+		return fmt.Errorf("create %s failed, ptrModel is nil: %w", ptrModel.TableName(), errs.ErrInvalidArgument)
 	}
 
-	fmt.Printf("type ptrModel: %T", ptrModel)
-
-	result := r.db.WithContext(ctx).
-		Create(ptrModel)
-	if result.Error != nil {
-		log.Printf("create %s failed, error: %v", ptrModel.TableName(), result.Error)
-		return fmt.Errorf("create %s failed, error: %v", ptrModel.TableName(), result.Error)
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("create %s failed, parse schema error: %w", ptrModel.TableName(), err)
 	}
-	if result.RowsAffected == 0 {
-		log.Printf("create %s failed, no rows affected", ptrModel.TableName())
-		return fmt.Errorf("create %s failed, no rows affected", ptrModel.TableName())
+	if err := r.assignTenant(ctx, sch, ptrModel); err != nil {
+		return fmt.Errorf("create %s failed: %w", ptrModel.TableName(), err)
 	}
-	return nil
+
+	return r.mutate(ctx, model.AuditActionCreate, nil, func(db *gorm.DB) ([]uint64, error) {
+		// 关联只用于读取,写操作不级联保存关联记录
+		result := db.Omit(clause.Associations).Create(ptrModel)
+		if result.Error != nil {
+			return nil, fmt.Errorf("create %s failed: %w", ptrModel.TableName(), translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("create %s failed, no rows affected", ptrModel.TableName())
+		}
+		return []uint64{ptrModel.GetID()}, nil
+	})
 }
 
 func (r *genericRepo[T, PT]) CreateInBatches(ctx context.Context, ptrModels []PT, batchSize int) error {
 	// 检查ptrModels是否为空
 	if len(ptrModels) == 0 || batchSize <= 0 {
-		var model T
-		ptr := PT(&model)
-		log.Printf("create %s in batchs failed, no models provided", ptr.TableName())
-		return nil
-	}
-	result := r.db.WithContext(ctx).
-		CreateInBatches(ptrModels, batchSize)
-	if result.Error != nil {
-		log.Printf("create %s in batchs failed, error: %v", ptrModels[0].TableName(), result.Error)
-		return fmt.Errorf("create %s in batchs failed, error: %v", ptrModels[0].TableName(), result.Error)
-	}
-	if result.RowsAffected == 0 {
-		log.Printf("create %s in batchs failed, no rows affected", ptrModels[0].TableName())
-		return fmt.Errorf("create %s in batchs failed, no rows affected", ptrModels[0].TableName())
-	}
-	return nil
+		ptr := PT(new(T))
+		return fmt.Errorf("create %s in batchs failed, no models provided or batchSize %d invalid: %w", ptr.TableName(), batchSize, errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("create %s in batchs failed, parse schema error: %w", ptrModels[0].TableName(), err)
+	}
+	if err := r.assignTenant(ctx, sch, ptrModels...); err != nil {
+		return fmt.Errorf("create %s in batchs failed: %w", ptrModels[0].TableName(), err)
+	}
+
+	return r.mutate(ctx, model.AuditActionCreate, nil, func(db *gorm.DB) ([]uint64, error) {
+		result := db.Omit(clause.Associations).CreateInBatches(ptrModels, batchSize)
+		if result.Error != nil {
+			return nil, fmt.Errorf("create %s in batchs failed: %w", ptrModels[0].TableName(), translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("create %s in batchs failed, no rows affected", ptrModels[0].TableName())
+		}
+		ids := make([]uint64, 0, len(ptrModels))
+		for _, ptrModel := range ptrModels {
+			ids = append(ids, ptrModel.GetID())
+		}
+		return ids, nil
+	})
 }
 
-func (r *genericRepo[T, PT]) GetByID(ctx context.Context, id uint64) (PT, error) {
+func (r *genericRepo[T, PT]) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (PT, error) {
 	var model T
 	ptrModel := PT(&model)
 
 	if id == 0 {
-		log.Printf("get %s by id %d failed, id must be greater than 0", ptrModel.TableName(), id)
-		return nil, nil
+		return nil, fmt.Errorf("get %s by id %d failed, id must be greater than 0: %w", ptrModel.TableName(), id, errs.ErrInvalidArgument)
+	}
+	query, err := r.query(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by id %d failed: %w", ptrModel.TableName(), id, err)
 	}
 
-	result := r.db.WithContext(ctx).
+	result := query.
 		Where(fmt.Sprintf("%s = ?", ptrModel.GetPrimaryKey()), id).
 		First(ptrModel)
 	if result.Error != nil {
-		log.Printf("get %s by id %d failed, error: %v", ptrModel.TableName(), id, result.Error)
-		return nil, fmt.Errorf("get %s by id %d failed, error: %v", ptrModel.TableName(), id, result.Error)
+		return nil, fmt.Errorf("get %s by id %d failed: %w", ptrModel.TableName(), id, translateError(result.Error))
 	}
 	return ptrModel, nil
 }
 
-func (r *genericRepo[T, PT]) GetByIDs(ctx context.Context, ids []uint64) ([]PT, error) {
+func (r *genericRepo[T, PT]) GetByIDs(ctx context.Context, ids []uint64, opts ...QueryOption) ([]PT, error) {
 	var model T
 	ptrModel := PT(&model)
 
 	if len(ids) == 0 {
-		log.Printf("get %s by ids %v failed, no ids provided", ptrModel.TableName(), ids)
-		return nil, nil
+		return nil, fmt.Errorf("get %s by ids failed, no ids provided: %w", ptrModel.TableName(), errs.ErrInvalidArgument)
 	}
 
-	fmt.Printf("type model: %T, type ptrModel: %T", model, ptrModel)
-
 	ptrModels := make([]PT, 0, len(ids))
 
-	fmt.Printf("type ptrModels: %T", ptrModels)
-
-	result := r.db.WithContext(ctx).
+	query, err := r.query(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by ids %v failed: %w", ptrModel.TableName(), ids, err)
+	}
+	result := query.
 		Where(fmt.Sprintf("%s IN ?", ptrModel.GetPrimaryKey()), ids).
 		Find(&ptrModels)
 	if result.Error != nil {
-		log.Printf("get %s by ids %v failed, error: %v", ptrModel.TableName(), ids, result.Error)
-		return nil, fmt.Errorf("get %s by ids %v failed, error: %v", ptrModel.TableName(), ids, result.Error)
+		return nil, fmt.Errorf("get %s by ids %v failed: %w", ptrModel.TableName(), ids, translateError(result.Error))
 	}
 	if result.RowsAffected == 0 {
-		log.Printf("get %s by ids %v failed, no rows affected", ptrModel.TableName(), ids)
-		return nil, fmt.Errorf("get %s by ids %v failed, no rows affected", ptrModel.TableName(), ids)
+		return nil, fmt.Errorf("get %s by ids %v failed: %w", ptrModel.TableName(), ids, errs.ErrNotFound)
 	}
 	return ptrModels, nil
 }
 
 func (r *genericRepo[T, PT]) GetByStructFields(ctx context.Context, structModel PT) ([]PT, error) {
 	if structModel == nil {
-		log.Printf("get %s by structModel %v failed, structModel is nil", structModel.TableName(), structModel)
-		return nil, nil
+		return nil, fmt.Errorf("get %s by structModel failed, structModel is nil: %w", structModel.TableName(), errs.ErrInvalidArgument)
 	}
 	ptrModels := make([]PT, 0, 10)
-	result := r.db.WithContext(ctx).
+	result := r.reader(ctx).
 		Where(structModel).
 		Find(&ptrModels)
 	if result.Error != nil {
-		log.Printf("get %s by structModel %v failed, error: %v", structModel.TableName(), structModel, result.Error)
-		return nil, fmt.Errorf("get %s by structModel %v failed, error: %v", structModel.TableName(), structModel, result.Error)
+		return nil, fmt.Errorf("get %s by structModel %v failed: %w", structModel.TableName(), structModel, translateError(result.Error))
 	}
 	if result.RowsAffected == 0 {
-		log.Printf("get %s by structModel %v failed, no rows affected", structModel.TableName(), structModel)
-		return nil, fmt.Errorf("get %s by structModel %v failed, no rows affected", structModel.TableName(), structModel)
+		return nil, fmt.Errorf("get %s by structModel %v failed: %w", structModel.TableName(), structModel, errs.ErrNotFound)
 	}
 	return ptrModels, nil
 }
@@ -130,20 +201,27 @@ func (r *genericRepo[T, PT]) GetByMapFields(ctx context.Context, mapFields map[s
 	var model T
 	ptrModel := PT(&model)
 	if mapFields == nil {
-		log.Printf("get %s by mapFields %v failed, mapFields is nil", ptrModel.TableName(), mapFields)
-		return nil, nil
+		return nil, fmt.Errorf("get %s by mapFields failed, mapFields is nil: %w", ptrModel.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by mapFields %v failed, parse schema error: %w", ptrModel.TableName(), mapFields, err)
+	}
+	// 键直接作为列名拼入SQL,按白名单校验,各数据库对未知列的报错不同
+	for column := range mapFields {
+		if _, ok := sch.FieldsByDBName[column]; !ok {
+			return nil, fmt.Errorf("get %s by mapFields %v failed, unknown column %q: %w", ptrModel.TableName(), mapFields, column, errs.ErrInvalidArgument)
+		}
 	}
 	ptrModels := make([]PT, 0, 10)
-	result := r.db.WithContext(ctx).
+	result := r.reader(ctx).
 		Where(mapFields).
 		Find(&ptrModels)
 	if result.Error != nil {
-		log.Printf("get %s by mapFields %v failed, error: %v", ptrModel.TableName(), mapFields, result.Error)
-		return nil, fmt.Errorf("get %s by mapFields %v failed, error: %v", ptrModel.TableName(), mapFields, result.Error)
+		return nil, fmt.Errorf("get %s by mapFields %v failed: %w", ptrModel.TableName(), mapFields, translateError(result.Error))
 	}
 	if result.RowsAffected == 0 {
-		log.Printf("get %s by mapFields %v failed, no rows affected", ptrModel.TableName(), mapFields)
-		return nil, fmt.Errorf("get %s by mapFields %v failed, no rows affected", ptrModel.TableName(), mapFields)
+		return nil, fmt.Errorf("get %s by mapFields %v failed: %w", ptrModel.TableName(), mapFields, errs.ErrNotFound)
 	}
 	return ptrModels, nil
 }
@@ -153,24 +231,21 @@ func (r *genericRepo[T, PT]) GetByPage(ctx context.Context, page, pageSize uint6
 	ptrModel := PT(&model)
 
 	if page <= 0 || pageSize <= 0 {
-		log.Printf("get %s by page %d, pageSize %d failed, page and pageSize must be greater than 0", ptrModel.TableName(), page, pageSize)
-		return nil, nil
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed, page and pageSize must be greater than 0: %w", ptrModel.TableName(), page, pageSize, errs.ErrInvalidArgument)
 	}
 
 	ptrModels := make([]PT, 0, pageSize)
 
-	result := r.db.WithContext(ctx).
+	result := r.reader(ctx).
 		Order(fmt.Sprintf("%s ASC", ptrModel.GetPrimaryKey())).
 		Offset(int((page - 1) * pageSize)).
 		Limit(int(pageSize)).
 		Find(&ptrModels)
 	if result.Error != nil {
-		log.Printf("get %s by page %d, pageSize %d failed, error: %v", ptrModel.TableName(), page, pageSize, result.Error)
-		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed, error: %v", ptrModel.TableName(), page, pageSize, result.Error)
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed: %w", ptrModel.TableName(), page, pageSize, translateError(result.Error))
 	}
 	if result.RowsAffected == 0 {
-		log.Printf("get %s by page %d, pageSize %d failed, no rows affected", ptrModel.TableName(), page, pageSize)
-		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed, no rows affected", ptrModel.TableName(), page, pageSize)
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed: %w", ptrModel.TableName(), page, pageSize, errs.ErrNotFound)
 	}
 	return ptrModels, nil
 }
@@ -180,24 +255,21 @@ func (r *genericRepo[T, PT]) GetByCursor(ctx context.Context, cursor, pageSize u
 	ptrModel := PT(&model)
 
 	if pageSize <= 0 {
-		log.Printf("get %s by cursor %d, pageSize %d failed, pageSize must be greater than 0", ptrModel.TableName(), cursor, pageSize)
-		return nil, cursor, false, nil
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d failed, pageSize must be greater than 0: %w", ptrModel.TableName(), cursor, errs.ErrInvalidArgument)
 	}
 	limit := pageSize + 1
 
 	ptrModels := make([]PT, 0, pageSize)
-	result := r.db.WithContext(ctx).
-		Where(fmt.Sprintf("%s = 0 OR %s > ?", ptrModel.GetPrimaryKey(), ptrModel.GetPrimaryKey()), cursor).
+	result := r.reader(ctx).
+		Where(fmt.Sprintf("%s > ?", ptrModel.GetPrimaryKey()), cursor).
 		Order(fmt.Sprintf("%s ASC", ptrModel.GetPrimaryKey())).
 		Limit(int(limit)).
 		Find(&ptrModels)
 	if result.Error != nil {
-		log.Printf("get %s by cursor %d, pageSize %d failed, error: %v", ptrModel.TableName(), cursor, pageSize, result.Error)
-		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed, error: %v", ptrModel.TableName(), cursor, pageSize, result.Error)
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed: %w", ptrModel.TableName(), cursor, pageSize, translateError(result.Error))
 	}
 	if result.RowsAffected == 0 {
-		log.Printf("get %s by cursor %d, pageSize %d failed, no rows affected", ptrModel.TableName(), cursor, pageSize)
-		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed, no rows affected", ptrModel.TableName(), cursor, pageSize)
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed: %w", ptrModel.TableName(), cursor, pageSize, errs.ErrNotFound)
 	}
 	hasMore := uint64(len(ptrModels)) > pageSize
 	if hasMore {
@@ -212,62 +284,239 @@ func (r *genericRepo[T, PT]) GetByCursor(ctx context.Context, cursor, pageSize u
 
 func (r *genericRepo[T, PT]) Update(ctx context.Context, ptrModel PT) error {
 	if ptrModel == nil {
-		var model T
-		ptr := PT(&model)
-		log.Printf("update %s failed, ptrModel is nil", ptr.TableName())
-		return nil
+		ptr := PT(new(T))
+		return fmt.Errorf("update %s failed, ptrModel is nil: %w", ptr.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("update %s failed, parse schema error: %w", ptrModel.TableName(), err)
+	}
+	// 记录不能通过更新转移到其他租户:模型属于其他租户时拒绝,租户列不写入
+	if err := r.assignTenant(ctx, sch, ptrModel); err != nil {
+		return fmt.Errorf("update %s failed: %w", ptrModel.TableName(), err)
+	}
+
+	// 乐观锁:带上期望版本作为条件,并写入新版本
+	versioned, isVersioned := any(ptrModel).(model.VersionedModel)
+	var expected uint64
+	if isVersioned {
+		expected = versioned.GetVersion()
+		versioned.SetVersion(expected + 1)
+	}
+
+	err = r.mutate(ctx, model.AuditActionUpdate, staticIDs(ptrModel.GetID()), func(db *gorm.DB) ([]uint64, error) {
+		query := db.Scopes(r.tenantScope(ctx)).Omit(clause.Associations, tenantColumn)
+		if isVersioned {
+			query = query.Where("version = ?", expected)
+		}
+		result := query.Updates(ptrModel)
+		if result.Error != nil {
+			return nil, fmt.Errorf("update %s failed: %w", ptrModel.TableName(), translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			if isVersioned {
+				return nil, fmt.Errorf("update %s id %d with version %d failed: %w", ptrModel.TableName(), ptrModel.GetID(), expected, errs.ErrVersionConflict)
+			}
+			return nil, fmt.Errorf("update %s by id %d failed: %w", ptrModel.TableName(), ptrModel.GetID(), errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+	if err != nil && isVersioned {
+		versioned.SetVersion(expected)
 	}
-	fmt.Printf("type ptrModel: %T", ptrModel)
+	return err
+}
+
+func (r *genericRepo[T, PT]) DeleteByID(ctx context.Context, id uint64) error {
+	pt := PT(new(T))
 
-	result := r.db.WithContext(ctx).
-		Updates(ptrModel)
+	if id == 0 {
+		return fmt.Errorf("delete %s failed, id is 0: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	return r.mutate(ctx, model.AuditActionDelete, staticIDs(id), func(db *gorm.DB) ([]uint64, error) {
+		result := db.
+			Scopes(r.tenantScope(ctx)).
+			Where(fmt.Sprintf("%s = ?", pt.GetPrimaryKey()), id).
+			Delete(pt)
+		if result.Error != nil {
+			return nil, fmt.Errorf("delete %s failed: %w", pt.TableName(), translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("delete %s by id %d failed: %w", pt.TableName(), id, errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+func (r *genericRepo[T, PT]) DeleteByIDs(ctx context.Context, ids []uint64) error {
+	pt := PT(new(T))
+
+	if len(ids) == 0 {
+		return fmt.Errorf("delete %s by ids failed, no ids provided: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	return r.mutate(ctx, model.AuditActionDelete, staticIDs(ids...), func(db *gorm.DB) ([]uint64, error) {
+		result := db.
+			Scopes(r.tenantScope(ctx)).
+			Where(fmt.Sprintf("%s IN ?", pt.GetPrimaryKey()), ids).
+			Delete(pt)
+		if result.Error != nil {
+			return nil, fmt.Errorf("delete %s by ids %v failed: %w", pt.TableName(), ids, translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("delete %s by ids %v failed: %w", pt.TableName(), ids, errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+func (r *genericRepo[T, PT]) Find(ctx context.Context, spec *Spec, opts ...QueryOption) ([]PT, error) {
+	var model T
+	ptrModel := PT(&model)
+
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("find %s failed, parse schema error: %w", ptrModel.TableName(), err)
+	}
+	query, err := r.query(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find %s failed: %w", ptrModel.TableName(), err)
+	}
+	query, err = spec.apply(query.Model(ptrModel), sch.FieldsByDBName)
+	if err != nil {
+		return nil, fmt.Errorf("find %s failed, invalid spec: %w: %w", ptrModel.TableName(), errs.ErrInvalidArgument, err)
+	}
+
+	ptrModels := make([]PT, 0, 10)
+	result := query.Find(&ptrModels)
 	if result.Error != nil {
-		log.Printf("update %s failed, error: %v", ptrModel.TableName(), result.Error)
-		return fmt.Errorf("update %s failed, error: %v", ptrModel.TableName(), result.Error)
+		return nil, fmt.Errorf("find %s failed: %w", ptrModel.TableName(), translateError(result.Error))
 	}
-	return nil
+	return ptrModels, nil
 }
 
-func (r *genericRepo[T, PT]) DeleteByID(ctx context.Context, id uint64) error {
+func (r *genericRepo[T, PT]) Count(ctx context.Context, spec *Spec) (int64, error) {
 	var model T
-	pt := PT(&model)
+	ptrModel := PT(&model)
 
-	if id == 0 {
-		log.Printf("delete %s failed, id is 0", pt.TableName())
-		return nil
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("count %s failed, parse schema error: %w", ptrModel.TableName(), err)
 	}
-	result := r.db.WithContext(ctx).
-		Where(fmt.Sprintf("%s = ?", pt.GetPrimaryKey()), id).
-		Delete(pt)
-	if result.Error != nil {
-		log.Printf("delete %s failed, error: %v", pt.TableName(), result.Error)
-		return fmt.Errorf("delete %s failed, error: %v", pt.TableName(), result.Error)
+	query, err := spec.applyWhere(r.reader(ctx).Model(ptrModel), sch.FieldsByDBName)
+	if err != nil {
+		return 0, fmt.Errorf("count %s failed, invalid spec: %w: %w", ptrModel.TableName(), errs.ErrInvalidArgument, err)
 	}
-	if result.RowsAffected == 0 {
-		log.Printf("delete %s failed, no rows affected", pt.TableName())
-		return fmt.Errorf("delete %s failed, no rows affected", pt.TableName())
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count %s failed: %w", ptrModel.TableName(), translateError(err))
 	}
-	return nil
+	return count, nil
 }
 
-func (r *genericRepo[T, PT]) DeleteByIDs(ctx context.Context, ids []uint64) error {
+func (r *genericRepo[T, PT]) Restore(ctx context.Context, id uint64) error {
+	pt := PT(new(T))
+
+	if id == 0 {
+		return fmt.Errorf("restore %s failed, id is 0: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	ok, err := r.softDeletable()
+	if err != nil {
+		return fmt.Errorf("restore %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	if !ok {
+		return fmt.Errorf("restore %s failed, model is not soft deletable: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+
+	return r.mutate(ctx, model.AuditActionRestore, staticIDs(id), func(db *gorm.DB) ([]uint64, error) {
+		result := db.
+			Unscoped().
+			Scopes(r.tenantScope(ctx)).
+			Model(pt).
+			Where(fmt.Sprintf("%s = ? AND deleted_at IS NOT NULL", pt.GetPrimaryKey()), id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return nil, fmt.Errorf("restore %s by id %d failed: %w", pt.TableName(), id, translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("restore %s by id %d failed, no deleted record: %w", pt.TableName(), id, errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+func (r *genericRepo[T, PT]) GetWithDeleted(ctx context.Context, id uint64, opts ...QueryOption) (PT, error) {
 	var model T
-	pt := PT(&model)
+	ptrModel := PT(&model)
 
-	if len(ids) == 0 {
-		log.Printf("delete %s by ids failed, no ids provided", pt.TableName())
-		return nil
+	if id == 0 {
+		return nil, fmt.Errorf("get %s with deleted by id %d failed, id must be greater than 0: %w", ptrModel.TableName(), id, errs.ErrInvalidArgument)
+	}
+	query, err := r.query(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s with deleted by id %d failed: %w", ptrModel.TableName(), id, err)
 	}
-	result := r.db.WithContext(ctx).
-		Where(fmt.Sprintf("%s IN ?", pt.GetPrimaryKey()), ids).
-		Delete(pt)
+
+	result := query.
+		Unscoped().
+		Where(fmt.Sprintf("%s = ?", ptrModel.GetPrimaryKey()), id).
+		First(ptrModel)
 	if result.Error != nil {
-		log.Printf("delete %s by ids %v failed, error: %v", pt.TableName(), ids, result.Error)
-		return fmt.Errorf("delete %s by ids %v failed, error: %v", pt.TableName(), ids, result.Error)
+		return nil, fmt.Errorf("get %s with deleted by id %d failed: %w", ptrModel.TableName(), id, translateError(result.Error))
 	}
-	if result.RowsAffected == 0 {
-		log.Printf("delete %s by ids %v failed, no rows affected", pt.TableName(), ids)
-		return fmt.Errorf("delete %s by ids %v failed, no rows affected", pt.TableName(), ids)
+	return ptrModel, nil
+}
+
+func (r *genericRepo[T, PT]) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	pt := PT(new(T))
+
+	if olderThan < 0 {
+		return 0, fmt.Errorf("purge %s failed, olderThan must not be negative: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	ok, err := r.softDeletable()
+	if err != nil {
+		return 0, fmt.Errorf("purge %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("purge %s failed, model is not soft deletable: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	// 审计时先锁定待清理记录的ID,写前快照与实际删除的是同一批记录
+	var (
+		purgeIDs []uint64
+		locked   bool
+	)
+	targets := func(db *gorm.DB) ([]uint64, error) {
+		locked = true
+		err := db.Unscoped().
+			Scopes(r.tenantScope(ctx)).
+			Model(pt).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Pluck(pt.GetPrimaryKey(), &purgeIDs).Error
+		return purgeIDs, err
+	}
+
+	var purged int64
+	err = r.mutate(ctx, model.AuditActionPurge, targets, func(db *gorm.DB) ([]uint64, error) {
+		query := db.Unscoped().Scopes(r.tenantScope(ctx))
+		if locked {
+			if len(purgeIDs) == 0 {
+				return nil, nil
+			}
+			query = query.Where(fmt.Sprintf("%s IN ?", pt.GetPrimaryKey()), purgeIDs)
+		} else {
+			query = query.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		}
+		result := query.Delete(pt)
+		if result.Error != nil {
+			return nil, fmt.Errorf("purge %s failed: %w", pt.TableName(), translateError(result.Error))
+		}
+		purged = result.RowsAffected
+		return nil, nil
+	})
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return purged, nil
 }
@@ -0,0 +1,209 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// UpsertResult upsert 的执行结果
+type UpsertResult struct {
+	Inserted int64
+	Updated  int64
+	Skipped  int64
+}
+
+func (u *UpsertResult) add(other UpsertResult) {
+	u.Inserted += other.Inserted
+	u.Updated += other.Updated
+	u.Skipped += other.Skipped
+}
+
+func (r *genericRepo[T, PT]) Upsert(ctx context.Context, ptrModel PT, conflictColumns []string, updateColumns []string) (UpsertResult, error) {
+	if ptrModel == nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed, ptrModel is nil: %w", ptrModel.TableName(), errs.ErrInvalidArgument)
+	}
+	return r.UpsertInBatches(ctx, []PT{ptrModel}, 1, conflictColumns, updateColumns)
+}
+
+func (r *genericRepo[T, PT]) UpsertInBatches(ctx context.Context, ptrModels []PT, batchSize int, conflictColumns []string, updateColumns []string) (UpsertResult, error) {
+	pt := PT(new(T))
+
+	if len(ptrModels) == 0 || batchSize <= 0 {
+		return UpsertResult{}, fmt.Errorf("upsert %s in batches failed, no models provided or batchSize %d invalid: %w", pt.TableName(), batchSize, errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	if err := r.assignTenant(ctx, sch, ptrModels...); err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed: %w", pt.TableName(), err)
+	}
+	onConflict, err := buildOnConflict(sch, pt, conflictColumns, updateColumns)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed: %w: %w", pt.TableName(), errs.ErrInvalidArgument, err)
+	}
+
+	// 冲突行在执行前无法确定,审计日志只记录写后快照
+	var total UpsertResult
+	err = r.mutate(ctx, model.AuditActionUpsert, nil, func(db *gorm.DB) ([]uint64, error) {
+		var ids []uint64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for start := 0; start < len(ptrModels); start += batchSize {
+				end := min(start+batchSize, len(ptrModels))
+				batch, batchIDs, err := upsertBatch(tx, sch, ptrModels[start:end], onConflict)
+				if err != nil {
+					return err
+				}
+				total.add(batch)
+				ids = append(ids, batchIDs...)
+			}
+			return nil
+		})
+		return ids, err
+	})
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s in batches failed: %w", pt.TableName(), translateError(err))
+	}
+	return total, nil
+}
+
+// buildOnConflict 校验冲突列与更新列并构造 ON CONFLICT 子句,updateColumns 为空时为 DO NOTHING
+func buildOnConflict(sch *schema.Schema, pt model.Model, conflictColumns []string, updateColumns []string) (clause.OnConflict, error) {
+	if len(conflictColumns) == 0 {
+		return clause.OnConflict{}, fmt.Errorf("conflict columns are required")
+	}
+	// 按租户隔离的模型,唯一约束都以 tenant_id 开头,冲突只发生在同一租户内
+	if sch.LookUpField(tenantColumn) != nil && !slices.Contains(conflictColumns, tenantColumn) {
+		conflictColumns = append([]string{tenantColumn}, conflictColumns...)
+	}
+	onConflict := clause.OnConflict{}
+	for _, column := range conflictColumns {
+		if _, ok := sch.FieldsByDBName[column]; !ok {
+			return clause.OnConflict{}, fmt.Errorf("unknown conflict column %q", column)
+		}
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	if len(updateColumns) == 0 {
+		onConflict.DoNothing = true
+		return onConflict, nil
+	}
+	for _, column := range updateColumns {
+		field, ok := sch.FieldsByDBName[column]
+		if !ok || field.PrimaryKey || column == "created_at" || column == "version" || column == tenantColumn {
+			return clause.OnConflict{}, fmt.Errorf("column %q can not be updated on conflict", column)
+		}
+	}
+	onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	// 乐观锁模型在冲突更新时同样递增版本号
+	if _, ok := any(pt).(model.VersionedModel); ok {
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.Assignment{
+			Column: clause.Column{Name: "version"},
+			Value:  gorm.Expr(fmt.Sprintf("%s.version + 1", pt.TableName())),
+		})
+	}
+	return onConflict, nil
+}
+
+// upsertBatch 借助 Postgres 的 xmax 系统列区分插入与更新:新插入的行 xmax 为 0
+// 其他方言没有 xmax,执行前先统计冲突键已存在的行数,再按返回的行数推算。
+// 返回实际插入或更新的行的主键
+func upsertBatch[PT any](tx *gorm.DB, sch *schema.Schema, batch []PT, onConflict clause.OnConflict) (UpsertResult, []uint64, error) {
+	primaryKey := sch.PrioritizedPrimaryField
+	xmax := DialectOf(tx) == DialectPostgres
+	returning := []clause.Column{{Name: primaryKey.DBName}}
+
+	var existing int64
+	if xmax {
+		returning = append(returning, clause.Column{Name: "(xmax = 0)", Raw: true})
+	} else {
+		// SQLite 的写事务持有库级写锁,统计与写入之间不会有其他写入
+		var err error
+		if existing, err = countConflicts(tx, sch, batch, onConflict.Columns); err != nil {
+			return UpsertResult{}, nil, err
+		}
+	}
+
+	stmt := tx.Session(&gorm.Session{DryRun: true}).
+		Omit(clause.Associations).
+		Clauses(onConflict, clause.Returning{Columns: returning}).
+		Create(&batch).Statement
+	if stmt.Error != nil {
+		return UpsertResult{}, nil, stmt.Error
+	}
+
+	rows, err := tx.Statement.ConnPool.QueryContext(tx.Statement.Context, stmt.SQL.String(), stmt.Vars...)
+	if err != nil {
+		return UpsertResult{}, nil, err
+	}
+	defer rows.Close()
+
+	var result UpsertResult
+	ids := make([]uint64, 0, len(batch))
+	for rows.Next() {
+		var (
+			id       uint64
+			inserted bool
+		)
+		dest := []any{&id}
+		if xmax {
+			dest = append(dest, &inserted)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return UpsertResult{}, nil, err
+		}
+		ids = append(ids, id)
+		if !xmax {
+			continue
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return UpsertResult{}, nil, err
+	}
+	if !xmax {
+		// DO UPDATE 时已存在的行都会被更新并返回,DO NOTHING 时都被跳过
+		if !onConflict.DoNothing {
+			result.Updated = existing
+		}
+		result.Inserted = int64(len(ids)) - result.Updated
+	}
+	result.Skipped = int64(len(batch)) - result.Inserted - result.Updated
+
+	// DO NOTHING 跳过的行不会返回,此时无法与入参一一对应,不回填主键
+	if len(ids) == len(batch) {
+		for i, item := range batch {
+			if err := primaryKey.Set(tx.Statement.Context, reflect.ValueOf(item), ids[i]); err != nil {
+				return UpsertResult{}, nil, err
+			}
+		}
+	}
+	return result, ids, nil
+}
+
+// countConflicts 统计 batch 中冲突键已存在于表中的行数,已软删除的行同样会触发唯一约束,一并统计
+func countConflicts[PT any](tx *gorm.DB, sch *schema.Schema, batch []PT, columns []clause.Column) (int64, error) {
+	conds := make([]clause.Expression, 0, len(batch))
+	for _, item := range batch {
+		eqs := make([]clause.Expression, 0, len(columns))
+		for _, column := range columns {
+			value, _ := sch.FieldsByDBName[column.Name].ValueOf(tx.Statement.Context, reflect.ValueOf(item))
+			eqs = append(eqs, clause.Eq{Column: clause.Column{Name: column.Name}, Value: value})
+		}
+		conds = append(conds, clause.And(eqs...))
+	}
+	var count int64
+	err := tx.Session(&gorm.Session{NewDB: true}).Table(sch.Table).Where(clause.Or(conds...)).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"context"
+	"go-pattern/internal/model"
+	"iter"
+	"time"
+)
+
+// interceptedRepo 让每个 GenericRepo 方法都经过拦截器链
+type interceptedRepo[T any, PT model.PointerModel[T]] struct {
+	repo      GenericRepo[T, PT]
+	intercept Interceptor
+	table     string
+}
+
+func newInterceptedRepo[T any, PT model.PointerModel[T]](repo GenericRepo[T, PT], interceptors []Interceptor) GenericRepo[T, PT] {
+	return &interceptedRepo[T, PT]{
+		repo:      repo,
+		intercept: chainInterceptors(interceptors),
+		table:     PT(new(T)).TableName(),
+	}
+}
+
+func (i *interceptedRepo[T, PT]) invoke(ctx context.Context, operation string, ids []uint64, args []any, fn Handler) error {
+	return i.intercept(ctx, &Invocation{Operation: operation, Table: i.table, IDs: ids, Args: args}, fn)
+}
+
+// modelIDs 模型的主键,跳过 nil 与尚未写入的模型
+func modelIDs[T any, PT model.PointerModel[T]](ptrModels ...PT) []uint64 {
+	ids := make([]uint64, 0, len(ptrModels))
+	for _, ptrModel := range ptrModels {
+		if ptrModel != nil && ptrModel.GetID() != 0 {
+			ids = append(ids, ptrModel.GetID())
+		}
+	}
+	return ids
+}
+
+// intercept 经过拦截器链调用 fn,拦截器未调用 next 时返回零值
+func intercept[R any, T any, PT model.PointerModel[T]](ctx context.Context, i *interceptedRepo[T, PT], operation string, ids []uint64, args []any, fn func(ctx context.Context) (R, error)) (R, error) {
+	var result R
+	err := i.invoke(ctx, operation, ids, args, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (i *interceptedRepo[T, PT]) Create(ctx context.Context, ptrModel PT) error {
+	return i.invoke(ctx, "Create", nil, []any{ptrModel}, func(ctx context.Context) error {
+		return i.repo.Create(ctx, ptrModel)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) CreateInBatches(ctx context.Context, ptrModels []PT, batchSize int) error {
+	return i.invoke(ctx, "CreateInBatches", nil, []any{ptrModels, batchSize}, func(ctx context.Context) error {
+		return i.repo.CreateInBatches(ctx, ptrModels, batchSize)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (PT, error) {
+	return intercept(ctx, i, "GetByID", []uint64{id}, []any{id}, func(ctx context.Context) (PT, error) {
+		return i.repo.GetByID(ctx, id, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetByIDs(ctx context.Context, ids []uint64, opts ...QueryOption) ([]PT, error) {
+	return intercept(ctx, i, "GetByIDs", ids, []any{ids}, func(ctx context.Context) ([]PT, error) {
+		return i.repo.GetByIDs(ctx, ids, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetByStructFields(ctx context.Context, structModel PT) ([]PT, error) {
+	return intercept(ctx, i, "GetByStructFields", nil, []any{structModel}, func(ctx context.Context) ([]PT, error) {
+		return i.repo.GetByStructFields(ctx, structModel)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetByMapFields(ctx context.Context, mapFields map[string]any) ([]PT, error) {
+	return intercept(ctx, i, "GetByMapFields", nil, []any{mapFields}, func(ctx context.Context) ([]PT, error) {
+		return i.repo.GetByMapFields(ctx, mapFields)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetByPage(ctx context.Context, page, pageSize uint64) ([]PT, error) {
+	return intercept(ctx, i, "GetByPage", nil, []any{page, pageSize}, func(ctx context.Context) ([]PT, error) {
+		return i.repo.GetByPage(ctx, page, pageSize)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetPage(ctx context.Context, spec *Spec, page, pageSize uint64, estimateTotal bool, opts ...QueryOption) (*Page[PT], error) {
+	return intercept(ctx, i, "GetPage", nil, []any{spec, page, pageSize, estimateTotal}, func(ctx context.Context) (*Page[PT], error) {
+		return i.repo.GetPage(ctx, spec, page, pageSize, estimateTotal, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetByCursor(ctx context.Context, cursor, pageSize uint64) ([]PT, uint64, bool, error) {
+	var (
+		ptrModels []PT
+		newCursor = cursor
+		hasMore   bool
+	)
+	err := i.invoke(ctx, "GetByCursor", nil, []any{cursor, pageSize}, func(ctx context.Context) error {
+		var err error
+		ptrModels, newCursor, hasMore, err = i.repo.GetByCursor(ctx, cursor, pageSize)
+		return err
+	})
+	return ptrModels, newCursor, hasMore, err
+}
+
+func (i *interceptedRepo[T, PT]) GetByKeyset(ctx context.Context, query KeysetQuery) (*KeysetPage[PT], error) {
+	return intercept(ctx, i, "GetByKeyset", nil, []any{query}, func(ctx context.Context) (*KeysetPage[PT], error) {
+		return i.repo.GetByKeyset(ctx, query)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) Update(ctx context.Context, ptrModel PT) error {
+	return i.invoke(ctx, "Update", modelIDs(ptrModel), []any{ptrModel}, func(ctx context.Context) error {
+		return i.repo.Update(ctx, ptrModel)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) UpdateFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) error {
+	return i.invoke(ctx, "UpdateFields", []uint64{id}, []any{id, fieldMask, values}, func(ctx context.Context) error {
+		return i.repo.UpdateFields(ctx, id, fieldMask, values)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) DeleteByID(ctx context.Context, id uint64) error {
+	return i.invoke(ctx, "DeleteByID", []uint64{id}, []any{id}, func(ctx context.Context) error {
+		return i.repo.DeleteByID(ctx, id)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) DeleteByIDs(ctx context.Context, ids []uint64) error {
+	return i.invoke(ctx, "DeleteByIDs", ids, []any{ids}, func(ctx context.Context) error {
+		return i.repo.DeleteByIDs(ctx, ids)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) UpdateWhere(ctx context.Context, filter *Spec, changes map[string]any, opts ...BulkOption) (int64, error) {
+	return intercept(ctx, i, "UpdateWhere", nil, []any{filter, changes}, func(ctx context.Context) (int64, error) {
+		return i.repo.UpdateWhere(ctx, filter, changes, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) DeleteWhere(ctx context.Context, filter *Spec, opts ...BulkOption) (int64, error) {
+	return intercept(ctx, i, "DeleteWhere", nil, []any{filter}, func(ctx context.Context) (int64, error) {
+		return i.repo.DeleteWhere(ctx, filter, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) Upsert(ctx context.Context, ptrModel PT, conflictColumns []string, updateColumns []string) (UpsertResult, error) {
+	return intercept(ctx, i, "Upsert", nil, []any{ptrModel, conflictColumns, updateColumns}, func(ctx context.Context) (UpsertResult, error) {
+		return i.repo.Upsert(ctx, ptrModel, conflictColumns, updateColumns)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) UpsertInBatches(ctx context.Context, ptrModels []PT, batchSize int, conflictColumns []string, updateColumns []string) (UpsertResult, error) {
+	return intercept(ctx, i, "UpsertInBatches", nil, []any{ptrModels, batchSize, conflictColumns, updateColumns}, func(ctx context.Context) (UpsertResult, error) {
+		return i.repo.UpsertInBatches(ctx, ptrModels, batchSize, conflictColumns, updateColumns)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) Find(ctx context.Context, spec *Spec, opts ...QueryOption) ([]PT, error) {
+	return intercept(ctx, i, "Find", nil, []any{spec}, func(ctx context.Context) ([]PT, error) {
+		return i.repo.Find(ctx, spec, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) Count(ctx context.Context, spec *Spec) (int64, error) {
+	return intercept(ctx, i, "Count", nil, []any{spec}, func(ctx context.Context) (int64, error) {
+		return i.repo.Count(ctx, spec)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) Aggregate(ctx context.Context, query AggregateQuery) ([]AggregateRow, error) {
+	return intercept(ctx, i, "Aggregate", nil, []any{query}, func(ctx context.Context) ([]AggregateRow, error) {
+		return i.repo.Aggregate(ctx, query)
+	})
+}
+
+// Iterate 整个遍历过程作为一次调用,调用方处理每条记录的时间记入 Invocation.Yielded
+func (i *interceptedRepo[T, PT]) Iterate(ctx context.Context, spec *Spec, batchSize int) iter.Seq2[PT, error] {
+	return func(yield func(PT, error) bool) {
+		stopped := false
+		inv := &Invocation{Operation: "Iterate", Table: i.table, Args: []any{spec, batchSize}}
+		err := i.intercept(ctx, inv, func(ctx context.Context) error {
+			for ptrModel, err := range i.repo.Iterate(ctx, spec, batchSize) {
+				if err != nil {
+					// 内层遍历产出错误后即结束,错误交给拦截器后统一产出
+					return err
+				}
+				start := time.Now()
+				ok := yield(ptrModel, nil)
+				inv.Yielded += time.Since(start)
+				if !ok {
+					stopped = true
+					return nil
+				}
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+func (i *interceptedRepo[T, PT]) Restore(ctx context.Context, id uint64) error {
+	return i.invoke(ctx, "Restore", []uint64{id}, []any{id}, func(ctx context.Context) error {
+		return i.repo.Restore(ctx, id)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) GetWithDeleted(ctx context.Context, id uint64, opts ...QueryOption) (PT, error) {
+	return intercept(ctx, i, "GetWithDeleted", []uint64{id}, []any{id}, func(ctx context.Context) (PT, error) {
+		return i.repo.GetWithDeleted(ctx, id, opts...)
+	})
+}
+
+func (i *interceptedRepo[T, PT]) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return intercept(ctx, i, "Purge", nil, []any{olderThan}, func(ctx context.Context) (int64, error) {
+		return i.repo.Purge(ctx, olderThan)
+	})
+}
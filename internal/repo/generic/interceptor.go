@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
+	"log/slog"
+	"time"
+)
+
+// Invocation 一次仓储调用:操作名为 GenericRepo 的方法名,Args 为除 ctx 与查询选项外的入参
+type Invocation struct {
+	Operation string
+	Table     string
+	// IDs 按主键操作时的目标记录ID,其他操作为空
+	IDs []uint64
+	// Args 包含模型与写入的值,可能含有个人信息,不应原样写入日志
+	Args []any
+	// Yielded Iterate 中调用方处理记录所用的时间,Observe 报告的耗时已扣除
+	Yielded time.Duration
+}
+
+// Handler 执行被拦截的调用
+type Handler func(ctx context.Context) error
+
+// Interceptor 环绕每次仓储调用,调用 next 执行实际操作,可以修改 ctx、记录耗时或直接返回错误
+type Interceptor func(ctx context.Context, inv *Invocation, next Handler) error
+
+// WithInterceptors 追加拦截器,先传入的在外层
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// chainInterceptors 将拦截器组合为一个,interceptors 不能为空
+func chainInterceptors(interceptors []Interceptor) Interceptor {
+	return func(ctx context.Context, inv *Invocation, next Handler) error {
+		handler := next
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], handler
+			handler = func(ctx context.Context) error {
+				return interceptor(ctx, inv, inner)
+			}
+		}
+		return handler(ctx)
+	}
+}
+
+// Observe 在每次调用结束后以耗时与错误回调 fn,用于日志、指标与链路追踪。
+// Iterate 的耗时只包含读取数据的时间,不含调用方处理记录的时间
+func Observe(fn func(ctx context.Context, inv *Invocation, elapsed time.Duration, err error)) Interceptor {
+	return func(ctx context.Context, inv *Invocation, next Handler) error {
+		start := time.Now()
+		yielded := inv.Yielded
+		err := next(ctx)
+		fn(ctx, inv, time.Since(start)-(inv.Yielded-yielded), err)
+		return err
+	}
+}
+
+// LoggingInterceptor 记录失败的调用,logger 为 nil 时使用 slog 默认 logger。
+// 只记录操作、表名与目标ID,不记录可能含有个人信息的参数。
+// 记录不存在属于正常分支,记为 Debug;参数错误与冲突由调用方处理,记为 Warn;其余为 Error
+func LoggingInterceptor(logger *slog.Logger) Interceptor {
+	logger = logging.OrDefault(logger)
+	return Observe(func(ctx context.Context, inv *Invocation, elapsed time.Duration, err error) {
+		if err == nil {
+			return
+		}
+		level := slog.LevelError
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			level = slog.LevelDebug
+		case errors.Is(err, errs.ErrInvalidArgument), errors.Is(err, errs.ErrConflict):
+			level = slog.LevelWarn
+		}
+		logger.Log(ctx, level, "repo call failed",
+			slog.String("operation", inv.Operation),
+			slog.String("table", inv.Table),
+			slog.Any("ids", inv.IDs),
+			slog.Duration("elapsed", elapsed),
+			slog.Any("error", err))
+	})
+}
+
+// SlowQueryInterceptor 耗时超过 threshold 时回调 alert
+func SlowQueryInterceptor(threshold time.Duration, alert func(ctx context.Context, inv *Invocation, elapsed time.Duration)) Interceptor {
+	return Observe(func(ctx context.Context, inv *Invocation, elapsed time.Duration, err error) {
+		if elapsed >= threshold {
+			alert(ctx, inv, elapsed)
+		}
+	})
+}
+
+// FaultInjectionInterceptor inject 返回非 nil 错误时不执行调用而直接返回该错误,用于测试故障处理
+func FaultInjectionInterceptor(inject func(ctx context.Context, inv *Invocation) error) Interceptor {
+	return func(ctx context.Context, inv *Invocation, next Handler) error {
+		if err := inject(ctx, inv); err != nil {
+			return err
+		}
+		return next(ctx)
+	}
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go-pattern/internal/model"
+)
+
+func TestObserveExcludesIterateConsumer(t *testing.T) {
+	db := NewMemoryDB()
+	ctx := seedProducts(t, db, 3)
+	var elapsed time.Duration
+	products := NewMemoryRepo[model.Product](db, WithInterceptors(Observe(func(ctx context.Context, inv *Invocation, d time.Duration, err error) {
+		elapsed = d
+	})))
+
+	const pause = 20 * time.Millisecond
+	for _, err := range products.Iterate(ctx, nil, 1) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(pause)
+	}
+	if elapsed >= pause {
+		t.Fatalf("Iterate elapsed = %v, must not include the consumer's %v per row", elapsed, pause)
+	}
+}
+
+func TestLoggingInterceptorOmitsArgs(t *testing.T) {
+	db := NewMemoryDB()
+	ctx := seedProducts(t, db, 1)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	products := NewMemoryRepo[model.Product](db, WithInterceptors(LoggingInterceptor(logger)))
+
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"update", func() error {
+			return products.Update(ctx, &model.Product{ID: 42, Name: "secret-name"})
+		}, "ids=[42]"},
+		{"update fields", func() error {
+			return products.UpdateFields(ctx, 43, []string{"name"}, map[string]any{"name": "secret-name"})
+		}, "ids=[43]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			if err := tt.call(); err == nil {
+				t.Fatal("expected the call to fail")
+			}
+			line := buf.String()
+			if !strings.Contains(line, tt.want) {
+				t.Fatalf("log %q does not contain %q", line, tt.want)
+			}
+			if strings.Contains(line, "secret-name") {
+				t.Fatalf("log %q leaks the arguments", line)
+			}
+		})
+	}
+}
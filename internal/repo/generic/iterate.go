@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"iter"
+
+	"gorm.io/gorm/schema"
+)
+
+func (r *genericRepo[T, PT]) Iterate(ctx context.Context, spec *Spec, batchSize int) iter.Seq2[PT, error] {
+	sch, err := r.schema()
+	if err != nil {
+		return func(yield func(PT, error) bool) {
+			yield(nil, fmt.Errorf("iterate %s failed, parse schema error: %w", PT(new(T)).TableName(), err))
+		}
+	}
+	return iterate(ctx, sch, spec, batchSize, r.seek)
+}
+
+// iterate 以键集扫描分批遍历的公共流程,读取由 seek 完成
+func iterate[PT any](ctx context.Context, sch *schema.Schema, spec *Spec, batchSize int, seek seekFunc[PT]) iter.Seq2[PT, error] {
+	return func(yield func(PT, error) bool) {
+		var zero PT
+		if batchSize <= 0 {
+			yield(zero, fmt.Errorf("iterate %s failed, batchSize must be greater than 0: %w", sch.Table, errs.ErrInvalidArgument))
+			return
+		}
+		var sorts []Sort
+		remaining := 0
+		if spec != nil {
+			sorts, remaining = spec.sorts, spec.limit
+		}
+		keys, err := keysetSorts(sch, sorts)
+		if err != nil {
+			yield(zero, fmt.Errorf("iterate %s failed: %w: %w", sch.Table, errs.ErrInvalidArgument, err))
+			return
+		}
+
+		// 每批从上一批最后一行的键值之后继续读取,内存占用只与 batchSize 有关
+		var values []any
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, fmt.Errorf("iterate %s canceled: %w", sch.Table, err))
+				return
+			}
+			limit := batchSize
+			if remaining > 0 && remaining < limit {
+				limit = remaining
+			}
+			batch, err := seek(ctx, sch, spec, keys, values, false, limit)
+			if err != nil {
+				yield(zero, fmt.Errorf("iterate %s failed: %w", sch.Table, err))
+				return
+			}
+			for _, item := range batch {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if remaining > 0 {
+				if remaining -= len(batch); remaining == 0 {
+					return
+				}
+			}
+			if len(batch) < limit {
+				return
+			}
+			values = keyValues(ctx, sch, keys, batch[len(batch)-1])
+		}
+	}
+}
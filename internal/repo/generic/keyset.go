@@ -0,0 +1,329 @@
+package repo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-pattern/internal/errs"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// KeysetQuery 键集分页请求
+type KeysetQuery struct {
+	// Spec 只使用其过滤条件,排序与分页由 KeysetQuery 决定
+	Spec *Spec
+	// Sorts 排序元组,未包含主键时自动追加主键升序作为决胜字段,排序列必须非空
+	Sorts []Sort
+	// Cursor 上一次返回的 NextCursor 或 PrevCursor,为空表示第一页
+	Cursor string
+	Limit  int
+}
+
+// KeysetPage 键集分页结果,游标为不透明且防篡改的 base64 字符串
+type KeysetPage[PT any] struct {
+	Items      []PT   `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+}
+
+// cursorPayload 游标内容:方向、表名、过滤条件摘要、排序签名与最后一行的键值。
+// 表名与过滤条件一并签名,游标不能用于其他模型或其他过滤条件的查询
+type cursorPayload struct {
+	Backward bool              `json:"b,omitempty"`
+	Table    string            `json:"t"`
+	Filter   string            `json:"f,omitempty"`
+	Sorts    string            `json:"s"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+// cursorScope 游标所属的查询:表名与过滤条件摘要
+type cursorScope struct {
+	table  string
+	filter string
+}
+
+func newCursorScope(sch *schema.Schema, filter *Spec) cursorScope {
+	return cursorScope{table: sch.Table, filter: filterDigest(filter)}
+}
+
+// filterDigest 过滤条件的摘要,没有过滤条件时为空
+func filterDigest(filter *Spec) string {
+	if filter == nil || len(filter.conds) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, cond := range filter.conds {
+		// 字段在查询时才按白名单校验,这里只需要稳定的文本表示
+		sql, vars, err := cond.buildWith(func(field string) (string, bool) { return field, true })
+		if err != nil {
+			sql = err.Error()
+		}
+		data, err := json.Marshal(vars)
+		if err != nil {
+			data = fmt.Appendf(nil, "%v", vars)
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00", sql, data)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+var (
+	defaultCursorSecret     []byte
+	defaultCursorSecretOnce sync.Once
+)
+
+// secret 未配置游标密钥时使用进程级随机密钥,重启后旧游标失效
+func (o *options) secret() []byte {
+	if len(o.cursorSecret) > 0 {
+		return o.cursorSecret
+	}
+	defaultCursorSecretOnce.Do(func() {
+		defaultCursorSecret = make([]byte, 32)
+		if _, err := rand.Read(defaultCursorSecret); err != nil {
+			panic(fmt.Sprintf("generate cursor secret failed: %v", err))
+		}
+	})
+	return defaultCursorSecret
+}
+
+func encodeCursor(secret []byte, payload cursorPayload) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func decodeCursor(secret []byte, token string) (cursorPayload, error) {
+	var payload cursorPayload
+	encodedData, encodedMac, ok := strings.Cut(token, ".")
+	if !ok {
+		return payload, fmt.Errorf("malformed cursor")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encodedData)
+	if err != nil {
+		return payload, fmt.Errorf("malformed cursor: %w", err)
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(encodedMac)
+	if err != nil {
+		return payload, fmt.Errorf("malformed cursor: %w", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return payload, fmt.Errorf("cursor signature mismatch")
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return payload, fmt.Errorf("malformed cursor: %w", err)
+	}
+	return payload, nil
+}
+
+// keysetSorts 校验排序列并追加主键作为决胜字段
+func keysetSorts(sch *schema.Schema, sorts []Sort) ([]Sort, error) {
+	primaryKey := sch.PrioritizedPrimaryField.DBName
+	keys := make([]Sort, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		if _, ok := sch.FieldsByDBName[sort.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort column %q", sort.Field)
+		}
+		keys = append(keys, sort)
+		// 主键唯一,其后的排序列没有意义
+		if sort.Field == primaryKey {
+			return keys, nil
+		}
+	}
+	return append(keys, Asc(primaryKey)), nil
+}
+
+func sortsSignature(sorts []Sort) string {
+	parts := make([]string, len(sorts))
+	for i, sort := range sorts {
+		if sort.Desc {
+			parts[i] = sort.Field + ":d"
+		} else {
+			parts[i] = sort.Field + ":a"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// seekCond 构造 "位于键值之后" 的条件:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...,降序列或向后翻页时比较方向取反
+func seekCond(sorts []Sort, values []any, backward bool) Cond {
+	branches := make([]Cond, 0, len(sorts))
+	for i, sort := range sorts {
+		conds := make([]Cond, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, Eq(sorts[j].Field, values[j]))
+		}
+		if sort.Desc != backward {
+			conds = append(conds, Lt(sort.Field, values[i]))
+		} else {
+			conds = append(conds, Gt(sort.Field, values[i]))
+		}
+		branches = append(branches, And(conds...))
+	}
+	return Or(branches...)
+}
+
+// keyValues 读取一行记录在排序列上的值
+func keyValues(ctx context.Context, sch *schema.Schema, sorts []Sort, item any) []any {
+	values := make([]any, len(sorts))
+	for i, sort := range sorts {
+		values[i], _ = sch.FieldsByDBName[sort.Field].ValueOf(ctx, reflect.ValueOf(item))
+	}
+	return values
+}
+
+func makeCursor(ctx context.Context, secret []byte, sch *schema.Schema, scope cursorScope, sorts []Sort, item any, backward bool) (string, error) {
+	payload := cursorPayload{Backward: backward, Table: scope.table, Filter: scope.filter, Sorts: sortsSignature(sorts)}
+	for _, value := range keyValues(ctx, sch, sorts, item) {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		payload.Values = append(payload.Values, raw)
+	}
+	return encodeCursor(secret, payload)
+}
+
+// parseCursor 校验游标签名、所属查询与排序签名,并按列的Go类型还原键值
+func parseCursor(secret []byte, sch *schema.Schema, scope cursorScope, sorts []Sort, token string) ([]any, bool, error) {
+	payload, err := decodeCursor(secret, token)
+	if err != nil {
+		return nil, false, err
+	}
+	if payload.Table != scope.table || payload.Filter != scope.filter {
+		return nil, false, fmt.Errorf("cursor does not match query")
+	}
+	if payload.Sorts != sortsSignature(sorts) || len(payload.Values) != len(sorts) {
+		return nil, false, fmt.Errorf("cursor does not match sort order")
+	}
+	values := make([]any, len(sorts))
+	for i, sort := range sorts {
+		value := reflect.New(sch.FieldsByDBName[sort.Field].FieldType)
+		if err := json.Unmarshal(payload.Values[i], value.Interface()); err != nil {
+			return nil, false, fmt.Errorf("malformed cursor value: %w", err)
+		}
+		values[i] = value.Elem().Interface()
+	}
+	return values, payload.Backward, nil
+}
+
+// seekFunc 从键值之后(backward 时为之前)按排序读取最多 limit 行,返回结果保持正向顺序
+type seekFunc[PT any] func(ctx context.Context, sch *schema.Schema, filter *Spec, sorts []Sort, values []any, backward bool, limit int) ([]PT, error)
+
+// seekSpec 构造键集扫描的查询规格,backward 时排序方向取反
+func seekSpec(filter *Spec, sorts []Sort, values []any, backward bool, limit int) *Spec {
+	spec := &Spec{limit: limit}
+	if filter != nil {
+		spec.conds = slices.Clone(filter.conds)
+	}
+	if values != nil {
+		spec.conds = append(spec.conds, seekCond(sorts, values, backward))
+	}
+	for _, sort := range sorts {
+		spec.sorts = append(spec.sorts, Sort{Field: sort.Field, Desc: sort.Desc != backward})
+	}
+	return spec
+}
+
+func (r *genericRepo[T, PT]) seek(ctx context.Context, sch *schema.Schema, filter *Spec, sorts []Sort, values []any, backward bool, limit int) ([]PT, error) {
+	var model T
+	spec := seekSpec(filter, sorts, values, backward, limit)
+	query, err := spec.apply(r.reader(ctx).Model(PT(&model)), sch.FieldsByDBName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+	}
+	ptrModels := make([]PT, 0, limit)
+	if err := query.Find(&ptrModels).Error; err != nil {
+		return nil, translateError(err)
+	}
+	if backward {
+		slices.Reverse(ptrModels)
+	}
+	return ptrModels, nil
+}
+
+func (r *genericRepo[T, PT]) GetByKeyset(ctx context.Context, query KeysetQuery) (*KeysetPage[PT], error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by keyset failed, parse schema error: %w", PT(new(T)).TableName(), err)
+	}
+	return keysetPage(ctx, r.opts.secret(), sch, query, r.seek)
+}
+
+// keysetPage 键集分页的公共流程,读取由 seek 完成
+func keysetPage[PT any](ctx context.Context, secret []byte, sch *schema.Schema, query KeysetQuery, seek seekFunc[PT]) (*KeysetPage[PT], error) {
+	if query.Limit <= 0 {
+		return nil, fmt.Errorf("get %s by keyset failed, limit must be greater than 0: %w", sch.Table, errs.ErrInvalidArgument)
+	}
+	sorts, err := keysetSorts(sch, query.Sorts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by keyset failed: %w: %w", sch.Table, errs.ErrInvalidArgument, err)
+	}
+
+	var (
+		values   []any
+		backward bool
+		scope    = newCursorScope(sch, query.Spec)
+	)
+	if query.Cursor != "" {
+		values, backward, err = parseCursor(secret, sch, scope, sorts, query.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("get %s by keyset failed, invalid cursor: %w: %w", sch.Table, errs.ErrInvalidArgument, err)
+		}
+	}
+
+	// 多取一行用于判断是否还有更多数据
+	ptrModels, err := seek(ctx, sch, query.Spec, sorts, values, backward, query.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by keyset failed: %w", sch.Table, err)
+	}
+
+	page := &KeysetPage[PT]{}
+	hasMore := len(ptrModels) > query.Limit
+	if backward {
+		if hasMore {
+			ptrModels = ptrModels[1:]
+		}
+		page.HasPrev, page.HasNext = hasMore, true
+	} else {
+		if hasMore {
+			ptrModels = ptrModels[:query.Limit]
+		}
+		page.HasNext, page.HasPrev = hasMore, values != nil
+	}
+	page.Items = ptrModels
+
+	// 空页没有可用于翻页的行,不生成游标,HasNext/HasPrev 只在给出对应游标时为 true
+	if len(ptrModels) == 0 {
+		page.HasNext, page.HasPrev = false, false
+		return page, nil
+	}
+	if page.HasNext {
+		if page.NextCursor, err = makeCursor(ctx, secret, sch, scope, sorts, ptrModels[len(ptrModels)-1], false); err != nil {
+			return nil, fmt.Errorf("get %s by keyset failed, encode cursor error: %w", sch.Table, err)
+		}
+	}
+	if page.HasPrev {
+		if page.PrevCursor, err = makeCursor(ctx, secret, sch, scope, sorts, ptrModels[0], true); err != nil {
+			return nil, fmt.Errorf("get %s by keyset failed, encode cursor error: %w", sch.Table, err)
+		}
+	}
+	return page, nil
+}
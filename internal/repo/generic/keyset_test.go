@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"go-pattern/internal/tenant"
+)
+
+func seedProducts(t *testing.T, db *MemoryDB, n int) context.Context {
+	t.Helper()
+	ctx := tenant.WithTenant(context.Background(), 1)
+	products := NewMemoryRepo[model.Product](db)
+	for i := range n {
+		product := &model.Product{Name: fmt.Sprintf("p%d", i), Price: float64(i % 2), Quantity: 1}
+		if err := products.Create(ctx, product); err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	return ctx
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	payload := cursorPayload{Table: "products", Filter: "f", Sorts: "id:a"}
+	token, err := encodeCursor(secret, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		wantErr bool
+	}{
+		{"valid", secret, token, false},
+		{"wrong secret", []byte("other"), token, true},
+		{"tampered", secret, "x" + token, true},
+		{"no signature", secret, "abc", true},
+		{"bad base64", secret, "!!.!!", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.secret, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (got.Table != payload.Table || got.Filter != payload.Filter || got.Sorts != payload.Sorts) {
+				t.Fatalf("decodeCursor() = %+v, want %+v", got, payload)
+			}
+		})
+	}
+}
+
+func TestKeysetCursorBoundToQuery(t *testing.T) {
+	db := NewMemoryDB()
+	ctx := seedProducts(t, db, 6)
+	products := NewMemoryRepo[model.Product](db, WithCursorSecret([]byte("secret")))
+	users := NewMemoryRepo[model.User](db, WithCursorSecret([]byte("secret")))
+
+	filter := NewSpec(Eq("price", 1.0))
+	first, err := products.GetByKeyset(ctx, KeysetQuery{Spec: filter, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.NextCursor == "" {
+		t.Fatal("first page has no next cursor")
+	}
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{"same query", func() error {
+			_, err := products.GetByKeyset(ctx, KeysetQuery{Spec: NewSpec(Eq("price", 1.0)), Cursor: first.NextCursor, Limit: 1})
+			return err
+		}, nil},
+		{"other filter value", func() error {
+			_, err := products.GetByKeyset(ctx, KeysetQuery{Spec: NewSpec(Eq("price", 0.0)), Cursor: first.NextCursor, Limit: 1})
+			return err
+		}, errs.ErrInvalidArgument},
+		{"no filter", func() error {
+			_, err := products.GetByKeyset(ctx, KeysetQuery{Cursor: first.NextCursor, Limit: 1})
+			return err
+		}, errs.ErrInvalidArgument},
+		{"other model", func() error {
+			_, err := users.GetByKeyset(ctx, KeysetQuery{Spec: NewSpec(Eq("price", 1.0)), Cursor: first.NextCursor, Limit: 1})
+			return err
+		}, errs.ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("GetByKeyset() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetByKeyset() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeysetEmptyBackwardPage(t *testing.T) {
+	db := NewMemoryDB()
+	ctx := seedProducts(t, db, 2)
+	products := NewMemoryRepo[model.Product](db)
+
+	first, err := products.GetByKeyset(ctx, KeysetQuery{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一行之前没有数据,向后翻页得到空页
+	page, err := products.GetByKeyset(ctx, KeysetQuery{Cursor: mustCursor(t, products, ctx, first.Items[0]), Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 || page.HasNext || page.HasPrev || page.NextCursor != "" || page.PrevCursor != "" {
+		t.Fatalf("empty backward page = %+v, want no items, cursors or flags", page)
+	}
+}
+
+// mustCursor 生成指向 item 之前的向后翻页游标
+func mustCursor(t *testing.T, r GenericRepo[model.Product, *model.Product], ctx context.Context, item *model.Product) string {
+	t.Helper()
+	sch, err := memorySchema[model.Product]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorts, err := keysetSorts(sch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := makeCursor(ctx, r.(*memoryRepo[model.Product, *model.Product]).opts.secret(), sch, newCursorScope(sch, nil), sorts, item, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
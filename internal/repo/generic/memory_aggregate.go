@@ -0,0 +1,231 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+func (r *memoryRepo[T, PT]) Aggregate(ctx context.Context, query AggregateQuery) ([]AggregateRow, error) {
+	pt := PT(new(T))
+
+	rows, err := r.aggregate(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate %s failed: %w", pt.TableName(), err)
+	}
+	return rows, nil
+}
+
+// memoryGroup 一个分组:分组键的值与组内的行
+type memoryGroup[PT any] struct {
+	keys []any
+	rows []PT
+}
+
+func (r *memoryRepo[T, PT]) aggregate(ctx context.Context, query AggregateQuery) ([]AggregateRow, error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("parse schema error: %w", err)
+	}
+	columns := sch.FieldsByDBName
+	// 只使用计划的校验结果,其中的 SQL 表达式不会执行,方言无关紧要
+	plan, err := planAggregate(columns, query, DialectPostgres)
+	if err != nil {
+		return nil, err
+	}
+
+	// 存储中的行不会被修改,可以在锁外计算
+	var rows []PT
+	err = r.read(ctx, func(d *memoryData) error {
+		rows, err = r.find(ctx, d, sch, query.Spec.whereOnly(), false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*memoryGroup[PT]
+	index := make(map[string]*memoryGroup[PT])
+	for _, row := range rows {
+		keys := make([]any, len(query.GroupBy))
+		for i, group := range query.GroupBy {
+			keys[i] = columnValue(ctx, columns[group.field], row)
+			if t, ok := keys[i].(time.Time); ok && group.bucket != "" {
+				keys[i] = truncateTime(t, group.bucket)
+			}
+		}
+		key := fmt.Sprintf("%#v", keys)
+		if _, ok := index[key]; !ok {
+			index[key] = &memoryGroup[PT]{keys: keys}
+			groups = append(groups, index[key])
+		}
+		index[key].rows = append(index[key].rows, row)
+	}
+	// 与 SQL 一样,没有分组时即使没有任何行也返回一行
+	if len(query.GroupBy) == 0 && len(groups) == 0 {
+		groups = append(groups, &memoryGroup[PT]{})
+	}
+
+	results := make([]AggregateRow, 0, len(groups))
+	for _, group := range groups {
+		result := make(AggregateRow, len(plan.aliases))
+		for i, g := range query.GroupBy {
+			result[g.alias] = group.keys[i]
+		}
+		for _, metric := range query.Metrics {
+			if result[metric.alias], err = metricValue(ctx, columns, metric, group.rows); err != nil {
+				return nil, err
+			}
+		}
+		matched := true
+		for _, cond := range query.Having {
+			ok, err := cond.match(func(alias string) (any, bool) {
+				if _, ok := plan.metrics[alias]; !ok {
+					return nil, false
+				}
+				return result[alias], true
+			})
+			if err != nil {
+				return nil, err
+			}
+			matched = matched && ok
+		}
+		if matched {
+			results = append(results, result)
+		}
+	}
+
+	if len(query.OrderBy) > 0 {
+		slices.SortStableFunc(results, func(a, b AggregateRow) int {
+			for _, sort := range query.OrderBy {
+				if cmp := compareSorted(a[sort.Field], b[sort.Field], sort.Desc); cmp != 0 {
+					return cmp
+				}
+			}
+			return 0
+		})
+	}
+	if query.Limit > 0 && query.Limit < len(results) {
+		results = results[:query.Limit]
+	}
+	return results, nil
+}
+
+// truncateTime 与 date_trunc 一致,按周截断时以周一为一周的开始
+func truncateTime(t time.Time, bucket TimeBucket) time.Time {
+	year, month, day := t.Date()
+	switch bucket {
+	case BucketHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case BucketWeek:
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case BucketMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// metricValue 计算一个指标,NULL 被忽略;整数列的 SUM 为 int64,AVG 为 float64,
+// 没有非 NULL 值时 SUM/AVG/MIN/MAX 为 nil
+func metricValue[PT any](ctx context.Context, columns map[string]*schema.Field, metric Metric, rows []PT) (any, error) {
+	if len(metric.fields) == 0 {
+		return int64(len(rows)), nil
+	}
+
+	values := make([]any, 0, len(rows))
+	for _, row := range rows {
+		value, err := rowProduct(ctx, columns, metric.fields, row)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			values = append(values, value)
+		}
+	}
+
+	switch metric.fn {
+	case AggCount:
+		return int64(len(values)), nil
+	case AggMin, AggMax:
+		var result any
+		for _, value := range values {
+			if result == nil {
+				result = value
+				continue
+			}
+			cmp, err := compareValues(value, result)
+			if err != nil {
+				return nil, err
+			}
+			if (metric.fn == AggMin && cmp < 0) || (metric.fn == AggMax && cmp > 0) {
+				result = value
+			}
+		}
+		return result, nil
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var (
+		intSum   int64
+		floatSum float64
+		isInt    = true
+	)
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		if !isNumber(v) {
+			return nil, fmt.Errorf("%s requires numeric columns", metric.fn)
+		}
+		floatSum += toFloat(v)
+		if isInteger(v) {
+			intSum += toInt(v)
+		} else {
+			isInt = false
+		}
+	}
+	if metric.fn == AggAvg {
+		return floatSum / float64(len(values)), nil
+	}
+	if isInt {
+		return intSum, nil
+	}
+	return floatSum, nil
+}
+
+// rowProduct 单列时返回列值,多列时返回各列的乘积,任一列为 NULL 时结果为 nil
+func rowProduct(ctx context.Context, columns map[string]*schema.Field, fields []string, row any) (any, error) {
+	if len(fields) == 1 {
+		return columnValue(ctx, columns[fields[0]], row), nil
+	}
+	var (
+		intProduct   int64 = 1
+		floatProduct       = 1.0
+		isInt              = true
+	)
+	for _, name := range fields {
+		value := columnValue(ctx, columns[name], row)
+		if value == nil {
+			return nil, nil
+		}
+		v := reflect.ValueOf(value)
+		if !isNumber(v) {
+			return nil, fmt.Errorf("column %q is not numeric", name)
+		}
+		floatProduct *= toFloat(v)
+		if isInteger(v) {
+			intProduct *= toInt(v)
+		} else {
+			isInt = false
+		}
+	}
+	if isInt {
+		return intProduct, nil
+	}
+	return floatProduct, nil
+}
@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+
+	"gorm.io/gorm/schema"
+)
+
+// bulk 在一次原子操作中按过滤条件查出命中的行,再逐行执行 write,语义同 genericRepo.bulk
+func (r *memoryRepo[T, PT]) bulk(ctx context.Context, sch *schema.Schema, action, verb string, filter *Spec, opts []BulkOption, write func(d *memoryData, id uint64) (int64, error)) (int64, error) {
+	o := newBulkOptions(opts)
+	if err := o.checkFilter(filter); err != nil {
+		return 0, fmt.Errorf("%s %s where failed, %w", verb, sch.Table, err)
+	}
+	if err := filter.checkWhere(sch.FieldsByDBName); err != nil {
+		return 0, fmt.Errorf("%s %s where failed, invalid filter: %w: %w", verb, sch.Table, errs.ErrInvalidArgument, err)
+	}
+
+	var (
+		ids       []uint64
+		collected bool
+	)
+	collect := func(d *memoryData) ([]uint64, error) {
+		collected, ids = true, nil
+		rows, err := r.find(ctx, d, sch, filter.whereOnly(), false)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s where failed: %w", verb, sch.Table, err)
+		}
+		for _, row := range rows {
+			ids = append(ids, row.GetID())
+		}
+		return ids, nil
+	}
+
+	var affected int64
+	err := r.mutate(ctx, sch, action, collect, func(d *memoryData) ([]uint64, error) {
+		if !collected {
+			if _, err := collect(d); err != nil {
+				return nil, err
+			}
+		}
+		for _, id := range ids {
+			rows, err := write(d, id)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s where failed: %w", verb, sch.Table, err)
+			}
+			affected += rows
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// 在事务中时提交后才回调,回调中的缓存失效不会被并发读取以未提交前的数据回填
+	if len(ids) > 0 && len(o.onAffected) > 0 {
+		AfterCommit(ctx, r.opts.txHooks, func() {
+			for _, fn := range o.onAffected {
+				fn(ids)
+			}
+		})
+	}
+	return affected, nil
+}
+
+func (r *memoryRepo[T, PT]) UpdateWhere(ctx context.Context, filter *Spec, changes map[string]any, opts ...BulkOption) (int64, error) {
+	pt := PT(new(T))
+
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("update %s where failed, parse schema error: %w", pt.TableName(), err)
+	}
+	updates, err := bulkUpdates(sch, pt, changes)
+	if err != nil {
+		return 0, fmt.Errorf("update %s where failed, %w", pt.TableName(), err)
+	}
+
+	return r.bulk(ctx, sch, model.AuditActionUpdate, "update", filter, opts, func(d *memoryData, id uint64) (int64, error) {
+		stored, ok, err := lookupRow[T, PT](ctx, d, sch, id, false)
+		if err != nil || !ok {
+			return 0, err
+		}
+		row := copyRow(ctx, sch, stored)
+		if err := applyUpdates(ctx, sch, row, updates, memoryNow()); err != nil {
+			return 0, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+		}
+		row = copyRow(ctx, sch, row)
+		if err := checkUnique(ctx, d, sch, row); err != nil {
+			return 0, translateError(err)
+		}
+		return 1, d.put(sch.Table, id, row)
+	})
+}
+
+func (r *memoryRepo[T, PT]) DeleteWhere(ctx context.Context, filter *Spec, opts ...BulkOption) (int64, error) {
+	pt := PT(new(T))
+
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("delete %s where failed, parse schema error: %w", pt.TableName(), err)
+	}
+	return r.bulk(ctx, sch, model.AuditActionDelete, "delete", filter, opts, func(d *memoryData, id uint64) (int64, error) {
+		return r.remove(ctx, d, sch, []uint64{id})
+	})
+}
@@ -0,0 +1,318 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// ErrReadOnlyTransaction 在只读事务中执行写操作
+var ErrReadOnlyTransaction = errors.New("cannot execute write in a read-only transaction")
+
+// MemoryDB 内存仓储使用的数据库句柄,与 *gorm.DB 相对应:根句柄直接读写共享数据,
+// Transaction 返回的句柄绑定在事务上
+//
+// 事务基于快照:首次访问某张表时复制其当前数据,事务内的读写只作用于副本,
+// 提交时把修改过的行写回共享数据(后提交者覆盖),回滚时直接丢弃副本。
+// 事务内再次调用 Transaction 创建保存点,失败时按撤销日志回滚到保存点。
+// 与 Postgres 的序列一样,自增ID在回滚后不会复用
+type MemoryDB struct {
+	store *memoryStore
+	// tx 为 nil 表示不在事务中
+	tx *memoryData
+}
+
+// memoryStore 所有句柄共享的数据
+type memoryStore struct {
+	data *memoryData
+
+	seqMu sync.Mutex
+	seqs  map[string]uint64
+
+	// locks 被事务锁定的行(FOR UPDATE),事务结束时释放
+	lockMu sync.Mutex
+	locks  map[string]map[uint64]*memoryData
+}
+
+// memoryData 一份可读写的数据:共享数据本身或某个事务的快照
+type memoryData struct {
+	mu     sync.Mutex
+	store  *memoryStore
+	tables map[string]map[uint64]any
+	// undo 撤销日志,用于单次操作失败和回滚到保存点
+	undo []memoryUndo
+	// dirty 事务中修改过的行,提交时写回共享数据;共享数据本身为 nil
+	dirty    map[string]map[uint64]bool
+	readOnly bool
+	done     bool
+}
+
+type memoryUndo struct {
+	table   string
+	id      uint64
+	row     any
+	existed bool
+}
+
+func NewMemoryDB() *MemoryDB {
+	store := &memoryStore{
+		seqs:  make(map[string]uint64),
+		locks: make(map[string]map[uint64]*memoryData),
+	}
+	store.data = &memoryData{store: store, tables: make(map[string]map[uint64]any)}
+	return &MemoryDB{store: store}
+}
+
+type memoryTxKey struct{}
+
+// WithMemoryTx 将内存事务放入上下文,与 WithTx 对应
+func WithMemoryTx(ctx context.Context, tx *MemoryDB) context.Context {
+	return context.WithValue(ctx, memoryTxKey{}, tx)
+}
+
+// MemoryTxFrom 读取上下文中的内存事务
+func MemoryTxFrom(ctx context.Context) (*MemoryDB, bool) {
+	tx, ok := ctx.Value(memoryTxKey{}).(*MemoryDB)
+	return tx, ok && tx != nil && tx.tx != nil
+}
+
+// MemoryConn 上下文中有内存事务时加入事务,否则使用 db,与 Conn 对应
+func MemoryConn(ctx context.Context, db *MemoryDB) *MemoryDB {
+	if tx, ok := MemoryTxFrom(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// Transaction 在事务中执行 fn,fn 返回错误或 panic 时回滚;
+// 在已绑定事务的句柄上调用时创建保存点,opts 被忽略
+func (db *MemoryDB) Transaction(fn func(tx *MemoryDB) error, opts ...*sql.TxOptions) (err error) {
+	if db.tx != nil {
+		return db.savepoint(fn)
+	}
+	tx := &memoryData{
+		store:  db.store,
+		tables: make(map[string]map[uint64]any),
+		dirty:  make(map[string]map[uint64]bool),
+	}
+	for _, opt := range opts {
+		if opt != nil && opt.ReadOnly {
+			tx.readOnly = true
+		}
+	}
+	defer db.store.unlockAll(tx)
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			tx.finish()
+		}
+	}()
+	err = fn(&MemoryDB{store: db.store, tx: tx})
+	panicked = false
+	if err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+func (db *MemoryDB) savepoint(fn func(tx *MemoryDB) error) (err error) {
+	mark := db.tx.mark()
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			db.tx.rollbackTo(mark)
+		}
+	}()
+	err = fn(db)
+	panicked = false
+	return err
+}
+
+// data 本次调用读写的数据
+func (db *MemoryDB) data() *memoryData {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.store.data
+}
+
+// run 在数据锁内原子地执行一次操作,fn 返回错误时撤销其全部修改
+func (d *memoryData) run(fn func(d *memoryData) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return fmt.Errorf("transaction has already been committed or rolled back")
+	}
+	mark := len(d.undo)
+	if err := fn(d); err != nil {
+		d.undoTo(mark)
+		return err
+	}
+	// 共享数据上的操作完成后不再需要撤销日志
+	if d.dirty == nil {
+		d.undo = d.undo[:0]
+	}
+	return nil
+}
+
+// table 返回表的行,事务首次访问时复制共享数据中的当前内容
+func (d *memoryData) table(name string) map[uint64]any {
+	rows, ok := d.tables[name]
+	if ok {
+		return rows
+	}
+	if d.dirty != nil {
+		shared := d.store.data
+		shared.mu.Lock()
+		rows = maps.Clone(shared.tables[name])
+		shared.mu.Unlock()
+	}
+	if rows == nil {
+		rows = make(map[uint64]any)
+	}
+	d.tables[name] = rows
+	return rows
+}
+
+// put 写入一行,row 此后不能再被修改
+func (d *memoryData) put(table string, id uint64, row any) error {
+	if d.readOnly {
+		return ErrReadOnlyTransaction
+	}
+	rows := d.table(table)
+	previous, existed := rows[id]
+	d.undo = append(d.undo, memoryUndo{table: table, id: id, row: previous, existed: existed})
+	rows[id] = row
+	d.markDirty(table, id)
+	return nil
+}
+
+func (d *memoryData) delete(table string, id uint64) error {
+	if d.readOnly {
+		return ErrReadOnlyTransaction
+	}
+	rows := d.table(table)
+	previous, existed := rows[id]
+	if !existed {
+		return nil
+	}
+	d.undo = append(d.undo, memoryUndo{table: table, id: id, row: previous, existed: true})
+	delete(rows, id)
+	d.markDirty(table, id)
+	return nil
+}
+
+func (d *memoryData) markDirty(table string, id uint64) {
+	if d.dirty == nil {
+		return
+	}
+	if d.dirty[table] == nil {
+		d.dirty[table] = make(map[uint64]bool)
+	}
+	d.dirty[table][id] = true
+}
+
+// nextID 分配自增ID,不随事务回滚
+func (d *memoryData) nextID(table string) uint64 {
+	d.store.seqMu.Lock()
+	defer d.store.seqMu.Unlock()
+	d.store.seqs[table]++
+	return d.store.seqs[table]
+}
+
+// observeID 显式指定的ID推进序列,避免之后分配到相同的ID
+func (d *memoryData) observeID(table string, id uint64) {
+	d.store.seqMu.Lock()
+	defer d.store.seqMu.Unlock()
+	if id > d.store.seqs[table] {
+		d.store.seqs[table] = id
+	}
+}
+
+func (d *memoryData) mark() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.undo)
+}
+
+func (d *memoryData) rollbackTo(mark int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.undoTo(mark)
+}
+
+func (d *memoryData) undoTo(mark int) {
+	for i := len(d.undo) - 1; i >= mark; i-- {
+		entry := d.undo[i]
+		rows := d.tables[entry.table]
+		if entry.existed {
+			rows[entry.id] = entry.row
+		} else {
+			delete(rows, entry.id)
+		}
+	}
+	d.undo = d.undo[:mark]
+}
+
+// commit 把事务中修改过的行写回共享数据
+func (d *memoryData) commit() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done = true
+
+	shared := d.store.data
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	for table, ids := range d.dirty {
+		rows := d.tables[table]
+		target := shared.table(table)
+		for id := range ids {
+			if row, ok := rows[id]; ok {
+				target[id] = row
+			} else {
+				delete(target, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *memoryData) finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done = true
+}
+
+// tryLock 为事务锁定一行,已被其他未结束的事务锁定时返回 false
+func (s *memoryStore) tryLock(owner *memoryData, table string, id uint64) bool {
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+	if holder, ok := s.locks[table][id]; ok && holder != owner {
+		return false
+	}
+	if owner.dirty == nil {
+		// 不在事务中时锁只在语句执行期间有效
+		return true
+	}
+	if s.locks[table] == nil {
+		s.locks[table] = make(map[uint64]*memoryData)
+	}
+	s.locks[table][id] = owner
+	return true
+}
+
+func (s *memoryStore) unlockAll(owner *memoryData) {
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+	for _, rows := range s.locks {
+		for id, holder := range rows {
+			if holder == owner {
+				delete(rows, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// 内存实现按 SQL 语义对条件求值:与 NULL 比较的结果为假,升序时 NULL 排在最后
+
+// columnValue 读取记录在某列上的值,并规整为可比较的形式
+func columnValue(ctx context.Context, field *schema.Field, row any) any {
+	value, _ := field.ValueOf(ctx, reflect.ValueOf(row))
+	return normalizeValue(value)
+}
+
+// normalizeValue 还原 driver.Valuer(如 gorm.DeletedAt)、解引用指针,NULL 统一为 nil
+func normalizeValue(value any) any {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil
+		}
+		converted, err := valuer.Value()
+		if err != nil {
+			return value
+		}
+		value = converted
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// compareValues 比较两个非 NULL 值,类型不可比较时返回错误
+func compareValues(a, b any) (int, error) {
+	a, b = normalizeValue(a), normalizeValue(b)
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb), nil
+		}
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isInteger(va) && isInteger(vb):
+		return compareIntegers(va, vb), nil
+	case isNumber(va) && isNumber(vb):
+		return compareOrdered(toFloat(va), toFloat(vb)), nil
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return strings.Compare(va.String(), vb.String()), nil
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return compareOrdered(boolInt(va.Bool()), boolInt(vb.Bool())), nil
+	}
+	if ba, ok := a.([]byte); ok {
+		if bb, ok := b.([]byte); ok {
+			return bytes.Compare(ba, bb), nil
+		}
+	}
+	return 0, fmt.Errorf("can not compare %T with %T", a, b)
+}
+
+func isInteger(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isNumber(v reflect.Value) bool {
+	return isInteger(v) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func isUnsigned(v reflect.Value) bool {
+	return v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr
+}
+
+func compareIntegers(a, b reflect.Value) int {
+	switch {
+	case isUnsigned(a) && isUnsigned(b):
+		return compareOrdered(a.Uint(), b.Uint())
+	case isUnsigned(a):
+		if b.Int() < 0 {
+			return 1
+		}
+		return compareOrdered(a.Uint(), uint64(b.Int()))
+	case isUnsigned(b):
+		return -compareIntegers(b, a)
+	default:
+		return compareOrdered(a.Int(), b.Int())
+	}
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isUnsigned(v):
+		return float64(v.Uint())
+	case isInteger(v):
+		return float64(v.Int())
+	default:
+		return v.Float()
+	}
+}
+
+func toInt(v reflect.Value) int64 {
+	if isUnsigned(v) {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareOrdered[V int | int64 | uint64 | float64](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// likePattern 将 LIKE 模式转换为正则表达式,支持 % _ 与反斜杠转义
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			sb.WriteString(".*")
+		case r == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// match 对一行求值,resolve 返回字段的值;字段与运算符的校验与 build 一致
+func (c Cond) match(resolve func(field string) (any, bool)) (bool, error) {
+	if c.logic != "" {
+		if len(c.conds) == 0 {
+			return false, fmt.Errorf("empty %s group", c.logic)
+		}
+		// 先校验全部子条件,不因短路跳过非法条件
+		result := c.logic == logicAnd
+		for _, child := range c.conds {
+			ok, err := child.match(resolve)
+			if err != nil {
+				return false, err
+			}
+			if c.logic == logicAnd {
+				result = result && ok
+			} else {
+				result = result || ok
+			}
+		}
+		return result, nil
+	}
+
+	value, ok := resolve(c.field)
+	if !ok {
+		return false, fmt.Errorf("unknown column %q", c.field)
+	}
+	value = normalizeValue(value)
+	switch c.op {
+	case OpIsNull:
+		return value == nil, nil
+	case OpIsNotNull:
+		return value != nil, nil
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte:
+		if value == nil || normalizeValue(c.values[0]) == nil {
+			return false, nil
+		}
+		cmp, err := compareValues(value, c.values[0])
+		if err != nil {
+			return false, fmt.Errorf("column %q: %w", c.field, err)
+		}
+		switch c.op {
+		case OpEq:
+			return cmp == 0, nil
+		case OpNe:
+			return cmp != 0, nil
+		case OpGt:
+			return cmp > 0, nil
+		case OpGte:
+			return cmp >= 0, nil
+		case OpLt:
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	case OpLike:
+		s, isString := value.(string)
+		pattern, isPattern := c.values[0].(string)
+		if value == nil {
+			return false, nil
+		}
+		if !isString || !isPattern {
+			return false, fmt.Errorf("LIKE on column %q requires strings", c.field)
+		}
+		re, err := likePattern(pattern)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(s), nil
+	case OpIn, OpNotIn:
+		list := reflect.ValueOf(c.values[0])
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return false, fmt.Errorf("%s on column %q requires a slice", c.op, c.field)
+		}
+		if list.Len() == 0 {
+			return false, fmt.Errorf("%s on column %q requires at least one value", c.op, c.field)
+		}
+		if value == nil {
+			return false, nil
+		}
+		found := false
+		for i := 0; i < list.Len() && !found; i++ {
+			item := normalizeValue(list.Index(i).Interface())
+			if item == nil {
+				continue
+			}
+			cmp, err := compareValues(value, item)
+			if err != nil {
+				return false, fmt.Errorf("column %q: %w", c.field, err)
+			}
+			found = cmp == 0
+		}
+		return found == (c.op == OpIn), nil
+	case OpBetween:
+		if value == nil {
+			return false, nil
+		}
+		low, err := compareValues(value, c.values[0])
+		if err != nil {
+			return false, fmt.Errorf("column %q: %w", c.field, err)
+		}
+		high, err := compareValues(value, c.values[1])
+		if err != nil {
+			return false, fmt.Errorf("column %q: %w", c.field, err)
+		}
+		return low >= 0 && high <= 0, nil
+	default:
+		return false, fmt.Errorf("unsupported operator %q", c.op)
+	}
+}
+
+// compareSorted 按排序列比较两行,NULL 视为最大值(升序在后、降序在前,与 Postgres 一致)
+func compareSorted(a, b any, desc bool) int {
+	a, b = normalizeValue(a), normalizeValue(b)
+	var cmp int
+	switch {
+	case a == nil && b == nil:
+		cmp = 0
+	case a == nil:
+		cmp = 1
+	case b == nil:
+		cmp = -1
+	default:
+		cmp, _ = compareValues(a, b)
+	}
+	if desc {
+		return -cmp
+	}
+	return cmp
+}
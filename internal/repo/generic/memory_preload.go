@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/schema"
+)
+
+// preloadPaths 解析查询选项中的预加载关联,每个关联为从模型出发的路径
+func preloadPaths(sch *schema.Schema, opts []QueryOption) ([][]*schema.Relationship, error) {
+	var paths [][]*schema.Relationship
+	for _, relation := range newQueryOptions(opts).preloads {
+		path, err := resolveRelation(sch, relation)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// preloadRows 为 owners 逐级加载关联,与 gorm 的 Preload 一样按租户隔离并排除已软删除的关联记录
+func preloadRows[PT any](ctx context.Context, d *memoryData, owners []PT, paths [][]*schema.Relationship) error {
+	if len(paths) == 0 || len(owners) == 0 {
+		return nil
+	}
+	values := make([]reflect.Value, 0, len(owners))
+	for _, owner := range owners {
+		values = append(values, reflect.ValueOf(owner))
+	}
+	for _, path := range paths {
+		level := values
+		for _, rel := range path {
+			var err error
+			if level, err = loadRelation(ctx, d, rel, level); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadRelation 为每个 owner 设置关联字段,返回加载的关联记录供下一级使用
+func loadRelation(ctx context.Context, d *memoryData, rel *schema.Relationship, owners []reflect.Value) ([]reflect.Value, error) {
+	if len(rel.References) != 1 {
+		return nil, fmt.Errorf("preload %s is not supported in memory", rel.Name)
+	}
+	ref := rel.References[0]
+	ownerField, targetField := ref.ForeignKey, ref.PrimaryKey
+	if ref.OwnPrimaryKey {
+		ownerField, targetField = ref.PrimaryKey, ref.ForeignKey
+	}
+	target := rel.FieldSchema
+	scope, err := newMemoryScope(ctx, target, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按关联键分组目标记录,保持主键顺序
+	rows := d.table(target.Table)
+	byKey := make(map[string][]reflect.Value)
+	for _, id := range slices.Sorted(maps.Keys(rows)) {
+		row := rows[id]
+		if !scope.visible(ctx, row) {
+			continue
+		}
+		if key := columnValue(ctx, targetField, row); key != nil {
+			byKey[fmt.Sprint(key)] = append(byKey[fmt.Sprint(key)], reflect.ValueOf(row))
+		}
+	}
+
+	var loaded []reflect.Value
+	for _, owner := range owners {
+		var matches []reflect.Value
+		if key := columnValue(ctx, ownerField, owner.Interface()); key != nil {
+			matches = byKey[fmt.Sprint(key)]
+		}
+		field := rel.Field.ReflectValueOf(ctx, owner)
+		if rel.Type == schema.HasMany {
+			slice := reflect.MakeSlice(field.Type(), 0, len(matches))
+			for _, match := range matches {
+				clone := copyValue(ctx, target, match)
+				loaded = append(loaded, clone)
+				slice = reflect.Append(slice, relatedValue(field.Type().Elem(), clone))
+			}
+			field.Set(slice)
+			continue
+		}
+		if len(matches) == 0 {
+			field.SetZero()
+			continue
+		}
+		clone := copyValue(ctx, target, matches[0])
+		loaded = append(loaded, clone)
+		field.Set(relatedValue(field.Type(), clone))
+	}
+	return loaded, nil
+}
+
+// relatedValue 按关联字段的类型返回指针或结构体本身
+func relatedValue(typ reflect.Type, ptr reflect.Value) reflect.Value {
+	if typ.Kind() == reflect.Pointer {
+		return ptr
+	}
+	return ptr.Elem()
+}
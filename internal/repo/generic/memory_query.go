@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/model"
+	"slices"
+)
+
+// MemoryQuery 自定义仓储方法内存实现的查询,对应 GORM 版本中的 Where/Order/Limit/Locking
+type MemoryQuery[PT any] struct {
+	// Where 为 nil 时匹配全部行,传入的行不能被修改
+	Where func(row PT) bool
+	// Compare 排序比较函数,为 nil 时按主键升序
+	Compare func(a, b PT) int
+	// Limit 为 0 时不限制
+	Limit int
+	// SkipLocked 对应 FOR UPDATE SKIP LOCKED:锁定返回的行直到事务结束,跳过已被其他事务锁定的行
+	SkipLocked bool
+}
+
+// MemorySelect 查询满足条件的行的副本,与 TenantScope 一样按租户隔离,并排除已软删除的行。
+// 供自定义仓储方法的内存实现使用,上下文中有内存事务时加入事务
+func MemorySelect[T any, PT model.PointerModel[T]](ctx context.Context, db *MemoryDB, query MemoryQuery[PT]) ([]PT, error) {
+	sch, err := memorySchema[T, PT]()
+	if err != nil {
+		return nil, fmt.Errorf("parse schema error: %w", err)
+	}
+	var selected []PT
+	err = MemoryConn(ctx, db).data().run(func(d *memoryData) error {
+		rows, err := visibleRows[T, PT](ctx, d, sch, false)
+		if err != nil {
+			return err
+		}
+		if query.Where != nil {
+			rows = slices.DeleteFunc(rows, func(row PT) bool { return !query.Where(row) })
+		}
+		if query.Compare != nil {
+			slices.SortStableFunc(rows, query.Compare)
+		}
+		for _, row := range rows {
+			if query.Limit > 0 && len(selected) == query.Limit {
+				break
+			}
+			if query.SkipLocked && !d.store.tryLock(d, sch.Table, row.GetID()) {
+				continue
+			}
+			selected = append(selected, copyRow(ctx, sch, row))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return selected, nil
+}
+
+// MemoryUpdate 原子地修改满足 where 的行并返回修改的行数:update 作用于行的副本,
+// 返回错误时撤销本次全部修改;自动刷新更新时间,并检查唯一约束。租户隔离与软删除同 MemorySelect
+func MemoryUpdate[T any, PT model.PointerModel[T]](ctx context.Context, db *MemoryDB, where func(row PT) bool, update func(row PT) error) (int64, error) {
+	sch, err := memorySchema[T, PT]()
+	if err != nil {
+		return 0, fmt.Errorf("parse schema error: %w", err)
+	}
+	var updated int64
+	err = MemoryConn(ctx, db).data().run(func(d *memoryData) error {
+		if d.readOnly {
+			return ErrReadOnlyTransaction
+		}
+		rows, err := visibleRows[T, PT](ctx, d, sch, false)
+		if err != nil {
+			return err
+		}
+		now := memoryNow()
+		for _, row := range rows {
+			if where != nil && !where(row) {
+				continue
+			}
+			changed := copyRow(ctx, sch, row)
+			if err := update(changed); err != nil {
+				return err
+			}
+			if err := applyUpdates(ctx, sch, changed, nil, now); err != nil {
+				return err
+			}
+			changed = copyRow(ctx, sch, changed)
+			if err := checkUnique(ctx, d, sch, changed); err != nil {
+				return translateError(err)
+			}
+			if err := d.put(sch.Table, row.GetID(), changed); err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,759 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"iter"
+	"reflect"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// memoryRepo GenericRepo 的内存实现,语义与 genericRepo 一致:租户隔离、软删除、
+// 乐观锁、唯一约束、审计以及分页与游标的行为都相同,用于不依赖数据库的单元测试
+type memoryRepo[T any, PT model.PointerModel[T]] struct {
+	db   *MemoryDB
+	opts options
+}
+
+// NewMemoryRepo 创建内存实现的 GenericRepo,共享同一个 MemoryDB 的仓储读写同一份数据
+func NewMemoryRepo[T any, PT model.PointerModel[T]](db *MemoryDB, opts ...Option) GenericRepo[T, PT] {
+	r := &memoryRepo[T, PT]{db: db}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if len(r.opts.interceptors) > 0 {
+		return newInterceptedRepo[T, PT](r, r.opts.interceptors)
+	}
+	return r
+}
+
+func (r *memoryRepo[T, PT]) schema() (*schema.Schema, error) {
+	return memorySchema[T, PT]()
+}
+
+// read 在数据锁内执行一次读操作,上下文中有内存事务时读取事务的快照
+func (r *memoryRepo[T, PT]) read(ctx context.Context, fn func(d *memoryData) error) error {
+	return MemoryConn(ctx, r.db).data().run(fn)
+}
+
+// mutate 原子地执行一次写操作,失败时撤销全部修改;targets 与 fn 的约定同 genericRepo.mutate
+func (r *memoryRepo[T, PT]) mutate(ctx context.Context, sch *schema.Schema, action string, targets func(d *memoryData) ([]uint64, error), fn func(d *memoryData) ([]uint64, error)) error {
+	return MemoryConn(ctx, r.db).data().run(func(d *memoryData) error {
+		if d.readOnly {
+			return fmt.Errorf("%s %s failed: %w", action, sch.Table, ErrReadOnlyTransaction)
+		}
+		if !r.opts.audit {
+			_, err := fn(d)
+			return err
+		}
+		var (
+			ids     []uint64
+			before  map[uint64]model.JSON
+			tenants = make(map[uint64]uint64)
+			err     error
+		)
+		if targets != nil {
+			if ids, err = targets(d); err != nil {
+				return err
+			}
+			if before, err = r.snapshot(ctx, d, sch, ids, tenants); err != nil {
+				return err
+			}
+		}
+		changed, err := fn(d)
+		if err != nil {
+			return err
+		}
+		if changed != nil {
+			ids = changed
+		}
+		after, err := r.snapshot(ctx, d, sch, ids, tenants)
+		if err != nil {
+			return err
+		}
+		return r.record(ctx, d, action, ids, before, after, tenants)
+	})
+}
+
+// snapshot 以JSON保存记录当前状态,包含已软删除的记录;其他租户的记录没有快照。
+// 记录所属的租户写入 tenants
+func (r *memoryRepo[T, PT]) snapshot(ctx context.Context, d *memoryData, sch *schema.Schema, ids []uint64, tenants map[uint64]uint64) (map[uint64]model.JSON, error) {
+	snapshots := make(map[uint64]model.JSON, len(ids))
+	scope, err := newMemoryScope(ctx, sch, true)
+	if err != nil {
+		return nil, err
+	}
+	rows := d.table(sch.Table)
+	for _, id := range ids {
+		row, ok := rows[id]
+		if !ok || !scope.visible(ctx, row) {
+			continue
+		}
+		tenants[id] = tenantOf(ctx, sch, reflect.ValueOf(row))
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s failed, marshal error: %w", sch.Table, err)
+		}
+		snapshots[id] = data
+	}
+	return snapshots, nil
+}
+
+func (r *memoryRepo[T, PT]) record(ctx context.Context, d *memoryData, action string, ids []uint64, before, after map[uint64]model.JSON, tenants map[uint64]uint64) error {
+	auditLog := &model.AuditLog{}
+	now := memoryNow()
+	for _, log := range auditLogs(ctx, PT(new(T)).TableName(), action, ids, before, after, tenants) {
+		log.ID = d.nextID(auditLog.TableName())
+		log.CreatedAt = now
+		if err := d.put(auditLog.TableName(), log.ID, log); err != nil {
+			return fmt.Errorf("record audit log for %s failed: %w", PT(new(T)).TableName(), err)
+		}
+	}
+	return nil
+}
+
+// memoryIDs 返回固定ID列表的 targets
+func memoryIDs(ids ...uint64) func(d *memoryData) ([]uint64, error) {
+	return func(d *memoryData) ([]uint64, error) {
+		return ids, nil
+	}
+}
+
+// find 查询对调用方可见且满足规格的行,规格须已校验;返回存储中的行,调用方不能修改
+func (r *memoryRepo[T, PT]) find(ctx context.Context, d *memoryData, sch *schema.Schema, spec *Spec, unscoped bool) ([]PT, error) {
+	rows, err := visibleRows[T, PT](ctx, d, sch, unscoped)
+	if err != nil {
+		return nil, err
+	}
+	return filterRows(ctx, sch, rows, spec)
+}
+
+// findCopies 在一次读操作中查询并复制结果,按查询选项预加载关联
+func (r *memoryRepo[T, PT]) findCopies(ctx context.Context, sch *schema.Schema, spec *Spec, preloads [][]*schema.Relationship) ([]PT, error) {
+	ptrModels := make([]PT, 0, 10)
+	err := r.read(ctx, func(d *memoryData) error {
+		rows, err := r.find(ctx, d, sch, spec, false)
+		if err != nil {
+			return err
+		}
+		ptrModels = append(ptrModels, copyRows(ctx, sch, rows)...)
+		return preloadRows(ctx, d, ptrModels, preloads)
+	})
+	return ptrModels, err
+}
+
+func (r *memoryRepo[T, PT]) Create(ctx context.Context, ptrModel PT) error {
+	if ptrModel == nil {
+		return fmt.Errorf("create %s failed, ptrModel is nil: %w", ptrModel.TableName(), errs.ErrInvalidArgument)
+	}
+
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("create %s failed, parse schema error: %w", ptrModel.TableName(), err)
+	}
+	if err := assignTenant(ctx, sch, ptrModel); err != nil {
+		return fmt.Errorf("create %s failed: %w", ptrModel.TableName(), err)
+	}
+
+	return r.mutate(ctx, sch, model.AuditActionCreate, nil, func(d *memoryData) ([]uint64, error) {
+		if err := insertRow(ctx, d, sch, ptrModel, memoryNow()); err != nil {
+			return nil, fmt.Errorf("create %s failed: %w", ptrModel.TableName(), translateError(err))
+		}
+		return []uint64{ptrModel.GetID()}, nil
+	})
+}
+
+func (r *memoryRepo[T, PT]) CreateInBatches(ctx context.Context, ptrModels []PT, batchSize int) error {
+	if len(ptrModels) == 0 || batchSize <= 0 {
+		ptr := PT(new(T))
+		return fmt.Errorf("create %s in batchs failed, no models provided or batchSize %d invalid: %w", ptr.TableName(), batchSize, errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("create %s in batchs failed, parse schema error: %w", ptrModels[0].TableName(), err)
+	}
+	if err := assignTenant(ctx, sch, ptrModels...); err != nil {
+		return fmt.Errorf("create %s in batchs failed: %w", ptrModels[0].TableName(), err)
+	}
+
+	return r.mutate(ctx, sch, model.AuditActionCreate, nil, func(d *memoryData) ([]uint64, error) {
+		now := memoryNow()
+		ids := make([]uint64, 0, len(ptrModels))
+		for _, ptrModel := range ptrModels {
+			if err := insertRow(ctx, d, sch, ptrModel, now); err != nil {
+				return nil, fmt.Errorf("create %s in batchs failed: %w", ptrModels[0].TableName(), translateError(err))
+			}
+			ids = append(ids, ptrModel.GetID())
+		}
+		return ids, nil
+	})
+}
+
+// preloads 解析查询选项中的预加载路径,非法时返回 ErrInvalidArgument
+func (r *memoryRepo[T, PT]) preloads(sch *schema.Schema, opts []QueryOption) ([][]*schema.Relationship, error) {
+	paths, err := preloadPaths(sch, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+	}
+	return paths, nil
+}
+
+// getByID 按ID读取一行的副本并预加载关联,unscoped 时包括已软删除的行
+func (r *memoryRepo[T, PT]) getByID(ctx context.Context, id uint64, unscoped bool, opts []QueryOption) (PT, error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("parse schema error: %w", err)
+	}
+	preloads, err := r.preloads(sch, opts)
+	if err != nil {
+		return nil, err
+	}
+	var ptrModel PT
+	err = r.read(ctx, func(d *memoryData) error {
+		row, ok, err := lookupRow[T, PT](ctx, d, sch, id, unscoped)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return translateError(gorm.ErrRecordNotFound)
+		}
+		ptrModel = copyRow(ctx, sch, row)
+		return preloadRows(ctx, d, []PT{ptrModel}, preloads)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ptrModel, nil
+}
+
+func (r *memoryRepo[T, PT]) GetByID(ctx context.Context, id uint64, opts ...QueryOption) (PT, error) {
+	pt := PT(new(T))
+
+	if id == 0 {
+		return nil, fmt.Errorf("get %s by id %d failed, id must be greater than 0: %w", pt.TableName(), id, errs.ErrInvalidArgument)
+	}
+	ptrModel, err := r.getByID(ctx, id, false, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by id %d failed: %w", pt.TableName(), id, err)
+	}
+	return ptrModel, nil
+}
+
+func (r *memoryRepo[T, PT]) GetByIDs(ctx context.Context, ids []uint64, opts ...QueryOption) ([]PT, error) {
+	pt := PT(new(T))
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("get %s by ids failed, no ids provided: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by ids %v failed, parse schema error: %w", pt.TableName(), ids, err)
+	}
+	preloads, err := r.preloads(sch, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by ids %v failed: %w", pt.TableName(), ids, err)
+	}
+
+	ptrModels, err := r.findCopies(ctx, sch, NewSpec(In(pt.GetPrimaryKey(), ids)), preloads)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by ids %v failed: %w", pt.TableName(), ids, err)
+	}
+	if len(ptrModels) == 0 {
+		return nil, fmt.Errorf("get %s by ids %v failed: %w", pt.TableName(), ids, errs.ErrNotFound)
+	}
+	return ptrModels, nil
+}
+
+func (r *memoryRepo[T, PT]) GetByStructFields(ctx context.Context, structModel PT) ([]PT, error) {
+	if structModel == nil {
+		return nil, fmt.Errorf("get %s by structModel failed, structModel is nil: %w", structModel.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by structModel %v failed, parse schema error: %w", structModel.TableName(), structModel, err)
+	}
+
+	// 与 gorm 的 Where(struct) 一样只以非零值的列作为条件
+	spec := NewSpec()
+	value := reflect.ValueOf(structModel)
+	for _, field := range sch.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if v, isZero := field.ValueOf(ctx, value); !isZero {
+			spec.Where(Eq(field.DBName, v))
+		}
+	}
+	ptrModels, err := r.findCopies(ctx, sch, spec, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by structModel %v failed: %w", structModel.TableName(), structModel, err)
+	}
+	if len(ptrModels) == 0 {
+		return nil, fmt.Errorf("get %s by structModel %v failed: %w", structModel.TableName(), structModel, errs.ErrNotFound)
+	}
+	return ptrModels, nil
+}
+
+func (r *memoryRepo[T, PT]) GetByMapFields(ctx context.Context, mapFields map[string]any) ([]PT, error) {
+	pt := PT(new(T))
+	if mapFields == nil {
+		return nil, fmt.Errorf("get %s by mapFields failed, mapFields is nil: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by mapFields %v failed, parse schema error: %w", pt.TableName(), mapFields, err)
+	}
+
+	// 与 gorm 的 Where(map) 一样:切片值为 IN,nil 为 IS NULL
+	spec := NewSpec()
+	for column, value := range mapFields {
+		v := reflect.ValueOf(value)
+		switch {
+		case value == nil:
+			spec.Where(IsNull(column))
+		case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+			spec.Where(In(column, value))
+		default:
+			spec.Where(Eq(column, value))
+		}
+	}
+	if err := spec.checkWhere(sch.FieldsByDBName); err != nil {
+		return nil, fmt.Errorf("get %s by mapFields %v failed: %w: %w", pt.TableName(), mapFields, errs.ErrInvalidArgument, err)
+	}
+	ptrModels, err := r.findCopies(ctx, sch, spec, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by mapFields %v failed: %w", pt.TableName(), mapFields, err)
+	}
+	if len(ptrModels) == 0 {
+		return nil, fmt.Errorf("get %s by mapFields %v failed: %w", pt.TableName(), mapFields, errs.ErrNotFound)
+	}
+	return ptrModels, nil
+}
+
+func (r *memoryRepo[T, PT]) GetByPage(ctx context.Context, page, pageSize uint64) ([]PT, error) {
+	pt := PT(new(T))
+
+	if page <= 0 || pageSize <= 0 {
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed, page and pageSize must be greater than 0: %w", pt.TableName(), page, pageSize, errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed, parse schema error: %w", pt.TableName(), page, pageSize, err)
+	}
+
+	spec := NewSpec().
+		OrderBy(Asc(pt.GetPrimaryKey())).
+		Offset(int((page - 1) * pageSize)).
+		Limit(int(pageSize))
+	ptrModels, err := r.findCopies(ctx, sch, spec, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed: %w", pt.TableName(), page, pageSize, err)
+	}
+	if len(ptrModels) == 0 {
+		return nil, fmt.Errorf("get %s by page %d, pageSize %d failed: %w", pt.TableName(), page, pageSize, errs.ErrNotFound)
+	}
+	return ptrModels, nil
+}
+
+func (r *memoryRepo[T, PT]) GetPage(ctx context.Context, spec *Spec, page, pageSize uint64, estimateTotal bool, opts ...QueryOption) (*Page[PT], error) {
+	pt := PT(new(T))
+
+	if page <= 0 || pageSize <= 0 {
+		return nil, fmt.Errorf("get %s page %d, pageSize %d failed, page and pageSize must be greater than 0: %w", pt.TableName(), page, pageSize, errs.ErrInvalidArgument)
+	}
+
+	pageSpec := newPageSpec(spec, page, pageSize, pt.GetPrimaryKey())
+	items, err := r.Find(ctx, pageSpec, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// 内存实现没有统计信息,总数总是精确统计
+	total, err := r.Count(ctx, pageSpec)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(items, page, pageSize, total, false), nil
+}
+
+func (r *memoryRepo[T, PT]) GetByCursor(ctx context.Context, cursor, pageSize uint64) ([]PT, uint64, bool, error) {
+	pt := PT(new(T))
+
+	if pageSize <= 0 {
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d failed, pageSize must be greater than 0: %w", pt.TableName(), cursor, errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed, parse schema error: %w", pt.TableName(), cursor, pageSize, err)
+	}
+
+	spec := NewSpec(Gt(pt.GetPrimaryKey(), cursor)).
+		OrderBy(Asc(pt.GetPrimaryKey())).
+		Limit(int(pageSize + 1))
+	ptrModels, err := r.findCopies(ctx, sch, spec, nil)
+	if err != nil {
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed: %w", pt.TableName(), cursor, pageSize, err)
+	}
+	if len(ptrModels) == 0 {
+		return nil, cursor, false, fmt.Errorf("get %s by cursor %d, pageSize %d failed: %w", pt.TableName(), cursor, pageSize, errs.ErrNotFound)
+	}
+	hasMore := uint64(len(ptrModels)) > pageSize
+	if hasMore {
+		ptrModels = ptrModels[:pageSize]
+	}
+	return ptrModels, ptrModels[len(ptrModels)-1].GetID(), hasMore, nil
+}
+
+func (r *memoryRepo[T, PT]) seek(ctx context.Context, sch *schema.Schema, filter *Spec, sorts []Sort, values []any, backward bool, limit int) ([]PT, error) {
+	spec := seekSpec(filter, sorts, values, backward, limit)
+	if err := spec.check(sch.FieldsByDBName); err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+	}
+	ptrModels, err := r.findCopies(ctx, sch, spec, nil)
+	if err != nil {
+		return nil, err
+	}
+	if backward {
+		slices.Reverse(ptrModels)
+	}
+	return ptrModels, nil
+}
+
+func (r *memoryRepo[T, PT]) GetByKeyset(ctx context.Context, query KeysetQuery) (*KeysetPage[PT], error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("get %s by keyset failed, parse schema error: %w", PT(new(T)).TableName(), err)
+	}
+	return keysetPage(ctx, r.opts.secret(), sch, query, r.seek)
+}
+
+func (r *memoryRepo[T, PT]) Iterate(ctx context.Context, spec *Spec, batchSize int) iter.Seq2[PT, error] {
+	sch, err := r.schema()
+	if err != nil {
+		return func(yield func(PT, error) bool) {
+			yield(nil, fmt.Errorf("iterate %s failed, parse schema error: %w", PT(new(T)).TableName(), err))
+		}
+	}
+	return iterate(ctx, sch, spec, batchSize, r.seek)
+}
+
+func (r *memoryRepo[T, PT]) Update(ctx context.Context, ptrModel PT) error {
+	if ptrModel == nil {
+		ptr := PT(new(T))
+		return fmt.Errorf("update %s failed, ptrModel is nil: %w", ptr.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("update %s failed, parse schema error: %w", ptrModel.TableName(), err)
+	}
+	// 记录不能通过更新转移到其他租户:模型属于其他租户时拒绝,租户列不写入
+	if err := assignTenant(ctx, sch, ptrModel); err != nil {
+		return fmt.Errorf("update %s failed: %w", ptrModel.TableName(), err)
+	}
+
+	// 乐观锁:带上期望版本作为条件,并写入新版本
+	versioned, isVersioned := any(ptrModel).(model.VersionedModel)
+	var expected uint64
+	if isVersioned {
+		expected = versioned.GetVersion()
+		versioned.SetVersion(expected + 1)
+	}
+
+	id := ptrModel.GetID()
+	err = r.mutate(ctx, sch, model.AuditActionUpdate, memoryIDs(id), func(d *memoryData) ([]uint64, error) {
+		if id == 0 {
+			return nil, fmt.Errorf("update %s failed: %w", ptrModel.TableName(), gorm.ErrMissingWhereClause)
+		}
+		stored, ok, err := lookupRow[T, PT](ctx, d, sch, id, false)
+		if err != nil {
+			return nil, fmt.Errorf("update %s failed: %w", ptrModel.TableName(), err)
+		}
+		if !ok || (isVersioned && any(stored).(model.VersionedModel).GetVersion() != expected) {
+			if isVersioned {
+				return nil, fmt.Errorf("update %s id %d with version %d failed: %w", ptrModel.TableName(), id, expected, errs.ErrVersionConflict)
+			}
+			return nil, fmt.Errorf("update %s by id %d failed: %w", ptrModel.TableName(), id, errs.ErrNotFound)
+		}
+
+		// 与 gorm 的 Updates(struct) 一致:只写入非零值的列,更新时间同时回写到模型
+		row := copyRow(ctx, sch, stored)
+		source, target := reflect.ValueOf(ptrModel), reflect.ValueOf(row)
+		now := memoryNow()
+		for _, field := range sch.Fields {
+			if field.DBName == "" || field.PrimaryKey || !field.Updatable || field.DBName == tenantColumn {
+				continue
+			}
+			if field.AutoUpdateTime > 0 {
+				if err := field.Set(ctx, source, now); err != nil {
+					return nil, fmt.Errorf("update %s failed: %w", ptrModel.TableName(), err)
+				}
+			}
+			if _, isZero := field.ValueOf(ctx, source); !isZero {
+				field.ReflectValueOf(ctx, target).Set(field.ReflectValueOf(ctx, source))
+			}
+		}
+		row = copyRow(ctx, sch, row)
+		if err := checkUnique(ctx, d, sch, row); err != nil {
+			return nil, fmt.Errorf("update %s failed: %w", ptrModel.TableName(), translateError(err))
+		}
+		return nil, d.put(sch.Table, id, row)
+	})
+	if err != nil && isVersioned {
+		versioned.SetVersion(expected)
+	}
+	return err
+}
+
+func (r *memoryRepo[T, PT]) UpdateFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) error {
+	pt := PT(new(T))
+
+	if id == 0 || len(fieldMask) == 0 {
+		return fmt.Errorf("update %s fields failed, id and fieldMask are required: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("update %s fields failed, parse schema error: %w", pt.TableName(), err)
+	}
+
+	updates, expected, hasExpected, err := fieldUpdates(sch, pt, fieldMask, values)
+	if err != nil {
+		return fmt.Errorf("update %s fields failed, %w", pt.TableName(), err)
+	}
+	_, isVersioned := any(pt).(model.VersionedModel)
+
+	return r.mutate(ctx, sch, model.AuditActionUpdate, memoryIDs(id), func(d *memoryData) ([]uint64, error) {
+		stored, ok, err := lookupRow[T, PT](ctx, d, sch, id, false)
+		if err != nil {
+			return nil, fmt.Errorf("update %s fields %v by id %d failed: %w", pt.TableName(), fieldMask, id, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("update %s fields by id %d failed: %w", pt.TableName(), id, errs.ErrNotFound)
+		}
+		if isVersioned && hasExpected {
+			if cmp, err := compareValues(any(stored).(model.VersionedModel).GetVersion(), expected); err != nil || cmp != 0 {
+				return nil, fmt.Errorf("update %s fields by id %d with version %v failed: %w", pt.TableName(), id, expected, errs.ErrVersionConflict)
+			}
+		}
+		row := copyRow(ctx, sch, stored)
+		if err := applyUpdates(ctx, sch, row, updates, memoryNow()); err != nil {
+			return nil, fmt.Errorf("update %s fields %v by id %d failed: %w: %w", pt.TableName(), fieldMask, id, errs.ErrInvalidArgument, err)
+		}
+		row = copyRow(ctx, sch, row)
+		if err := checkUnique(ctx, d, sch, row); err != nil {
+			return nil, fmt.Errorf("update %s fields %v by id %d failed: %w", pt.TableName(), fieldMask, id, translateError(err))
+		}
+		return nil, d.put(sch.Table, id, row)
+	})
+}
+
+// remove 删除对调用方可见的行,软删除模型只标记 deleted_at,返回删除的行数
+func (r *memoryRepo[T, PT]) remove(ctx context.Context, d *memoryData, sch *schema.Schema, ids []uint64) (int64, error) {
+	deletedAt := softDeleteField(sch)
+	now := memoryNow()
+	var removed int64
+	for _, id := range slices.Compact(slices.Sorted(slices.Values(ids))) {
+		stored, ok, err := lookupRow[T, PT](ctx, d, sch, id, false)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		if deletedAt == nil {
+			err = d.delete(sch.Table, id)
+		} else {
+			row := copyRow(ctx, sch, stored)
+			deletedAt.ReflectValueOf(ctx, reflect.ValueOf(row)).Set(reflect.ValueOf(gorm.DeletedAt{Time: now, Valid: true}))
+			err = d.put(sch.Table, id, row)
+		}
+		if err != nil {
+			return 0, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (r *memoryRepo[T, PT]) DeleteByID(ctx context.Context, id uint64) error {
+	pt := PT(new(T))
+
+	if id == 0 {
+		return fmt.Errorf("delete %s failed, id is 0: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("delete %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	return r.mutate(ctx, sch, model.AuditActionDelete, memoryIDs(id), func(d *memoryData) ([]uint64, error) {
+		removed, err := r.remove(ctx, d, sch, []uint64{id})
+		if err != nil {
+			return nil, fmt.Errorf("delete %s failed: %w", pt.TableName(), err)
+		}
+		if removed == 0 {
+			return nil, fmt.Errorf("delete %s by id %d failed: %w", pt.TableName(), id, errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+func (r *memoryRepo[T, PT]) DeleteByIDs(ctx context.Context, ids []uint64) error {
+	pt := PT(new(T))
+
+	if len(ids) == 0 {
+		return fmt.Errorf("delete %s by ids failed, no ids provided: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("delete %s by ids %v failed, parse schema error: %w", pt.TableName(), ids, err)
+	}
+	return r.mutate(ctx, sch, model.AuditActionDelete, memoryIDs(ids...), func(d *memoryData) ([]uint64, error) {
+		removed, err := r.remove(ctx, d, sch, ids)
+		if err != nil {
+			return nil, fmt.Errorf("delete %s by ids %v failed: %w", pt.TableName(), ids, err)
+		}
+		if removed == 0 {
+			return nil, fmt.Errorf("delete %s by ids %v failed: %w", pt.TableName(), ids, errs.ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+func (r *memoryRepo[T, PT]) Find(ctx context.Context, spec *Spec, opts ...QueryOption) ([]PT, error) {
+	pt := PT(new(T))
+
+	sch, err := r.schema()
+	if err != nil {
+		return nil, fmt.Errorf("find %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	preloads, err := r.preloads(sch, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find %s failed: %w", pt.TableName(), err)
+	}
+	if err := spec.check(sch.FieldsByDBName); err != nil {
+		return nil, fmt.Errorf("find %s failed, invalid spec: %w: %w", pt.TableName(), errs.ErrInvalidArgument, err)
+	}
+
+	ptrModels, err := r.findCopies(ctx, sch, spec, preloads)
+	if err != nil {
+		return nil, fmt.Errorf("find %s failed: %w", pt.TableName(), err)
+	}
+	return ptrModels, nil
+}
+
+func (r *memoryRepo[T, PT]) Count(ctx context.Context, spec *Spec) (int64, error) {
+	pt := PT(new(T))
+
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("count %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	if err := spec.checkWhere(sch.FieldsByDBName); err != nil {
+		return 0, fmt.Errorf("count %s failed, invalid spec: %w: %w", pt.TableName(), errs.ErrInvalidArgument, err)
+	}
+
+	var count int64
+	err = r.read(ctx, func(d *memoryData) error {
+		rows, err := r.find(ctx, d, sch, spec.whereOnly(), false)
+		count = int64(len(rows))
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("count %s failed: %w", pt.TableName(), err)
+	}
+	return count, nil
+}
+
+func (r *memoryRepo[T, PT]) Restore(ctx context.Context, id uint64) error {
+	pt := PT(new(T))
+
+	if id == 0 {
+		return fmt.Errorf("restore %s failed, id is 0: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return fmt.Errorf("restore %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	deletedAt := softDeleteField(sch)
+	if deletedAt == nil {
+		return fmt.Errorf("restore %s failed, model is not soft deletable: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+
+	return r.mutate(ctx, sch, model.AuditActionRestore, memoryIDs(id), func(d *memoryData) ([]uint64, error) {
+		stored, ok, err := lookupRow[T, PT](ctx, d, sch, id, true)
+		if err != nil {
+			return nil, fmt.Errorf("restore %s by id %d failed: %w", pt.TableName(), id, err)
+		}
+		if !ok || columnValue(ctx, deletedAt, stored) == nil {
+			return nil, fmt.Errorf("restore %s by id %d failed, no deleted record: %w", pt.TableName(), id, errs.ErrNotFound)
+		}
+		row := copyRow(ctx, sch, stored)
+		deletedAt.ReflectValueOf(ctx, reflect.ValueOf(row)).SetZero()
+		return nil, d.put(sch.Table, id, row)
+	})
+}
+
+func (r *memoryRepo[T, PT]) GetWithDeleted(ctx context.Context, id uint64, opts ...QueryOption) (PT, error) {
+	pt := PT(new(T))
+
+	if id == 0 {
+		return nil, fmt.Errorf("get %s with deleted by id %d failed, id must be greater than 0: %w", pt.TableName(), id, errs.ErrInvalidArgument)
+	}
+	ptrModel, err := r.getByID(ctx, id, true, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s with deleted by id %d failed: %w", pt.TableName(), id, err)
+	}
+	return ptrModel, nil
+}
+
+func (r *memoryRepo[T, PT]) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	pt := PT(new(T))
+
+	if olderThan < 0 {
+		return 0, fmt.Errorf("purge %s failed, olderThan must not be negative: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return 0, fmt.Errorf("purge %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	if softDeleteField(sch) == nil {
+		return 0, fmt.Errorf("purge %s failed, model is not soft deletable: %w", pt.TableName(), errs.ErrInvalidArgument)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var purgeIDs []uint64
+	targets := func(d *memoryData) ([]uint64, error) {
+		rows, err := r.find(ctx, d, sch, NewSpec(IsNotNull("deleted_at"), Lt("deleted_at", cutoff)), true)
+		if err != nil {
+			return nil, fmt.Errorf("purge %s failed: %w", pt.TableName(), err)
+		}
+		purgeIDs = make([]uint64, 0, len(rows))
+		for _, row := range rows {
+			purgeIDs = append(purgeIDs, row.GetID())
+		}
+		return purgeIDs, nil
+	}
+
+	err = r.mutate(ctx, sch, model.AuditActionPurge, targets, func(d *memoryData) ([]uint64, error) {
+		if purgeIDs == nil {
+			if _, err := targets(d); err != nil {
+				return nil, err
+			}
+		}
+		for _, id := range purgeIDs {
+			if err := d.delete(sch.Table, id); err != nil {
+				return nil, fmt.Errorf("purge %s failed: %w", pt.TableName(), err)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(purgeIDs)), nil
+}
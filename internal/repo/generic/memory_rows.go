@@ -0,0 +1,381 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"go-pattern/internal/tenant"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// memoryNamingStrategy 与 gorm 的默认命名策略一致
+var memoryNamingStrategy = schema.NamingStrategy{IdentifierMaxLength: 64}
+
+func memorySchema[T any, PT model.PointerModel[T]]() (*schema.Schema, error) {
+	return schema.Parse(PT(new(T)), schemaCache, memoryNamingStrategy)
+}
+
+// memoryNow 当前时间,截断到 Postgres 时间戳的微秒精度
+func memoryNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// softDeleteField 模型的 gorm.DeletedAt 字段,非软删除模型返回 nil
+func softDeleteField(sch *schema.Schema) *schema.Field {
+	field := sch.LookUpField("deleted_at")
+	if field == nil || field.FieldType != reflect.TypeOf(gorm.DeletedAt{}) {
+		return nil
+	}
+	return field
+}
+
+// memoryScope 内存实现的租户隔离与软删除过滤,对应 tenantScope 与 gorm 的软删除条件
+type memoryScope struct {
+	tenantField *schema.Field
+	tenantID    uint64
+	deletedAt   *schema.Field
+}
+
+// newMemoryScope 上下文没有租户且未 Bypass 时返回错误;unscoped 时包含已软删除的行
+func newMemoryScope(ctx context.Context, sch *schema.Schema, unscoped bool) (memoryScope, error) {
+	var scope memoryScope
+	if field := sch.LookUpField(tenantColumn); field != nil && !tenant.Bypassed(ctx) {
+		tenantID, ok := tenant.FromContext(ctx)
+		if !ok {
+			return scope, fmt.Errorf("tenant is required: %w", errs.ErrInvalidArgument)
+		}
+		scope.tenantField, scope.tenantID = field, tenantID
+	}
+	if !unscoped {
+		scope.deletedAt = softDeleteField(sch)
+	}
+	return scope, nil
+}
+
+func (s memoryScope) visible(ctx context.Context, row any) bool {
+	if s.tenantField != nil {
+		value := columnValue(ctx, s.tenantField, row)
+		if cmp, err := compareValues(value, s.tenantID); value == nil || err != nil || cmp != 0 {
+			return false
+		}
+	}
+	return s.deletedAt == nil || columnValue(ctx, s.deletedAt, row) == nil
+}
+
+// copyRow 复制一行:切片与指针列深拷贝,关联字段置零。
+// 存储中的行不含关联且不会被修改,返回给调用方的都是副本
+func copyRow[T any, PT model.PointerModel[T]](ctx context.Context, sch *schema.Schema, row PT) PT {
+	return copyValue(ctx, sch, reflect.ValueOf(row)).Interface().(PT)
+}
+
+// copyValue 复制指向模型的指针 row,规则同 copyRow
+func copyValue(ctx context.Context, sch *schema.Schema, row reflect.Value) reflect.Value {
+	clone := reflect.New(row.Type().Elem())
+	clone.Elem().Set(row.Elem())
+	for _, field := range sch.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		fieldValue := field.ReflectValueOf(ctx, clone)
+		switch fieldValue.Kind() {
+		case reflect.Slice:
+			if !fieldValue.IsNil() {
+				fieldValue.Set(reflect.AppendSlice(reflect.MakeSlice(fieldValue.Type(), 0, fieldValue.Len()), fieldValue))
+			}
+		case reflect.Pointer:
+			if !fieldValue.IsNil() {
+				copied := reflect.New(fieldValue.Type().Elem())
+				copied.Elem().Set(fieldValue.Elem())
+				fieldValue.Set(copied)
+			}
+		}
+	}
+	// Relations 中还有其他模型声明的反向关联(如 _Product_Orders),其字段不属于本模型
+	for _, rel := range sch.Relationships.Relations {
+		if rel.Field != nil && rel.Field.Schema == sch {
+			rel.Field.ReflectValueOf(ctx, clone).SetZero()
+		}
+	}
+	return clone
+}
+
+func copyRows[T any, PT model.PointerModel[T]](ctx context.Context, sch *schema.Schema, rows []PT) []PT {
+	copied := make([]PT, 0, len(rows))
+	for _, row := range rows {
+		copied = append(copied, copyRow(ctx, sch, row))
+	}
+	return copied
+}
+
+// tableRows 按主键顺序返回表中的全部行,包括其他租户与已软删除的行
+func tableRows[PT any](d *memoryData, table string) []PT {
+	rows := d.table(table)
+	result := make([]PT, 0, len(rows))
+	for _, id := range slices.Sorted(maps.Keys(rows)) {
+		result = append(result, rows[id].(PT))
+	}
+	return result
+}
+
+// visibleRows 按主键顺序返回对调用方可见的行
+func visibleRows[T any, PT model.PointerModel[T]](ctx context.Context, d *memoryData, sch *schema.Schema, unscoped bool) ([]PT, error) {
+	scope, err := newMemoryScope(ctx, sch, unscoped)
+	if err != nil {
+		return nil, err
+	}
+	var rows []PT
+	for _, row := range tableRows[PT](d, sch.Table) {
+		if scope.visible(ctx, row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// lookupRow 按主键读取对调用方可见的一行
+func lookupRow[T any, PT model.PointerModel[T]](ctx context.Context, d *memoryData, sch *schema.Schema, id uint64, unscoped bool) (PT, bool, error) {
+	scope, err := newMemoryScope(ctx, sch, unscoped)
+	if err != nil {
+		return nil, false, err
+	}
+	row, ok := d.table(sch.Table)[id]
+	if !ok || !scope.visible(ctx, row) {
+		return nil, false, nil
+	}
+	return row.(PT), true, nil
+}
+
+// matches 一行是否满足全部过滤条件
+func (s *Spec) matches(ctx context.Context, columns map[string]*schema.Field, row any) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+	resolve := func(name string) (any, bool) {
+		field, ok := columns[name]
+		if !ok {
+			return nil, false
+		}
+		return columnValue(ctx, field, row), true
+	}
+	for _, cond := range s.conds {
+		ok, err := cond.match(resolve)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// filterRows 对已按主键排序的行应用查询规格,规格须已校验;未指定排序时保持主键顺序
+func filterRows[PT any](ctx context.Context, sch *schema.Schema, rows []PT, spec *Spec) ([]PT, error) {
+	columns := sch.FieldsByDBName
+	matched := make([]PT, 0, len(rows))
+	for _, row := range rows {
+		ok, err := spec.matches(ctx, columns, row)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errs.ErrInvalidArgument, err)
+		}
+		if ok {
+			matched = append(matched, row)
+		}
+	}
+	if spec == nil {
+		return matched, nil
+	}
+	if len(spec.sorts) > 0 {
+		slices.SortStableFunc(matched, func(a, b PT) int {
+			for _, sort := range spec.sorts {
+				field := columns[sort.Field]
+				if cmp := compareSorted(columnValue(ctx, field, a), columnValue(ctx, field, b), sort.Desc); cmp != 0 {
+					return cmp
+				}
+			}
+			return 0
+		})
+	}
+	if spec.offset > 0 {
+		matched = matched[min(spec.offset, len(matched)):]
+	}
+	if spec.limit > 0 && spec.limit < len(matched) {
+		matched = matched[:spec.limit]
+	}
+	return matched, nil
+}
+
+// insertRow 插入一行:按 gorm 的规则填充默认值与时间戳,分配自增主键并检查唯一约束,
+// 成功后把写入的列回填到 ptrModel,效果与 Postgres 的 RETURNING 一致
+func insertRow[T any, PT model.PointerModel[T]](ctx context.Context, d *memoryData, sch *schema.Schema, ptrModel PT, now time.Time) error {
+	row := copyRow(ctx, sch, ptrModel)
+	value := reflect.ValueOf(row)
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.PrimaryKey {
+			continue
+		}
+		if _, isZero := field.ValueOf(ctx, value); !isZero {
+			continue
+		}
+		var err error
+		switch {
+		case field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 ||
+			(field.DataType == schema.Time && strings.EqualFold(field.DefaultValue, "current_timestamp")):
+			err = field.Set(ctx, value, now)
+		case field.DefaultValueInterface != nil:
+			err = field.Set(ctx, value, field.DefaultValueInterface)
+		}
+		if err != nil {
+			return fmt.Errorf("set default value of column %q failed: %w", field.DBName, err)
+		}
+	}
+
+	primaryKey := sch.PrioritizedPrimaryField
+	id := row.GetID()
+	if id == 0 {
+		id = d.nextID(sch.Table)
+		if err := primaryKey.Set(ctx, value, id); err != nil {
+			return err
+		}
+	} else {
+		if _, exists := d.table(sch.Table)[id]; exists {
+			return fmt.Errorf("duplicate key value violates unique constraint %q: %w", sch.Table+"_pkey", gorm.ErrDuplicatedKey)
+		}
+		d.observeID(sch.Table, id)
+	}
+	if err := checkUnique(ctx, d, sch, row); err != nil {
+		return err
+	}
+	if err := d.put(sch.Table, id, row); err != nil {
+		return err
+	}
+
+	returned := reflect.ValueOf(copyRow(ctx, sch, row))
+	target := reflect.ValueOf(ptrModel)
+	for _, field := range sch.Fields {
+		if field.DBName != "" {
+			field.ReflectValueOf(ctx, target).Set(field.ReflectValueOf(ctx, returned))
+		}
+	}
+	return nil
+}
+
+// uniqueKey 主键之外的一个唯一约束
+type uniqueKey struct {
+	name   string
+	fields []*schema.Field
+}
+
+// uniqueKeys 模型声明的唯一索引与 unique 列
+func uniqueKeys(sch *schema.Schema) []uniqueKey {
+	var keys []uniqueKey
+	for _, index := range sch.ParseIndexes() {
+		if index.Class != "UNIQUE" {
+			continue
+		}
+		key := uniqueKey{name: index.Name}
+		for _, option := range index.Fields {
+			key.fields = append(key.fields, option.Field)
+		}
+		keys = append(keys, key)
+	}
+	for _, field := range sch.Fields {
+		if field.Unique && !field.PrimaryKey {
+			keys = append(keys, uniqueKey{name: fmt.Sprintf("%s_%s_key", sch.Table, field.DBName), fields: []*schema.Field{field}})
+		}
+	}
+	return keys
+}
+
+// hasUniqueKey columns 是否恰好对应主键或某个唯一约束(与顺序无关),ON CONFLICT 要求如此
+func hasUniqueKey(sch *schema.Schema, columns []string) bool {
+	sorted := slices.Sorted(slices.Values(columns))
+	if slices.Equal(sorted, []string{sch.PrioritizedPrimaryField.DBName}) {
+		return true
+	}
+	for _, key := range uniqueKeys(sch) {
+		names := make([]string, 0, len(key.fields))
+		for _, field := range key.fields {
+			names = append(names, field.DBName)
+		}
+		if slices.Equal(sorted, slices.Sorted(slices.Values(names))) {
+			return true
+		}
+	}
+	return false
+}
+
+// findConflict 查找在 fields 上与 row 取值相同的另一行,包括其他租户与已软删除的行;
+// 与 SQL 一致,含 NULL 的键不会冲突
+func findConflict[PT model.Model](ctx context.Context, d *memoryData, sch *schema.Schema, fields []*schema.Field, row PT) (PT, bool) {
+	var zero PT
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if values[i] = columnValue(ctx, field, row); values[i] == nil {
+			return zero, false
+		}
+	}
+	for _, other := range tableRows[PT](d, sch.Table) {
+		if other.GetID() == row.GetID() {
+			continue
+		}
+		equal := true
+		for i, field := range fields {
+			if cmp, err := compareValues(columnValue(ctx, field, other), values[i]); err != nil || cmp != 0 {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return other, true
+		}
+	}
+	return zero, false
+}
+
+// checkUnique 检查 row 是否违反唯一约束,违反时返回 gorm.ErrDuplicatedKey
+func checkUnique[PT model.Model](ctx context.Context, d *memoryData, sch *schema.Schema, row PT) error {
+	for _, key := range uniqueKeys(sch) {
+		if _, ok := findConflict(ctx, d, sch, key.fields, row); ok {
+			return fmt.Errorf("duplicate key value violates unique constraint %q: %w", key.name, gorm.ErrDuplicatedKey)
+		}
+	}
+	return nil
+}
+
+// applyUpdates 把以列名为键的更新写入 row,并刷新自动更新时间的列;
+// 表达式只支持 bulkUpdates 与 fieldUpdates 生成的 "version + 1"
+func applyUpdates(ctx context.Context, sch *schema.Schema, row any, updates map[string]any, now time.Time) error {
+	value := reflect.ValueOf(row)
+	for column, update := range updates {
+		field, ok := sch.FieldsByDBName[column]
+		if !ok {
+			return fmt.Errorf("unknown column %q", column)
+		}
+		if expr, ok := update.(clause.Expr); ok {
+			versioned, isVersioned := row.(model.VersionedModel)
+			if column != "version" || !isVersioned {
+				return fmt.Errorf("expression %q on column %q is not supported in memory", expr.SQL, column)
+			}
+			versioned.SetVersion(versioned.GetVersion() + 1)
+			continue
+		}
+		if err := field.Set(ctx, value, update); err != nil {
+			return fmt.Errorf("set column %q failed: %w", column, err)
+		}
+	}
+	for _, field := range sch.Fields {
+		if _, ok := updates[field.DBName]; !ok && field.AutoUpdateTime > 0 {
+			if err := field.Set(ctx, value, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
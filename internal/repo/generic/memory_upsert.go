@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+func (r *memoryRepo[T, PT]) Upsert(ctx context.Context, ptrModel PT, conflictColumns []string, updateColumns []string) (UpsertResult, error) {
+	if ptrModel == nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed, ptrModel is nil: %w", ptrModel.TableName(), errs.ErrInvalidArgument)
+	}
+	return r.UpsertInBatches(ctx, []PT{ptrModel}, 1, conflictColumns, updateColumns)
+}
+
+func (r *memoryRepo[T, PT]) UpsertInBatches(ctx context.Context, ptrModels []PT, batchSize int, conflictColumns []string, updateColumns []string) (UpsertResult, error) {
+	pt := PT(new(T))
+
+	if len(ptrModels) == 0 || batchSize <= 0 {
+		return UpsertResult{}, fmt.Errorf("upsert %s in batches failed, no models provided or batchSize %d invalid: %w", pt.TableName(), batchSize, errs.ErrInvalidArgument)
+	}
+	sch, err := r.schema()
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed, parse schema error: %w", pt.TableName(), err)
+	}
+	if err := assignTenant(ctx, sch, ptrModels...); err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed: %w", pt.TableName(), err)
+	}
+	onConflict, err := buildOnConflict(sch, pt, conflictColumns, updateColumns)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed: %w: %w", pt.TableName(), errs.ErrInvalidArgument, err)
+	}
+	// 与 Postgres 一样,冲突列必须恰好对应一个唯一约束
+	conflict := make([]*schema.Field, 0, len(onConflict.Columns))
+	names := make([]string, 0, len(onConflict.Columns))
+	for _, column := range onConflict.Columns {
+		conflict = append(conflict, sch.FieldsByDBName[column.Name])
+		names = append(names, column.Name)
+	}
+	if !hasUniqueKey(sch, names) {
+		return UpsertResult{}, fmt.Errorf("upsert %s failed, no unique constraint matches conflict columns %v: %w", pt.TableName(), names, errs.ErrInvalidArgument)
+	}
+
+	var total UpsertResult
+	err = r.mutate(ctx, sch, model.AuditActionUpsert, nil, func(d *memoryData) ([]uint64, error) {
+		var ids []uint64
+		now := memoryNow()
+		for start := 0; start < len(ptrModels); start += batchSize {
+			end := min(start+batchSize, len(ptrModels))
+			batch, batchIDs, err := upsertRows(ctx, d, sch, ptrModels[start:end], conflict, onConflict, now)
+			if err != nil {
+				return nil, err
+			}
+			total.add(batch)
+			ids = append(ids, batchIDs...)
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("upsert %s in batches failed: %w", pt.TableName(), translateError(err))
+	}
+	return total, nil
+}
+
+// upsertRows 逐行插入或按 onConflict 更新冲突行,返回实际插入或更新的行的主键;
+// 与 upsertBatch 一样,只有每行都被插入或更新时才回填主键
+func upsertRows[T any, PT model.PointerModel[T]](ctx context.Context, d *memoryData, sch *schema.Schema, batch []PT, conflict []*schema.Field, onConflict clause.OnConflict, now time.Time) (UpsertResult, []uint64, error) {
+	var result UpsertResult
+	ids := make([]uint64, 0, len(batch))
+	for _, item := range batch {
+		existing, found := findConflict(ctx, d, sch, conflict, item)
+		switch {
+		case !found:
+			if err := insertRow(ctx, d, sch, item, now); err != nil {
+				return UpsertResult{}, nil, err
+			}
+			result.Inserted++
+			ids = append(ids, item.GetID())
+		case onConflict.DoNothing:
+			result.Skipped++
+		default:
+			row := copyRow(ctx, sch, existing)
+			source, target := reflect.ValueOf(item), reflect.ValueOf(row)
+			for _, assignment := range onConflict.DoUpdates {
+				if versioned, ok := any(row).(model.VersionedModel); ok && assignment.Column.Name == "version" {
+					versioned.SetVersion(versioned.GetVersion() + 1)
+					continue
+				}
+				field := sch.FieldsByDBName[assignment.Column.Name]
+				field.ReflectValueOf(ctx, target).Set(field.ReflectValueOf(ctx, source))
+			}
+			row = copyRow(ctx, sch, row)
+			if err := checkUnique(ctx, d, sch, row); err != nil {
+				return UpsertResult{}, nil, err
+			}
+			if err := d.put(sch.Table, row.GetID(), row); err != nil {
+				return UpsertResult{}, nil, err
+			}
+			result.Updated++
+			ids = append(ids, row.GetID())
+		}
+	}
+
+	if len(ids) == len(batch) {
+		primaryKey := sch.PrioritizedPrimaryField
+		for i, item := range batch {
+			if err := primaryKey.Set(ctx, reflect.ValueOf(item), ids[i]); err != nil {
+				return UpsertResult{}, nil, err
+			}
+		}
+	}
+	return result, ids, nil
+}
@@ -0,0 +1,47 @@
+package repo
+
+// Option 配置 genericRepo 的可选行为
+type Option func(*options)
+
+type options struct {
+	// cursorSecret 键集分页游标的 HMAC 密钥
+	cursorSecret []byte
+	// replicas 读操作使用的只读副本,为空时全部走主库
+	replicas *ReplicaPool
+	// audit 为 true 时每次写操作都会记录审计日志
+	audit bool
+	// interceptors 环绕每次仓储调用的拦截器链
+	interceptors []Interceptor
+	// txHooks 仓储绑定在事务上时,提交后执行的回调
+	txHooks *TxHooks
+}
+
+// WithCursorSecret 设置游标签名密钥,多实例部署时必须一致,否则游标无法跨实例使用
+func WithCursorSecret(secret []byte) Option {
+	return func(o *options) {
+		o.cursorSecret = secret
+	}
+}
+
+// WithReplicas 将 Get*/Find/Count 等读操作路由到只读副本,写操作始终使用主库;
+// 传入 nil 关闭路由(事务内的仓储即如此)
+func WithReplicas(pool *ReplicaPool) Option {
+	return func(o *options) {
+		o.replicas = pool
+	}
+}
+
+// WithAudit 开启审计:每次写操作在同一事务中写入 audit_log,包含写前写后快照与操作者
+func WithAudit(enabled bool) Option {
+	return func(o *options) {
+		o.audit = enabled
+	}
+}
+
+// WithAfterCommit 仓储绑定在事务上时设置事务的提交回调,WithAffectedIDs 的回调在提交后执行;
+// 由事务工厂传入,通过上下文加入事务时从上下文读取
+func WithAfterCommit(hooks *TxHooks) Option {
+	return func(o *options) {
+		o.txHooks = hooks
+	}
+}
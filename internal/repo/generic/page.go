@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+)
+
+// Page 偏移分页结果,包含总数等元信息
+type Page[PT any] struct {
+	Items      []PT   `json:"items"`
+	Page       uint64 `json:"page"`
+	PageSize   uint64 `json:"page_size"`
+	Total      int64  `json:"total"`
+	TotalPages uint64 `json:"total_pages"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	// Estimated 为 true 时 Total 来自 Postgres 统计信息,仅为近似值;精确统计时不输出
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+func newPage[PT any](items []PT, page, pageSize uint64, total int64, estimated bool) *Page[PT] {
+	totalPages := (uint64(total) + pageSize - 1) / pageSize
+	return &Page[PT]{
+		Items:      items,
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+		Estimated:  estimated,
+	}
+}
+
+// newPageSpec 复制查询规格并设置 limit/offset,避免修改调用方的规格;未指定排序时按主键升序
+func newPageSpec(spec *Spec, page, pageSize uint64, primaryKey string) *Spec {
+	pageSpec := &Spec{limit: int(pageSize), offset: int((page - 1) * pageSize)}
+	if spec != nil {
+		pageSpec.conds = spec.conds
+		pageSpec.sorts = spec.sorts
+	}
+	if len(pageSpec.sorts) == 0 {
+		pageSpec.sorts = []Sort{Asc(primaryKey)}
+	}
+	return pageSpec
+}
+
+// estimateCount 读取 pg_class.reltuples 作为表行数估计,表从未 ANALYZE 时返回 ok=false。
+// SQLite 没有可用的统计信息,同样返回 ok=false 改为精确计数
+func (r *genericRepo[T, PT]) estimateCount(ctx context.Context, table string) (int64, bool, error) {
+	if DialectOf(r.db) != DialectPostgres {
+		return 0, false, nil
+	}
+	var estimate int64
+	err := r.readConn(ctx).
+		Raw("SELECT reltuples::bigint FROM pg_class WHERE oid = ?::regclass", table).
+		Scan(&estimate).Error
+	if err != nil {
+		return 0, false, err
+	}
+	return estimate, estimate >= 0, nil
+}
+
+func (r *genericRepo[T, PT]) GetPage(ctx context.Context, spec *Spec, page, pageSize uint64, estimateTotal bool, opts ...QueryOption) (*Page[PT], error) {
+	var model T
+	ptrModel := PT(&model)
+
+	if page <= 0 || pageSize <= 0 {
+		return nil, fmt.Errorf("get %s page %d, pageSize %d failed, page and pageSize must be greater than 0: %w", ptrModel.TableName(), page, pageSize, errs.ErrInvalidArgument)
+	}
+
+	pageSpec := newPageSpec(spec, page, pageSize, ptrModel.GetPrimaryKey())
+	items, err := r.Find(ctx, pageSpec, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		total     int64
+		estimated bool
+	)
+	// 估算只适用于无过滤条件的全表分页,按租户隔离的表只统计当前租户,无法估算
+	if estimateTotal && len(pageSpec.conds) == 0 && !r.tenantScoped() {
+		total, estimated, err = r.estimateCount(ctx, ptrModel.TableName())
+		if err != nil {
+			return nil, fmt.Errorf("estimate %s count failed: %w", ptrModel.TableName(), translateError(err))
+		}
+	}
+	if !estimated {
+		total, err = r.Count(ctx, pageSpec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newPage(items, page, pageSize, total, estimated), nil
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// QueryOption 查询方法的可选项
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	preloads []string
+}
+
+// WithPreload 预加载关联,如 "User"、"Product" 或嵌套的 "User.Orders"。
+// 每个关联额外执行一次 IN 查询,与结果行数无关,避免 N+1
+func WithPreload(relations ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.preloads = append(o.preloads, relations...)
+	}
+}
+
+func newQueryOptions(opts []QueryOption) queryOptions {
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// resolveRelation 按点分隔的路径逐级查找关联
+func resolveRelation(sch *schema.Schema, relation string) ([]*schema.Relationship, error) {
+	var path []*schema.Relationship
+	target := sch
+	for _, name := range strings.Split(relation, ".") {
+		rel, ok := target.Relationships.Relations[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown relation %q", relation)
+		}
+		path = append(path, rel)
+		target = rel.FieldSchema
+	}
+	return path, nil
+}
+
+// applyQueryOptions 校验关联名称并应用预加载,按租户隔离的关联模型同样加上租户条件
+func applyQueryOptions(ctx context.Context, db *gorm.DB, sch *schema.Schema, opts []QueryOption) (*gorm.DB, error) {
+	for _, relation := range newQueryOptions(opts).preloads {
+		path, err := resolveRelation(sch, relation)
+		if err != nil {
+			return nil, err
+		}
+		if target := path[len(path)-1].FieldSchema; target.LookUpField(tenantColumn) != nil {
+			db = db.Preload(relation, TenantScope(ctx))
+		} else {
+			db = db.Preload(relation)
+		}
+	}
+	return db, nil
+}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"go-pattern/internal/logging"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReplicaPool 只读副本池,按轮询选择健康的副本
+type ReplicaPool struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+func NewReplicaPool(dbs ...*gorm.DB) *ReplicaPool {
+	pool := &ReplicaPool{replicas: make([]*replica, 0, len(dbs))}
+	for _, db := range dbs {
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		pool.replicas = append(pool.replicas, r)
+	}
+	return pool
+}
+
+// pick 轮询返回一个健康副本,全部不可用时返回 nil,由调用方回退到主库
+func (p *ReplicaPool) pick() *gorm.DB {
+	if p == nil || len(p.replicas) == 0 {
+		return nil
+	}
+	start := p.next.Add(1)
+	for i := range uint64(len(p.replicas)) {
+		r := p.replicas[(start+i)%uint64(len(p.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// StartHealthCheck 定期 Ping 每个副本并更新其健康状态,ctx 取消后停止
+func (p *ReplicaPool) StartHealthCheck(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	logger = logging.OrDefault(logger)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for i, r := range p.replicas {
+					healthy := ping(ctx, r.db, interval)
+					if r.healthy.Swap(healthy) != healthy {
+						level := slog.LevelInfo
+						if !healthy {
+							level = slog.LevelWarn
+						}
+						logger.Log(ctx, level, "replica health changed", slog.Int("replica", i), slog.Bool("healthy", healthy))
+					}
+				}
+			}
+		}
+	}()
+}
+
+func ping(ctx context.Context, db *gorm.DB, timeout time.Duration) bool {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx) == nil
+}
+
+type primaryKey struct{}
+
+// WithPrimary 标记本次调用从主库读取,用于"读自己的写"
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// reader 返回读操作使用的连接:上下文中有事务时使用事务,否则优先健康副本,
+// 强制主库或无可用副本时使用主库。按租户隔离的模型自动加上租户条件
+func (r *genericRepo[T, PT]) reader(ctx context.Context) *gorm.DB {
+	return r.readConn(ctx).Scopes(r.tenantScope(ctx))
+}
+
+func (r *genericRepo[T, PT]) readConn(ctx context.Context) *gorm.DB {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	if !usePrimary(ctx) {
+		if db := r.opts.replicas.pick(); db != nil {
+			return db.WithContext(ctx)
+		}
+	}
+	return r.db.WithContext(ctx)
+}
@@ -3,18 +3,59 @@ package repo
 import (
 	"context"
 	"go-pattern/internal/model"
+	"iter"
+	"time"
 )
 
 type GenericRepo[T any, PT model.PointerModel[T]] interface {
 	Create(ctx context.Context, ptrModel PT) error
 	CreateInBatches(ctx context.Context, ptrModels []PT, batchSize int) error
-	GetByID(ctx context.Context, id uint64) (PT, error)
-	GetByIDs(ctx context.Context, ids []uint64) ([]PT, error)
+	GetByID(ctx context.Context, id uint64, opts ...QueryOption) (PT, error)
+	GetByIDs(ctx context.Context, ids []uint64, opts ...QueryOption) ([]PT, error)
 	GetByStructFields(ctx context.Context, structModel PT) ([]PT, error)
 	GetByMapFields(ctx context.Context, mapFields map[string]any) ([]PT, error)
 	GetByPage(ctx context.Context, page, pageSize uint64) ([]PT, error)
+	// GetPage 偏移分页并返回总数、总页数等元信息,页超出范围时返回空列表;
+	// estimateTotal 为 true 且无过滤条件时使用 Postgres 统计信息估算总数,适合大表;按租户隔离的模型总是精确统计
+	GetPage(ctx context.Context, spec *Spec, page, pageSize uint64, estimateTotal bool, opts ...QueryOption) (*Page[PT], error)
 	GetByCursor(ctx context.Context, cursor, pageSize uint64) ([]PT, uint64, bool, error)
+	// GetByKeyset 按任意排序元组进行键集分页,支持向前与向后翻页
+	GetByKeyset(ctx context.Context, query KeysetQuery) (*KeysetPage[PT], error)
+	// Update 写入非零值的列,不会修改租户列;记录不存在时返回 ErrNotFound,
+	// 乐观锁模型的版本不一致时返回 ErrVersionConflict
 	Update(ctx context.Context, ptrModel PT) error
+	// UpdateFields 只更新 fieldMask 中列出的列,可以写入零值;values 以列名为键。
+	// 乐观锁模型可在 values["version"] 中携带期望版本
+	UpdateFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) error
 	DeleteByID(ctx context.Context, id uint64) error
 	DeleteByIDs(ctx context.Context, ids []uint64) error
+	// UpdateWhere 按过滤条件批量更新 changes 中的列(以列名为键),返回更新行数;
+	// 过滤条件为空时除非传入 ForceAll 否则拒绝执行,排序与分页被忽略
+	UpdateWhere(ctx context.Context, filter *Spec, changes map[string]any, opts ...BulkOption) (int64, error)
+	// DeleteWhere 按过滤条件批量删除(软删除模型只标记删除),返回删除行数;空过滤条件的处理同 UpdateWhere
+	DeleteWhere(ctx context.Context, filter *Spec, opts ...BulkOption) (int64, error)
+	// Upsert 基于 ON CONFLICT 插入或更新,conflictColumns 须有唯一约束,
+	// updateColumns 为空时冲突行保持不变(DO NOTHING)
+	Upsert(ctx context.Context, ptrModel PT, conflictColumns []string, updateColumns []string) (UpsertResult, error)
+	// UpsertInBatches 在同一事务中分批 upsert,返回插入、更新与跳过的总行数
+	UpsertInBatches(ctx context.Context, ptrModels []PT, batchSize int, conflictColumns []string, updateColumns []string) (UpsertResult, error)
+	// Find 按查询规格查询,没有匹配记录时返回空切片
+	Find(ctx context.Context, spec *Spec, opts ...QueryOption) ([]PT, error)
+	// Count 统计满足查询规格过滤条件的记录数,忽略排序与分页
+	Count(ctx context.Context, spec *Spec) (int64, error)
+	// Aggregate 执行聚合与分组查询,过滤条件与 Find 相同,列、函数与别名都经过白名单校验
+	Aggregate(ctx context.Context, query AggregateQuery) ([]AggregateRow, error)
+	// Iterate 以键集扫描分批流式遍历满足规格的记录(按规格排序并以主键决胜,忽略 Offset),
+	// 内存占用只与 batchSize 有关;出错或 ctx 取消时产出一个错误后结束
+	Iterate(ctx context.Context, spec *Spec, batchSize int) iter.Seq2[PT, error]
+
+	// 以下方法仅适用于声明了 gorm.DeletedAt 字段的软删除模型,
+	// 软删除模型的 Delete* 只标记 deleted_at,所有 Get*/Find 自动排除已删除记录
+
+	// Restore 恢复一条已软删除的记录
+	Restore(ctx context.Context, id uint64) error
+	// GetWithDeleted 按ID查询,包含已软删除的记录
+	GetWithDeleted(ctx context.Context, id uint64, opts ...QueryOption) (PT, error)
+	// Purge 物理删除软删除时间早于 olderThan 之前的记录,返回删除条数
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
 }
@@ -0,0 +1,250 @@
+package repo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Operator 查询条件的比较运算符
+type Operator string
+
+const (
+	OpEq        Operator = "="
+	OpNe        Operator = "<>"
+	OpGt        Operator = ">"
+	OpGte       Operator = ">="
+	OpLt        Operator = "<"
+	OpLte       Operator = "<="
+	OpIn        Operator = "IN"
+	OpNotIn     Operator = "NOT IN"
+	OpLike      Operator = "LIKE"
+	OpBetween   Operator = "BETWEEN"
+	OpIsNull    Operator = "IS NULL"
+	OpIsNotNull Operator = "IS NOT NULL"
+)
+
+const (
+	logicAnd = "AND"
+	logicOr  = "OR"
+)
+
+// Cond 单个过滤条件,或由 And/Or 组合而成的条件组
+type Cond struct {
+	field  string
+	op     Operator
+	values []any
+	// logic 不为空时表示这是一个条件组
+	logic string
+	conds []Cond
+}
+
+func Eq(field string, value any) Cond  { return Cond{field: field, op: OpEq, values: []any{value}} }
+func Ne(field string, value any) Cond  { return Cond{field: field, op: OpNe, values: []any{value}} }
+func Gt(field string, value any) Cond  { return Cond{field: field, op: OpGt, values: []any{value}} }
+func Gte(field string, value any) Cond { return Cond{field: field, op: OpGte, values: []any{value}} }
+func Lt(field string, value any) Cond  { return Cond{field: field, op: OpLt, values: []any{value}} }
+func Lte(field string, value any) Cond { return Cond{field: field, op: OpLte, values: []any{value}} }
+
+// In values 必须是非空切片
+func In(field string, values any) Cond {
+	return Cond{field: field, op: OpIn, values: []any{values}}
+}
+
+func NotIn(field string, values any) Cond {
+	return Cond{field: field, op: OpNotIn, values: []any{values}}
+}
+
+func Like(field string, pattern string) Cond {
+	return Cond{field: field, op: OpLike, values: []any{pattern}}
+}
+
+func Between(field string, low, high any) Cond {
+	return Cond{field: field, op: OpBetween, values: []any{low, high}}
+}
+
+func IsNull(field string) Cond    { return Cond{field: field, op: OpIsNull} }
+func IsNotNull(field string) Cond { return Cond{field: field, op: OpIsNotNull} }
+
+// And 组合多个条件,全部满足时成立
+func And(conds ...Cond) Cond { return Cond{logic: logicAnd, conds: conds} }
+
+// Or 组合多个条件,任意一个满足时成立
+func Or(conds ...Cond) Cond { return Cond{logic: logicOr, conds: conds} }
+
+// build 将条件编译为带占位符的SQL片段,字段必须存在于模型的列白名单中
+func (c Cond) build(columns map[string]*schema.Field) (string, []any, error) {
+	return c.buildWith(func(field string) (string, bool) {
+		_, ok := columns[field]
+		return field, ok
+	})
+}
+
+// buildWith 由 resolve 将字段名解析为SQL表达式,无法解析的字段视为非法
+func (c Cond) buildWith(resolve func(field string) (string, bool)) (string, []any, error) {
+	if c.logic != "" {
+		if len(c.conds) == 0 {
+			return "", nil, fmt.Errorf("empty %s group", c.logic)
+		}
+		parts := make([]string, 0, len(c.conds))
+		vars := make([]any, 0, len(c.conds))
+		for _, child := range c.conds {
+			sql, childVars, err := child.buildWith(resolve)
+			if err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, sql)
+			vars = append(vars, childVars...)
+		}
+		return "(" + strings.Join(parts, " "+c.logic+" ") + ")", vars, nil
+	}
+
+	expr, ok := resolve(c.field)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown column %q", c.field)
+	}
+	switch c.op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpLike:
+		return fmt.Sprintf("%s %s ?", expr, c.op), c.values, nil
+	case OpIn, OpNotIn:
+		v := reflect.ValueOf(c.values[0])
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return "", nil, fmt.Errorf("%s on column %q requires a slice", c.op, c.field)
+		}
+		if v.Len() == 0 {
+			return "", nil, fmt.Errorf("%s on column %q requires at least one value", c.op, c.field)
+		}
+		return fmt.Sprintf("%s %s ?", expr, c.op), c.values, nil
+	case OpBetween:
+		return fmt.Sprintf("%s BETWEEN ? AND ?", expr), c.values, nil
+	case OpIsNull, OpIsNotNull:
+		return fmt.Sprintf("%s %s", expr, c.op), nil, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operator %q", c.op)
+	}
+}
+
+// Sort 排序字段
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+func Asc(field string) Sort  { return Sort{Field: field} }
+func Desc(field string) Sort { return Sort{Field: field, Desc: true} }
+
+// Spec 可组合的查询规格:过滤条件、排序与分页限制
+// 所有字段名都会在执行前与模型的列进行白名单校验
+type Spec struct {
+	conds  []Cond
+	sorts  []Sort
+	limit  int
+	offset int
+}
+
+func NewSpec(conds ...Cond) *Spec {
+	return &Spec{conds: conds}
+}
+
+// Where 追加过滤条件,多次调用之间为AND关系
+func (s *Spec) Where(conds ...Cond) *Spec {
+	s.conds = append(s.conds, conds...)
+	return s
+}
+
+func (s *Spec) OrderBy(sorts ...Sort) *Spec {
+	s.sorts = append(s.sorts, sorts...)
+	return s
+}
+
+func (s *Spec) Limit(limit int) *Spec {
+	s.limit = limit
+	return s
+}
+
+func (s *Spec) Offset(offset int) *Spec {
+	s.offset = offset
+	return s
+}
+
+// checkWhere 只校验过滤条件,与 applyWhere 一致
+func (s *Spec) checkWhere(columns map[string]*schema.Field) error {
+	if s == nil {
+		return nil
+	}
+	for _, cond := range s.conds {
+		if _, _, err := cond.build(columns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check 校验过滤条件、排序与分页,与 apply 一致
+func (s *Spec) check(columns map[string]*schema.Field) error {
+	if err := s.checkWhere(columns); err != nil || s == nil {
+		return err
+	}
+	for _, sort := range s.sorts {
+		if _, ok := columns[sort.Field]; !ok {
+			return fmt.Errorf("unknown sort column %q", sort.Field)
+		}
+	}
+	if s.limit < 0 || s.offset < 0 {
+		return fmt.Errorf("limit and offset must not be negative")
+	}
+	return nil
+}
+
+// whereOnly 只保留过滤条件的规格
+func (s *Spec) whereOnly() *Spec {
+	if s == nil {
+		return nil
+	}
+	return &Spec{conds: s.conds}
+}
+
+// applyWhere 只应用过滤条件,用于Count等不关心排序与分页的查询
+func (s *Spec) applyWhere(db *gorm.DB, columns map[string]*schema.Field) (*gorm.DB, error) {
+	if s == nil {
+		return db, nil
+	}
+	for _, cond := range s.conds {
+		sql, vars, err := cond.build(columns)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(sql, vars...)
+	}
+	return db, nil
+}
+
+func (s *Spec) apply(db *gorm.DB, columns map[string]*schema.Field) (*gorm.DB, error) {
+	db, err := s.applyWhere(db, columns)
+	if err != nil || s == nil {
+		return db, err
+	}
+	for _, sort := range s.sorts {
+		if _, ok := columns[sort.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort column %q", sort.Field)
+		}
+		if sort.Desc {
+			db = db.Order(sort.Field + " DESC")
+		} else {
+			db = db.Order(sort.Field + " ASC")
+		}
+	}
+	if s.limit < 0 || s.offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+	if s.limit > 0 {
+		db = db.Limit(s.limit)
+	}
+	if s.offset > 0 {
+		db = db.Offset(s.offset)
+	}
+	return db, nil
+}
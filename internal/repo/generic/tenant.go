@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"go-pattern/internal/tenant"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// tenantColumn 声明了该列的模型按租户隔离
+const tenantColumn = "tenant_id"
+
+// TenantScope 为查询加上上下文中租户的条件;上下文没有租户且未 Bypass 时查询失败。
+// 供自定义仓储方法通过 Scopes 使用
+func TenantScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.Bypassed(ctx) {
+			return db
+		}
+		tenantID, ok := tenant.FromContext(ctx)
+		if !ok {
+			db.AddError(fmt.Errorf("tenant is required: %w", errs.ErrInvalidArgument))
+			return db
+		}
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: tenantColumn}, Value: tenantID})
+	}
+}
+
+// tenantScoped 模型是否有 tenant_id 列
+func (r *genericRepo[T, PT]) tenantScoped() bool {
+	sch, err := r.schema()
+	return err == nil && sch.LookUpField(tenantColumn) != nil
+}
+
+// tenantScope 按租户隔离的模型加上租户条件,其他模型不做处理
+func (r *genericRepo[T, PT]) tenantScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	if !r.tenantScoped() {
+		return func(db *gorm.DB) *gorm.DB { return db }
+	}
+	return TenantScope(ctx)
+}
+
+// tenantOf 记录所属的租户,模型不按租户隔离时为 0
+func tenantOf(ctx context.Context, sch *schema.Schema, row reflect.Value) uint64 {
+	field := sch.LookUpField(tenantColumn)
+	if field == nil {
+		return 0
+	}
+	value, _ := field.ValueOf(ctx, row)
+	tenantID, _ := value.(uint64)
+	return tenantID
+}
+
+// assignTenant 写入前为模型填充上下文中的租户,已属于其他租户的模型拒绝写入
+func (r *genericRepo[T, PT]) assignTenant(ctx context.Context, sch *schema.Schema, ptrModels ...PT) error {
+	return assignTenant(ctx, sch, ptrModels...)
+}
+
+func assignTenant[PT model.Model](ctx context.Context, sch *schema.Schema, ptrModels ...PT) error {
+	field := sch.LookUpField(tenantColumn)
+	if field == nil || tenant.Bypassed(ctx) {
+		return nil
+	}
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("tenant is required: %w", errs.ErrInvalidArgument)
+	}
+	for _, ptrModel := range ptrModels {
+		value := reflect.ValueOf(ptrModel)
+		current, isZero := field.ValueOf(ctx, value)
+		if !isZero && current != tenantID {
+			return fmt.Errorf("%s %d belongs to tenant %v: %w", ptrModel.TableName(), ptrModel.GetID(), current, errs.ErrInvalidArgument)
+		}
+		if err := field.Set(ctx, value, tenantID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
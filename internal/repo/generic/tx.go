@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// WithTx 将事务放入上下文,之后使用该上下文的仓储方法都会加入这个事务
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom 读取上下文中的事务
+func TxFrom(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// InTx 上下文中是否有事务,包括内存实现的事务
+func InTx(ctx context.Context) bool {
+	_, inTx := TxFrom(ctx)
+	_, inMemoryTx := MemoryTxFrom(ctx)
+	return inTx || inMemoryTx
+}
+
+// Conn 返回本次调用使用的连接:上下文中有事务时加入事务,否则使用 db。
+// 自定义仓储方法通过它参与外层事务
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+func (r *genericRepo[T, PT]) conn(ctx context.Context) *gorm.DB {
+	return Conn(ctx, r.db)
+}
+
+// TxHooks 事务提交后执行的回调,如缓存失效:在提交前失效,并发的读取可能把未提交前的旧数据重新写入缓存。
+// 回滚到保存点时丢弃保存点内注册的回调,事务回滚时全部丢弃;提交后注册的回调立即执行
+type TxHooks struct {
+	mu        sync.Mutex
+	fns       []func()
+	committed bool
+}
+
+func NewTxHooks() *TxHooks {
+	return &TxHooks{}
+}
+
+// Add 注册提交后执行的回调,hooks 为 nil 或事务已提交时立即执行
+func (h *TxHooks) Add(fn func()) {
+	if h == nil {
+		fn()
+		return
+	}
+	h.mu.Lock()
+	if h.committed {
+		h.mu.Unlock()
+		fn()
+		return
+	}
+	h.fns = append(h.fns, fn)
+	h.mu.Unlock()
+}
+
+// Mark 返回当前位置,创建保存点时调用
+func (h *TxHooks) Mark() int {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.fns)
+}
+
+// Discard 丢弃 mark 之后注册的回调,回滚到保存点时调用
+func (h *TxHooks) Discard(mark int) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if mark < len(h.fns) {
+		h.fns = h.fns[:mark]
+	}
+}
+
+// Commit 标记事务已提交并按注册顺序执行回调,只能由开启最外层事务的一方调用
+func (h *TxHooks) Commit() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	fns := h.fns
+	h.fns, h.committed = nil, true
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+type txHooksKey struct{}
+
+// WithTxHooks 将事务的提交回调放入上下文,与 WithTx 一起使用
+func WithTxHooks(ctx context.Context, hooks *TxHooks) context.Context {
+	return context.WithValue(ctx, txHooksKey{}, hooks)
+}
+
+// TxHooksFrom 读取上下文中事务的提交回调
+func TxHooksFrom(ctx context.Context) (*TxHooks, bool) {
+	hooks, ok := ctx.Value(txHooksKey{}).(*TxHooks)
+	return hooks, ok && hooks != nil
+}
+
+// AfterCommit 在事务提交后执行 fn:优先使用 hooks(绑定在事务工厂上的仓储),
+// 其次是上下文中的事务;不在事务中时立即执行。fn 执行时 ctx 可能已取消,需要时使用 context.WithoutCancel
+func AfterCommit(ctx context.Context, hooks *TxHooks, fn func()) {
+	if hooks == nil {
+		hooks, _ = TxHooksFrom(ctx)
+	}
+	hooks.Add(fn)
+}
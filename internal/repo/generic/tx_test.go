@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// recordHooks 返回一个记录执行顺序的 add 函数
+func recordHooks(h *TxHooks, got *[]string) func(string) {
+	return func(s string) { h.Add(func() { *got = append(*got, s) }) }
+}
+
+func TestTxHooks(t *testing.T) {
+	var got []string
+	h := NewTxHooks()
+	add := recordHooks(h, &got)
+
+	add("outer")
+	// 回滚的保存点丢弃其间注册的钩子,释放的保存点保留
+	rolledBack := h.Mark()
+	add("rolled back")
+	h.Discard(rolledBack)
+	h.Mark()
+	add("released")
+	if len(got) != 0 {
+		t.Fatalf("hooks ran before commit: %v", got)
+	}
+	h.Commit()
+	if want := []string{"outer", "released"}; !slices.Equal(got, want) {
+		t.Fatalf("hooks after commit = %v, want %v", got, want)
+	}
+}
+
+func TestTxHooksNestedDiscard(t *testing.T) {
+	var got []string
+	h := NewTxHooks()
+	add := recordHooks(h, &got)
+
+	outer := h.Mark()
+	add("a")
+	inner := h.Mark()
+	add("b")
+	h.Discard(inner)
+	add("c")
+	h.Discard(outer)
+	h.Commit()
+	if len(got) != 0 {
+		t.Fatalf("hooks of the discarded savepoint ran: %v", got)
+	}
+}
+
+func TestTxHooksOutsideTransaction(t *testing.T) {
+	var got []string
+	var nilHooks *TxHooks
+	nilHooks.Add(func() { got = append(got, "nil hooks") })
+
+	committed := NewTxHooks()
+	committed.Commit()
+	committed.Add(func() { got = append(got, "after commit") })
+
+	AfterCommit(context.Background(), nil, func() { got = append(got, "no transaction") })
+
+	ctxHooks := NewTxHooks()
+	AfterCommit(WithTxHooks(context.Background(), ctxHooks), nil, func() { got = append(got, "ctx hooks") })
+
+	want := []string{"nil hooks", "after commit", "no transaction"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("immediate hooks = %v, want %v", got, want)
+	}
+	ctxHooks.Commit()
+	if want = append(want, "ctx hooks"); !slices.Equal(got, want) {
+		t.Fatalf("hooks after ctx commit = %v, want %v", got, want)
+	}
+}
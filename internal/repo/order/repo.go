@@ -16,8 +16,13 @@ type orderRepo struct {
 	genericRepo.GenericRepo[model.Order, *model.Order]
 }
 
-func NewOrderRepo(db *gorm.DB) OrderRepo {
+func NewOrderRepo(db *gorm.DB, opts ...genericRepo.Option) OrderRepo {
+	return NewOrderRepoFrom(genericRepo.NewGenericRepo[model.Order](db, opts...))
+}
+
+// NewOrderRepoFrom 基于已构建的通用仓储(如带缓存的装饰器)创建仓储
+func NewOrderRepoFrom(repo genericRepo.GenericRepo[model.Order, *model.Order]) OrderRepo {
 	return &orderRepo{
-		GenericRepo: genericRepo.NewGenericRepo[model.Order](db),
+		GenericRepo: repo,
 	}
 }
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"slices"
+	"time"
+
+	genericRepo "go-pattern/internal/repo/generic"
+)
+
+type memoryOutboxRepo struct {
+	genericRepo.GenericRepo[model.OutboxEvent, *model.OutboxEvent]
+	db *genericRepo.MemoryDB
+}
+
+// NewMemoryOutboxRepo 内存实现的发件箱仓储,用于单元测试
+func NewMemoryOutboxRepo(db *genericRepo.MemoryDB, opts ...genericRepo.Option) OutboxRepo {
+	opts = append(opts, genericRepo.WithAudit(false))
+	return NewMemoryOutboxRepoFrom(db, genericRepo.NewMemoryRepo[model.OutboxEvent](db, opts...))
+}
+
+// NewMemoryOutboxRepoFrom 同 NewOutboxRepoFrom
+func NewMemoryOutboxRepoFrom(db *genericRepo.MemoryDB, repo genericRepo.GenericRepo[model.OutboxEvent, *model.OutboxEvent]) OutboxRepo {
+	return &memoryOutboxRepo{
+		GenericRepo: repo,
+		db:          db,
+	}
+}
+
+func (o *memoryOutboxRepo) Enqueue(ctx context.Context, topic string, aggregateID uint64, payload any) (*model.OutboxEvent, error) {
+	return enqueue(ctx, o.GenericRepo, topic, aggregateID, payload)
+}
+
+func (o *memoryOutboxRepo) ClaimBatch(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("claim outbox events failed, limit must be greater than 0: %w", errs.ErrInvalidArgument)
+	}
+	now := time.Now()
+	events, err := genericRepo.MemorySelect(ctx, o.db, genericRepo.MemoryQuery[*model.OutboxEvent]{
+		Where: func(event *model.OutboxEvent) bool {
+			return event.Status == model.OutboxStatusPending && !event.NextAttemptAt.After(now)
+		},
+		Compare: func(a, b *model.OutboxEvent) int {
+			return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+		},
+		Limit:      limit,
+		SkipLocked: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events failed: %w", err)
+	}
+	return events, nil
+}
+
+func (o *memoryOutboxRepo) Lease(ctx context.Context, lease time.Duration, ids ...uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	until := time.Now().Add(lease)
+	_, err := genericRepo.MemoryUpdate(ctx, o.db,
+		func(event *model.OutboxEvent) bool {
+			return event.Status == model.OutboxStatusPending && slices.Contains(ids, event.ID)
+		},
+		func(event *model.OutboxEvent) error {
+			event.NextAttemptAt = until
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("lease outbox events %v failed: %w", ids, err)
+	}
+	return nil
+}
+
+func (o *memoryOutboxRepo) MarkDelivered(ctx context.Context, ids ...uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now()
+	_, err := genericRepo.MemoryUpdate(ctx, o.db,
+		func(event *model.OutboxEvent) bool {
+			return slices.Contains(ids, event.ID)
+		},
+		func(event *model.OutboxEvent) error {
+			event.Status = model.OutboxStatusDelivered
+			event.DeliveredAt = &now
+			event.LastError = ""
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("mark outbox events %v delivered failed: %w", ids, err)
+	}
+	return nil
+}
+
+func (o *memoryOutboxRepo) MarkFailed(ctx context.Context, id uint64, cause error, retryAfter time.Duration) error {
+	return o.markFailed(ctx, id, func(event *model.OutboxEvent) {
+		event.Attempts++
+		event.LastError = cause.Error()
+		event.NextAttemptAt = time.Now().Add(retryAfter)
+	})
+}
+
+func (o *memoryOutboxRepo) MarkDead(ctx context.Context, id uint64, cause error) error {
+	return o.markFailed(ctx, id, func(event *model.OutboxEvent) {
+		event.Attempts++
+		event.LastError = cause.Error()
+		event.Status = model.OutboxStatusDead
+	})
+}
+
+func (o *memoryOutboxRepo) markFailed(ctx context.Context, id uint64, update func(event *model.OutboxEvent)) error {
+	updated, err := genericRepo.MemoryUpdate(ctx, o.db,
+		func(event *model.OutboxEvent) bool {
+			return event.ID == id
+		},
+		func(event *model.OutboxEvent) error {
+			update(event)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("mark outbox event %d failed: %w", id, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("mark outbox event %d failed: %w", id, errs.ErrNotFound)
+	}
+	return nil
+}
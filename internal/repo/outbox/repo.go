@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/model"
+	"time"
+
+	genericRepo "go-pattern/internal/repo/generic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepo interface {
+	genericRepo.GenericRepo[model.OutboxEvent, *model.OutboxEvent]
+	// Enqueue 写入一条待投递事件,需在业务事务中调用才能与业务数据一起提交
+	Enqueue(ctx context.Context, topic string, aggregateID uint64, payload any) (*model.OutboxEvent, error)
+	// ClaimBatch 锁定最多 limit 条到期的待投递事件,已被其他中继锁定的行会被跳过。
+	// 行锁持续到事务结束,必须在事务内调用
+	ClaimBatch(ctx context.Context, limit int) ([]*model.OutboxEvent, error)
+	// Lease 将事件的下次投递时间推迟 lease,租约内其他中继不会领取这些事件;
+	// 与 ClaimBatch 在同一事务中调用,事务提交后即可在事务外投递
+	Lease(ctx context.Context, lease time.Duration, ids ...uint64) error
+	MarkDelivered(ctx context.Context, ids ...uint64) error
+	// MarkFailed 记录失败原因,retryAfter 之后再次投递
+	MarkFailed(ctx context.Context, id uint64, cause error, retryAfter time.Duration) error
+	// MarkDead 记录失败原因并停止投递
+	MarkDead(ctx context.Context, id uint64, cause error) error
+}
+
+type outboxRepo struct {
+	genericRepo.GenericRepo[model.OutboxEvent, *model.OutboxEvent]
+	db *gorm.DB
+}
+
+// NewOutboxRepo 发件箱的状态流转不记录审计
+func NewOutboxRepo(db *gorm.DB, opts ...genericRepo.Option) OutboxRepo {
+	opts = append(opts, genericRepo.WithAudit(false))
+	return NewOutboxRepoFrom(db, genericRepo.NewGenericRepo[model.OutboxEvent](db, opts...))
+}
+
+// NewOutboxRepoFrom 基于已构建的通用仓储创建仓储,repo 须关闭审计;db 用于领取与状态流转
+func NewOutboxRepoFrom(db *gorm.DB, repo genericRepo.GenericRepo[model.OutboxEvent, *model.OutboxEvent]) OutboxRepo {
+	return &outboxRepo{
+		GenericRepo: repo,
+		db:          db,
+	}
+}
+
+func (o *outboxRepo) Enqueue(ctx context.Context, topic string, aggregateID uint64, payload any) (*model.OutboxEvent, error) {
+	return enqueue(ctx, o.GenericRepo, topic, aggregateID, payload)
+}
+
+// enqueue 通过通用仓储写入事件,GORM 与内存实现共用
+func enqueue(ctx context.Context, repo genericRepo.GenericRepo[model.OutboxEvent, *model.OutboxEvent], topic string, aggregateID uint64, payload any) (*model.OutboxEvent, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("enqueue outbox event failed, topic is empty: %w", errs.ErrInvalidArgument)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue outbox event %s failed, marshal payload error: %w: %w", topic, errs.ErrInvalidArgument, err)
+	}
+	event := &model.OutboxEvent{
+		Topic:       topic,
+		AggregateID: aggregateID,
+		Payload:     data,
+		Status:      model.OutboxStatusPending,
+	}
+	if err := repo.Create(ctx, event); err != nil {
+		return nil, fmt.Errorf("enqueue outbox event %s failed: %w", topic, err)
+	}
+	return event, nil
+}
+
+func (o *outboxRepo) ClaimBatch(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("claim outbox events failed, limit must be greater than 0: %w", errs.ErrInvalidArgument)
+	}
+	events := make([]*model.OutboxEvent, 0, limit)
+	db := genericRepo.Conn(ctx, o.db)
+	dialect := genericRepo.DialectOf(db)
+	// SQLite 没有行锁,写事务之间本就串行,不会重复领取
+	if dialect.RowLocking() {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	}
+	result := db.
+		Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, dialect.Now()).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("claim outbox events failed: %w", result.Error)
+	}
+	return events, nil
+}
+
+func (o *outboxRepo) Lease(ctx context.Context, lease time.Duration, ids ...uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	db := genericRepo.Conn(ctx, o.db)
+	result := db.
+		Model(&model.OutboxEvent{}).
+		Where("id IN ? AND status = ?", ids, model.OutboxStatusPending).
+		Update("next_attempt_at", genericRepo.DialectOf(db).NowPlus(lease))
+	if result.Error != nil {
+		return fmt.Errorf("lease outbox events %v failed: %w", ids, result.Error)
+	}
+	return nil
+}
+
+func (o *outboxRepo) MarkDelivered(ctx context.Context, ids ...uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	db := genericRepo.Conn(ctx, o.db)
+	result := db.
+		Model(&model.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"status":       model.OutboxStatusDelivered,
+			"delivered_at": genericRepo.DialectOf(db).Now(),
+			"last_error":   "",
+		})
+	if result.Error != nil {
+		return fmt.Errorf("mark outbox events %v delivered failed: %w", ids, result.Error)
+	}
+	return nil
+}
+
+func (o *outboxRepo) MarkFailed(ctx context.Context, id uint64, cause error, retryAfter time.Duration) error {
+	return o.markFailed(ctx, id, map[string]any{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      cause.Error(),
+		"next_attempt_at": genericRepo.DialectOf(o.db).NowPlus(retryAfter),
+	})
+}
+
+func (o *outboxRepo) MarkDead(ctx context.Context, id uint64, cause error) error {
+	return o.markFailed(ctx, id, map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": cause.Error(),
+		"status":     model.OutboxStatusDead,
+	})
+}
+
+func (o *outboxRepo) markFailed(ctx context.Context, id uint64, updates map[string]any) error {
+	result := genericRepo.Conn(ctx, o.db).
+		Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("mark outbox event %d failed: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("mark outbox event %d failed: %w", id, errs.ErrNotFound)
+	}
+	return nil
+}
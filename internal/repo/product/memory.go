@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go-pattern/internal/model"
+
+	genericRepo "go-pattern/internal/repo/generic"
+)
+
+type memoryProductRepo struct {
+	genericRepo.GenericRepo[model.Product, *model.Product]
+}
+
+// NewMemoryProductRepo 内存实现的商品仓储,用于单元测试
+func NewMemoryProductRepo(db *genericRepo.MemoryDB, opts ...genericRepo.Option) ProductRepo {
+	return NewMemoryProductRepoFrom(genericRepo.NewMemoryRepo[model.Product](db, opts...))
+}
+
+// NewMemoryProductRepoFrom 同 NewProductRepoFrom
+func NewMemoryProductRepoFrom(repo genericRepo.GenericRepo[model.Product, *model.Product]) ProductRepo {
+	return &memoryProductRepo{GenericRepo: repo}
+}
+
+func (p *memoryProductRepo) ReduceQuantity(ctx context.Context, productID, count uint64) error {
+	// 内存实现不支持列表达式,以读到的库存作为条件写入新值(比较并交换),
+	// 期间库存被并发修改时重新读取;写入同样经由 UpdateWhere,与 GORM 版本一致
+	for {
+		products, err := p.Find(ctx, genericRepo.NewSpec(genericRepo.Eq("id", productID), genericRepo.Gte("quantity", count)))
+		if err != nil {
+			return fmt.Errorf("reduce quantity failed: %w", err)
+		}
+		if len(products) == 0 {
+			return reduceFailed(ctx, p.GenericRepo, productID, count)
+		}
+		quantity := products[0].Quantity
+		updated, err := p.UpdateWhere(ctx,
+			genericRepo.NewSpec(genericRepo.Eq("id", productID), genericRepo.Eq("quantity", quantity)),
+			map[string]any{"quantity": quantity - count})
+		if err != nil {
+			return fmt.Errorf("reduce quantity failed: %w", err)
+		}
+		if updated > 0 {
+			return nil
+		}
+	}
+}
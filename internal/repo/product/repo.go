@@ -3,12 +3,12 @@ package repo
 import (
 	"context"
 	"fmt"
+	"go-pattern/internal/errs"
 	"go-pattern/internal/model"
 
 	genericRepo "go-pattern/internal/repo/generic"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type ProductRepo interface {
@@ -18,25 +18,40 @@ type ProductRepo interface {
 
 type productRepo struct {
 	genericRepo.GenericRepo[model.Product, *model.Product]
-	db *gorm.DB
 }
 
-func NewProductRepo(db *gorm.DB) ProductRepo {
-	return &productRepo{
-		GenericRepo: genericRepo.NewGenericRepo[model.Product](db),
-		db:          db,
-	}
+func NewProductRepo(db *gorm.DB, opts ...genericRepo.Option) ProductRepo {
+	return NewProductRepoFrom(genericRepo.NewGenericRepo[model.Product](db, opts...))
+}
+
+// NewProductRepoFrom 基于已构建的通用仓储(如带缓存的装饰器)创建仓储
+func NewProductRepoFrom(repo genericRepo.GenericRepo[model.Product, *model.Product]) ProductRepo {
+	return &productRepo{GenericRepo: repo}
 }
 
 func (p *productRepo) ReduceQuantity(ctx context.Context, productID, count uint64) error {
-	result := p.db.Transaction(func(tx *gorm.DB) error {
-		return tx.Model(&model.Product{}).
-			Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("id = ? AND quantity >= ?", productID, count).
-			Update("quantity", gorm.Expr("quantity - ?", count)).Error
-	})
-	if result != nil {
-		return fmt.Errorf("reduce quantity failed: %w", result)
+	// 带库存条件的单条 UPDATE 本身是原子的,不会超卖;上下文中有事务时加入该事务。
+	// 经由 UpdateWhere 写入,版本号随之递增并记录审计、经过拦截器,缓存在提交后失效
+	updated, err := p.UpdateWhere(ctx,
+		genericRepo.NewSpec(genericRepo.Eq("id", productID), genericRepo.Gte("quantity", count)),
+		map[string]any{"quantity": gorm.Expr("quantity - ?", count)})
+	if err != nil {
+		return fmt.Errorf("reduce quantity failed: %w", err)
+	}
+	if updated == 0 {
+		return reduceFailed(ctx, p.GenericRepo, productID, count)
 	}
 	return nil
 }
+
+// reduceFailed 扣减没有命中任何行时区分商品不存在与库存不足
+func reduceFailed(ctx context.Context, repo genericRepo.GenericRepo[model.Product, *model.Product], productID, count uint64) error {
+	exists, err := repo.Count(ctx, genericRepo.NewSpec(genericRepo.Eq("id", productID)))
+	if err != nil {
+		return fmt.Errorf("reduce quantity failed: %w", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("reduce quantity failed, product %d: %w", productID, errs.ErrNotFound)
+	}
+	return fmt.Errorf("reduce quantity failed, product %d quantity less than %d: %w", productID, count, errs.ErrConflict)
+}
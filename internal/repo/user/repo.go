@@ -15,8 +15,13 @@ type userRepo struct {
 	genericRepo.GenericRepo[model.User, *model.User]
 }
 
-func NewUserRepo(db *gorm.DB) UserRepo {
+func NewUserRepo(db *gorm.DB, opts ...genericRepo.Option) UserRepo {
+	return NewUserRepoFrom(genericRepo.NewGenericRepo[model.User](db, opts...))
+}
+
+// NewUserRepoFrom 基于已构建的通用仓储(如带缓存的装饰器)创建仓储
+func NewUserRepoFrom(repo genericRepo.GenericRepo[model.User, *model.User]) UserRepo {
 	return &userRepo{
-		GenericRepo: genericRepo.NewGenericRepo[model.User](db),
+		GenericRepo: repo,
 	}
 }
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"go-pattern/internal/model"
+	repo "go-pattern/internal/repo/factory"
+)
+
+type AuditService interface {
+	// GetEntityHistory 查询单条记录的全部审计日志,最新的在前
+	GetEntityHistory(ctx context.Context, table string, entityID uint64) ([]*model.AuditLog, error)
+}
+
+type auditService struct {
+	repoFactory repo.RepoFactory
+}
+
+func NewAuditService(repoFactory repo.RepoFactory) AuditService {
+	return &auditService{repoFactory: repoFactory}
+}
+
+func (a *auditService) GetEntityHistory(ctx context.Context, table string, entityID uint64) ([]*model.AuditLog, error) {
+	return a.repoFactory.Audit().ListByEntity(ctx, table, entityID)
+}
@@ -3,22 +3,53 @@ package service
 import (
 	"context"
 	"fmt"
+	"go-pattern/internal/errs"
+	"go-pattern/internal/logging"
 	"go-pattern/internal/model"
 	repo "go-pattern/internal/repo/factory"
-	"log"
+	genericRepo "go-pattern/internal/repo/generic"
+	"log/slog"
+	"time"
 )
 
+// TopicOrderCreated 订单创建后通过发件箱投递的事件主题
+const TopicOrderCreated = "order.created"
+
+// UserOrderCount 用户的订单数
+type UserOrderCount struct {
+	UserID uint64 `json:"user_id"`
+	Orders int64  `json:"orders"`
+}
+
+// DailyOrderTotal 每日订单数
+type DailyOrderTotal struct {
+	Day    time.Time `json:"day"`
+	Orders int64     `json:"orders"`
+}
+
 type OrderService interface {
 	CreateOrder(ctx context.Context, order *model.Order) error
 	CreateOrders(ctx context.Context, orders []*model.Order, batchSize int) error
 	GetOrder(ctx context.Context, id uint64) (*model.Order, error)
+	// GetOrderDetail 查询订单并预加载下单用户与商品
+	GetOrderDetail(ctx context.Context, id uint64) (*model.Order, error)
 	GetOrders(ctx context.Context, ids []uint64) ([]*model.Order, error)
 	GetOrdersByUserID(ctx context.Context, userID uint64) ([]*model.Order, error)
-	GetOrdersByPage(ctx context.Context, page, pageSize uint64) ([]*model.Order, error)
+	GetOrdersByPage(ctx context.Context, page, pageSize uint64) (*genericRepo.Page[*model.Order], error)
 	GetOrdersByCursor(ctx context.Context, cursor, pageSize uint64) ([]*model.Order, uint64, bool, error)
+	// GetLatestOrders 按创建时间倒序的键集分页
+	GetLatestOrders(ctx context.Context, cursor string, pageSize int) (*genericRepo.KeysetPage[*model.Order], error)
 	UpdateOrder(ctx context.Context, order *model.Order) error
+	// UpdateOrderFields 按字段掩码部分更新,返回更新后的记录
+	UpdateOrderFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) (*model.Order, error)
 	DeleteOrder(ctx context.Context, id uint64) error
+	// CountOrdersByUser 统计每个用户的订单数,只返回订单数不少于 minOrders 的用户,按订单数倒序
+	CountOrdersByUser(ctx context.Context, minOrders int64) ([]UserOrderCount, error)
+	// GetDailyOrderTotals 统计 since 之后每天的订单数
+	GetDailyOrderTotals(ctx context.Context, since time.Time) ([]DailyOrderTotal, error)
 	DeleteOrders(ctx context.Context, ids []uint64) error
+	// DeleteOrdersByUserID 删除用户的全部订单,返回删除数量
+	DeleteOrdersByUserID(ctx context.Context, userID uint64) (int64, error)
 	//事务代码
 	//transaction
 	CreateOrderWithUser(ctx context.Context, userID uint64, orderID uint64) error
@@ -26,10 +57,11 @@ type OrderService interface {
 
 type orderService struct {
 	repoFactory repo.RepoFactory
+	logger      *slog.Logger
 }
 
-func NewOrderService(repoFactory repo.RepoFactory) OrderService {
-	return &orderService{repoFactory: repoFactory}
+func NewOrderService(repoFactory repo.RepoFactory, logger *slog.Logger) OrderService {
+	return &orderService{repoFactory: repoFactory, logger: logging.OrDefault(logger)}
 }
 
 func (o *orderService) CreateOrder(ctx context.Context, order *model.Order) error {
@@ -44,39 +76,112 @@ func (o *orderService) GetOrder(ctx context.Context, id uint64) (*model.Order, e
 	return o.repoFactory.Order().GetByID(ctx, id)
 }
 
+func (o *orderService) GetOrderDetail(ctx context.Context, id uint64) (*model.Order, error) {
+	return o.repoFactory.Order().GetByID(ctx, id, genericRepo.WithPreload("User", "Product"))
+}
+
 func (o *orderService) GetOrders(ctx context.Context, ids []uint64) ([]*model.Order, error) {
 	return o.repoFactory.Order().GetByIDs(ctx, ids)
 }
 
 func (o *orderService) GetOrdersByUserID(ctx context.Context, userID uint64) ([]*model.Order, error) {
-	return o.repoFactory.Order().GetByStructFields(ctx, &model.Order{UserID: userID})
+	spec := genericRepo.NewSpec(genericRepo.Eq("user_id", userID)).
+		OrderBy(genericRepo.Desc("created_at"))
+	return o.repoFactory.Order().Find(ctx, spec)
 }
 
-func (o *orderService) GetOrdersByPage(ctx context.Context, page, pageSize uint64) ([]*model.Order, error) {
-	return o.repoFactory.Order().GetByPage(ctx, page, pageSize)
+func (o *orderService) GetOrdersByPage(ctx context.Context, page, pageSize uint64) (*genericRepo.Page[*model.Order], error) {
+	return o.repoFactory.Order().GetPage(ctx, nil, page, pageSize, false)
 }
 
 func (o *orderService) GetOrdersByCursor(ctx context.Context, cursor, pageSize uint64) ([]*model.Order, uint64, bool, error) {
 	return o.repoFactory.Order().GetByCursor(ctx, cursor, pageSize)
 }
 
+func (o *orderService) GetLatestOrders(ctx context.Context, cursor string, pageSize int) (*genericRepo.KeysetPage[*model.Order], error) {
+	return o.repoFactory.Order().GetByKeyset(ctx, genericRepo.KeysetQuery{
+		Sorts:  []genericRepo.Sort{genericRepo.Desc("created_at")},
+		Cursor: cursor,
+		Limit:  pageSize,
+	})
+}
+
 func (o *orderService) UpdateOrder(ctx context.Context, order *model.Order) error {
 	return o.repoFactory.Order().Update(ctx, order)
 }
 
+func (o *orderService) UpdateOrderFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) (*model.Order, error) {
+	// 在同一事务中读回更新后的记录,返回的版本号就是本次更新写入的版本
+	var updated *model.Order
+	err := o.repoFactory.RunInTx(ctx, func(ctx context.Context) error {
+		if err := o.repoFactory.Order().UpdateFields(ctx, id, fieldMask, values); err != nil {
+			return err
+		}
+		var err error
+		updated, err = o.repoFactory.Order().GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
 func (o *orderService) DeleteOrder(ctx context.Context, id uint64) error {
 	return o.repoFactory.Order().DeleteByID(ctx, id)
 }
 
+func (o *orderService) CountOrdersByUser(ctx context.Context, minOrders int64) ([]UserOrderCount, error) {
+	rows, err := o.repoFactory.Order().Aggregate(ctx, genericRepo.AggregateQuery{
+		GroupBy: []genericRepo.Group{genericRepo.GroupBy("user_id")},
+		Metrics: []genericRepo.Metric{genericRepo.CountAll("orders")},
+		Having:  []genericRepo.Cond{genericRepo.Gte("orders", minOrders)},
+		OrderBy: []genericRepo.Sort{genericRepo.Desc("orders")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]UserOrderCount, 0, len(rows))
+	for _, row := range rows {
+		counts = append(counts, UserOrderCount{UserID: row.Uint("user_id"), Orders: row.Int("orders")})
+	}
+	return counts, nil
+}
+
+func (o *orderService) GetDailyOrderTotals(ctx context.Context, since time.Time) ([]DailyOrderTotal, error) {
+	rows, err := o.repoFactory.Order().Aggregate(ctx, genericRepo.AggregateQuery{
+		Spec:    genericRepo.NewSpec(genericRepo.Gte("created_at", since)),
+		GroupBy: []genericRepo.Group{genericRepo.GroupByTime("created_at", genericRepo.BucketDay, "day")},
+		Metrics: []genericRepo.Metric{genericRepo.CountAll("orders")},
+		OrderBy: []genericRepo.Sort{genericRepo.Asc("day")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals := make([]DailyOrderTotal, 0, len(rows))
+	for _, row := range rows {
+		totals = append(totals, DailyOrderTotal{Day: row.Time("day"), Orders: row.Int("orders")})
+	}
+	return totals, nil
+}
+
 func (o *orderService) DeleteOrders(ctx context.Context, ids []uint64) error {
 	return o.repoFactory.Order().DeleteByIDs(ctx, ids)
 }
+
+func (o *orderService) DeleteOrdersByUserID(ctx context.Context, userID uint64) (int64, error) {
+	if userID == 0 {
+		return 0, fmt.Errorf("delete orders by user id failed, user id is 0: %w", errs.ErrInvalidArgument)
+	}
+	return o.repoFactory.Order().DeleteWhere(ctx, genericRepo.NewSpec(genericRepo.Eq("user_id", userID)))
+}
 func (o *orderService) CreateOrderWithUser(ctx context.Context, userID uint64, orderID uint64) error {
 
-	err := o.repoFactory.Transaction(ctx, func(factory repo.RepoFactory) error {
-		// 1. 获取事务版本的仓储
-		orderRepo := factory.Order()
-		userRepo := factory.User()
+	// 事务随 ctx 传递,其中的仓储调用(包括其他服务的调用)都会加入该事务
+	err := o.repoFactory.RunInTx(ctx, func(ctx context.Context) error {
+		// 1. 仓储方法使用携带事务的 ctx 即可加入事务
+		orderRepo := o.repoFactory.Order()
+		userRepo := o.repoFactory.User()
 
 		// 2. 执行数据库操作（在事务中）
 		order := &model.Order{
@@ -89,16 +194,22 @@ func (o *orderService) CreateOrderWithUser(ctx context.Context, userID uint64, o
 			return fmt.Errorf("user not found: %w", err)
 		}
 
-		log.Printf("user: %v", user)
-
 		if err := orderRepo.Create(ctx, order); err != nil {
 			return fmt.Errorf("create order failed: %w", err)
 		}
 
-		log.Printf("order: %v", order)
+		o.logger.DebugContext(ctx, "order created",
+			slog.Uint64("order_id", order.ID),
+			slog.Uint64("user_id", user.ID),
+			slog.Uint64("product_id", order.ProductID))
+
+		// 3. 事件与订单在同一事务中写入发件箱,提交后由中继投递
+		if _, err := o.repoFactory.Outbox().Enqueue(ctx, TopicOrderCreated, order.ID, order); err != nil {
+			return fmt.Errorf("enqueue order created event failed: %w", err)
+		}
 
 		return nil
-	})
+	}, repo.WithRetry(3))
 	if err != nil {
 		return fmt.Errorf("transaction failed: %w", err)
 	}
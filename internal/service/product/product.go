@@ -2,21 +2,39 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"go-pattern/internal/model"
 	repo "go-pattern/internal/repo/factory"
+	genericRepo "go-pattern/internal/repo/generic"
+	"io"
 )
 
+// ProductStockValue 商品的库存价值 price * quantity
+type ProductStockValue struct {
+	ProductID uint64  `json:"product_id"`
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+}
+
 type ProductService interface {
 	CreateProduct(ctx context.Context, product *model.Product) error
 	CreateProducts(ctx context.Context, products []*model.Product, batchSize int) error
 	GetProduct(ctx context.Context, id uint64) (*model.Product, error)
 	GetProducts(ctx context.Context, ids []uint64) ([]*model.Product, error)
-	GetProductsByPage(ctx context.Context, page, pageSize uint64) ([]*model.Product, error)
+	GetProductsByPage(ctx context.Context, page, pageSize uint64) (*genericRepo.Page[*model.Product], error)
 	GetProductsByCursor(ctx context.Context, cursor, pageSize uint64) ([]*model.Product, uint64, bool, error)
+	// GetProductsByPrice 按价格升序的键集分页
+	GetProductsByPrice(ctx context.Context, cursor string, pageSize int) (*genericRepo.KeysetPage[*model.Product], error)
 	UpdateProduct(ctx context.Context, product *model.Product) error
 	DeleteProduct(ctx context.Context, id uint64) error
 	DeleteProducts(ctx context.Context, ids []uint64) error
 	ReduceQuantity(ctx context.Context, productID, count uint64) error
+	// GetStockValues 按库存价值倒序返回前 limit 个商品
+	GetStockValues(ctx context.Context, limit int) ([]ProductStockValue, error)
+	// ExportProducts 以 JSON Lines 格式导出全部商品
+	ExportProducts(ctx context.Context, w io.Writer) error
 }
 
 type productService struct {
@@ -43,14 +61,22 @@ func (p *productService) GetProducts(ctx context.Context, ids []uint64) ([]*mode
 	return p.repoFactory.Product().GetByIDs(ctx, ids)
 }
 
-func (p *productService) GetProductsByPage(ctx context.Context, page, pageSize uint64) ([]*model.Product, error) {
-	return p.repoFactory.Product().GetByPage(ctx, page, pageSize)
+func (p *productService) GetProductsByPage(ctx context.Context, page, pageSize uint64) (*genericRepo.Page[*model.Product], error) {
+	return p.repoFactory.Product().GetPage(ctx, nil, page, pageSize, false)
 }
 
 func (p *productService) GetProductsByCursor(ctx context.Context, cursor, pageSize uint64) ([]*model.Product, uint64, bool, error) {
 	return p.repoFactory.Product().GetByCursor(ctx, cursor, pageSize)
 }
 
+func (p *productService) GetProductsByPrice(ctx context.Context, cursor string, pageSize int) (*genericRepo.KeysetPage[*model.Product], error) {
+	return p.repoFactory.Product().GetByKeyset(ctx, genericRepo.KeysetQuery{
+		Sorts:  []genericRepo.Sort{genericRepo.Asc("price")},
+		Cursor: cursor,
+		Limit:  pageSize,
+	})
+}
+
 func (p *productService) UpdateProduct(ctx context.Context, Product *model.Product) error {
 	return p.repoFactory.Product().Update(ctx, Product)
 }
@@ -64,5 +90,45 @@ func (p *productService) DeleteProducts(ctx context.Context, ids []uint64) error
 }
 
 func (p *productService) ReduceQuantity(ctx context.Context, productID, count uint64) error {
-	return p.repoFactory.Product().ReduceQuantity(ctx, productID, count)
+	// 行锁竞争导致的死锁可以通过重试整个事务解决
+	return p.repoFactory.RunInTx(ctx, func(ctx context.Context) error {
+		return p.repoFactory.Product().ReduceQuantity(ctx, productID, count)
+	}, repo.WithRetry(3))
+}
+
+func (p *productService) GetStockValues(ctx context.Context, limit int) ([]ProductStockValue, error) {
+	rows, err := p.repoFactory.Product().Aggregate(ctx, genericRepo.AggregateQuery{
+		GroupBy: []genericRepo.Group{genericRepo.GroupBy("id"), genericRepo.GroupBy("name")},
+		Metrics: []genericRepo.Metric{genericRepo.SumOf("stock_value", "price", "quantity")},
+		OrderBy: []genericRepo.Sort{genericRepo.Desc("stock_value")},
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	values := make([]ProductStockValue, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, ProductStockValue{
+			ProductID: row.Uint("id"),
+			Name:      row.String("name"),
+			Value:     row.Float("stock_value"),
+		})
+	}
+	return values, nil
+}
+
+func (p *productService) ExportProducts(ctx context.Context, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	// 可重复读的只读事务保证分批读取的是同一个快照
+	return p.repoFactory.RunInTx(ctx, func(ctx context.Context) error {
+		for product, err := range p.repoFactory.Product().Iterate(ctx, nil, 500) {
+			if err != nil {
+				return fmt.Errorf("export products failed: %w", err)
+			}
+			if err := encoder.Encode(product); err != nil {
+				return fmt.Errorf("export products failed, encode error: %w", err)
+			}
+		}
+		return nil
+	}, repo.WithIsolation(sql.LevelRepeatableRead), repo.ReadOnly())
 }
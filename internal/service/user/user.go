@@ -4,16 +4,21 @@ import (
 	"context"
 	"go-pattern/internal/model"
 	repo "go-pattern/internal/repo/factory"
+	genericRepo "go-pattern/internal/repo/generic"
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, user *model.User) error
 	CreateUsers(ctx context.Context, users []*model.User, batchSize int) error
+	// ImportUsers 按邮箱导入用户,已存在的用户更新姓名
+	ImportUsers(ctx context.Context, users []*model.User, batchSize int) (genericRepo.UpsertResult, error)
 	GetUser(ctx context.Context, id uint64) (*model.User, error)
 	GetUsers(ctx context.Context, ids []uint64) ([]*model.User, error)
-	GetUsersByPage(ctx context.Context, page, pageSize uint64) ([]*model.User, error)
+	GetUsersByPage(ctx context.Context, page, pageSize uint64) (*genericRepo.Page[*model.User], error)
 	GetUsersByCursor(ctx context.Context, cursor uint64, pageSize uint64) ([]*model.User, uint64, bool, error)
 	UpdateUser(ctx context.Context, user *model.User) error
+	// UpdateUserFields 按字段掩码部分更新,返回更新后的记录
+	UpdateUserFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) (*model.User, error)
 	DeleteUser(ctx context.Context, id uint64) error
 	DeleteUsers(ctx context.Context, ids []uint64) error
 }
@@ -34,6 +39,10 @@ func (u *userService) CreateUsers(ctx context.Context, users []*model.User, batc
 	return u.repoFactory.User().CreateInBatches(ctx, users, batchSize)
 }
 
+func (u *userService) ImportUsers(ctx context.Context, users []*model.User, batchSize int) (genericRepo.UpsertResult, error) {
+	return u.repoFactory.User().UpsertInBatches(ctx, users, batchSize, []string{"email"}, []string{"name"})
+}
+
 func (u *userService) GetUser(ctx context.Context, id uint64) (*model.User, error) {
 	return u.repoFactory.User().GetByID(ctx, id)
 }
@@ -42,8 +51,8 @@ func (u *userService) GetUsers(ctx context.Context, ids []uint64) ([]*model.User
 	return u.repoFactory.User().GetByIDs(ctx, ids)
 }
 
-func (u *userService) GetUsersByPage(ctx context.Context, page, pageSize uint64) ([]*model.User, error) {
-	return u.repoFactory.User().GetByPage(ctx, page, pageSize)
+func (u *userService) GetUsersByPage(ctx context.Context, page, pageSize uint64) (*genericRepo.Page[*model.User], error) {
+	return u.repoFactory.User().GetPage(ctx, nil, page, pageSize, false)
 }
 
 func (u *userService) GetUsersByCursor(ctx context.Context, cursor, pageSize uint64) ([]*model.User, uint64, bool, error) {
@@ -54,6 +63,23 @@ func (u *userService) UpdateUser(ctx context.Context, user *model.User) error {
 	return u.repoFactory.User().Update(ctx, user)
 }
 
+func (u *userService) UpdateUserFields(ctx context.Context, id uint64, fieldMask []string, values map[string]any) (*model.User, error) {
+	// 在同一事务中读回更新后的记录,返回的版本号就是本次更新写入的版本
+	var updated *model.User
+	err := u.repoFactory.RunInTx(ctx, func(ctx context.Context) error {
+		if err := u.repoFactory.User().UpdateFields(ctx, id, fieldMask, values); err != nil {
+			return err
+		}
+		var err error
+		updated, err = u.repoFactory.User().GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
 func (u *userService) DeleteUser(ctx context.Context, id uint64) error {
 	return u.repoFactory.User().DeleteByID(ctx, id)
 }
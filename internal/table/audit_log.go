@@ -0,0 +1,38 @@
+package table
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func NewAuditLogTable(db *gorm.DB) error {
+	d := dialectOf(db)
+	table := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS audit_log(
+			id %[1]s,
+			tenant_id BIGINT NOT NULL DEFAULT 0,
+			table_name VARCHAR(64) NOT NULL,
+			entity_id BIGINT NOT NULL,
+			action VARCHAR(16) NOT NULL,
+			actor VARCHAR(128) NOT NULL,
+			before_data %[3]s,
+			after_data %[3]s,
+			created_at TIMESTAMP DEFAULT %[2]s
+		);`, d.id, d.now, d.json)
+	err := db.Exec(table).Error
+	if err != nil {
+		return fmt.Errorf("创建审计日志表失败: %w", err)
+	}
+	// 兼容已存在的审计日志表,补充租户列及索引
+	if err := addColumn(db, "audit_log", "tenant_id", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("创建审计日志租户列失败: %w", err)
+	}
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id ON audit_log(tenant_id);`).Error; err != nil {
+		return fmt.Errorf("创建审计日志租户索引失败: %w", err)
+	}
+	// 按实体查询审计记录
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(table_name, entity_id);`).Error; err != nil {
+		return fmt.Errorf("创建审计日志索引失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package table
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// dialect 建表语句中与数据库相关的部分
+type dialect struct {
+	sqlite bool
+	// id 自增主键列
+	id string
+	// json JSON 列类型
+	json string
+	// now 时间列的默认值
+	now string
+}
+
+var (
+	postgresDialect = dialect{id: "BIGSERIAL PRIMARY KEY", json: "JSONB", now: "CURRENT_TIMESTAMP"}
+	// SQLite 的时间以 UTC 文本保存,与驱动写入的时间可以直接按文本比较
+	sqliteDialect = dialect{
+		sqlite: true,
+		id:     "INTEGER PRIMARY KEY AUTOINCREMENT",
+		json:   "TEXT",
+		now:    "(strftime('%Y-%m-%d %H:%M:%f', 'now'))",
+	}
+)
+
+func dialectOf(db *gorm.DB) dialect {
+	if db.Dialector.Name() == "sqlite" {
+		return sqliteDialect
+	}
+	return postgresDialect
+}
+
+// addColumn 兼容已存在的表补充列。SQLite 不支持 ADD COLUMN IF NOT EXISTS,先检查列是否存在
+func addColumn(db *gorm.DB, table, column, definition string) error {
+	if db.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition)).Error
+}
+
+// updatedAtTrigger 创建更新行时刷新 updated_at 的触发器。
+// postgres 使用 NewUpdateAtTrigger 创建的函数;SQLite 没有 plpgsql,触发器也不能修改 NEW,
+// 改为更新后回写,应用本次已写入 updated_at 时保留其值
+func updatedAtTrigger(db *gorm.DB, table string) error {
+	d := dialectOf(db)
+	sql := fmt.Sprintf(`
+		DROP TRIGGER IF EXISTS update_%[1]s_updated_at ON %[1]s;
+		CREATE TRIGGER update_%[1]s_updated_at
+		BEFORE UPDATE ON %[1]s
+		FOR EACH ROW
+		EXECUTE FUNCTION update_updated_at_column();`, table)
+	if d.sqlite {
+		sql = fmt.Sprintf(`
+		DROP TRIGGER IF EXISTS update_%[1]s_updated_at;
+		CREATE TRIGGER update_%[1]s_updated_at
+		AFTER UPDATE ON %[1]s
+		FOR EACH ROW
+		WHEN NEW.updated_at IS OLD.updated_at
+		BEGIN
+			UPDATE %[1]s SET updated_at = %[2]s WHERE id = NEW.id;
+		END;`, table, d.now)
+	}
+	return db.Exec(sql).Error
+}
@@ -1,37 +1,48 @@
 package table
 
 import (
-	"log"
+	"fmt"
 
 	"gorm.io/gorm"
 )
 
 func NewOrderTable(db *gorm.DB) error {
-	table := `CREATE TABLE IF NOT EXISTS orders(
-			id BIGSERIAL PRIMARY KEY,
+	d := dialectOf(db)
+	table := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS orders(
+			id %[1]s,
+			tenant_id BIGINT NOT NULL DEFAULT 0,
 			user_id BIGINT NOT NULL,
 			product_id BIGINT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);`
+			version BIGINT NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT %[2]s,
+			updated_at TIMESTAMP DEFAULT %[2]s,
+			deleted_at TIMESTAMP
+		);`, d.id, d.now)
 	err := db.Exec(table).Error
 	if err != nil {
-		log.Printf("NewOrderTable(table): 创建订单表失败: %v", err)
-		return err
+		return fmt.Errorf("创建订单表失败: %w", err)
 	}
-	// 创建用户表更新时间戳触发器
-	createUserUpdateTrigger := `
-	DROP TRIGGER IF EXISTS update_orders_updated_at ON orders;
-	CREATE TRIGGER update_orders_updated_at
-	BEFORE UPDATE ON orders
-	FOR EACH ROW
-	EXECUTE FUNCTION update_updated_at_column();`
-
-	if err := db.Exec(createUserUpdateTrigger).Error; err != nil {
-		log.Printf("创建用户表更新时间戳触发器失败: %v", err)
-		return err
+	// 兼容已存在的订单表,补充乐观锁版本列
+	if err := addColumn(db, "orders", "version", "BIGINT NOT NULL DEFAULT 1"); err != nil {
+		return fmt.Errorf("创建订单版本列失败: %w", err)
+	}
+	// 兼容已存在的订单表,补充软删除列及索引
+	if err := addColumn(db, "orders", "deleted_at", "TIMESTAMP"); err != nil {
+		return fmt.Errorf("创建订单软删除列失败: %w", err)
+	}
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders(deleted_at);`).Error; err != nil {
+		return fmt.Errorf("创建订单软删除索引失败: %w", err)
+	}
+	// 兼容已存在的订单表,补充租户列及索引
+	if err := addColumn(db, "orders", "tenant_id", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("创建订单租户列失败: %w", err)
+	}
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_orders_tenant_id ON orders(tenant_id);`).Error; err != nil {
+		return fmt.Errorf("创建订单租户索引失败: %w", err)
+	}
+	// 创建订单表更新时间戳触发器
+	if err := updatedAtTrigger(db, "orders"); err != nil {
+		return fmt.Errorf("创建订单表更新时间戳触发器失败: %w", err)
 	}
-
-	log.Println("用户表更新时间戳触发器初始化完成")
 	return nil
 }
@@ -0,0 +1,32 @@
+package table
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func NewOutboxEventTable(db *gorm.DB) error {
+	d := dialectOf(db)
+	table := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS outbox_events(
+			id %[1]s,
+			topic VARCHAR(128) NOT NULL,
+			aggregate_id BIGINT NOT NULL,
+			payload %[3]s NOT NULL,
+			status VARCHAR(16) NOT NULL DEFAULT 'pending',
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT %[2]s,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT %[2]s,
+			delivered_at TIMESTAMP
+		);`, d.id, d.now, d.json)
+	err := db.Exec(table).Error
+	if err != nil {
+		return fmt.Errorf("创建事件发件箱表失败: %w", err)
+	}
+	// 中继按状态与下次投递时间轮询待投递事件
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(next_attempt_at, id) WHERE status = 'pending';`).Error; err != nil {
+		return fmt.Errorf("创建事件发件箱索引失败: %w", err)
+	}
+	return nil
+}
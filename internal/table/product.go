@@ -1,39 +1,42 @@
 package table
 
 import (
-	"log"
+	"fmt"
 
 	"gorm.io/gorm"
 )
 
 func NewProductTable(db *gorm.DB) error {
-	table := `CREATE TABLE IF NOT EXISTS products(
-			id BIGSERIAL PRIMARY KEY,
+	d := dialectOf(db)
+	table := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS products(
+			id %[1]s,
+			tenant_id BIGINT NOT NULL DEFAULT 0,
 			name VARCHAR(50) NOT NULL,
 			description TEXT,
 			price DECIMAL(10, 2) NOT NULL,
 			quantity BIGINT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);`
+			version BIGINT NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT %[2]s,
+			updated_at TIMESTAMP DEFAULT %[2]s
+		);`, d.id, d.now)
 	err := db.Exec(table).Error
 	if err != nil {
-		log.Printf("NewProductTable(table): 创建商品表失败: %v", err)
-		return err
+		return fmt.Errorf("创建商品表失败: %w", err)
 	}
-	// 创建用户表更新时间戳触发器
-	createUserUpdateTrigger := `
-		DROP TRIGGER IF EXISTS update_products_updated_at ON products;
-		CREATE TRIGGER update_products_updated_at
-		BEFORE UPDATE ON products
-		FOR EACH ROW
-		EXECUTE FUNCTION update_updated_at_column();`
-
-	if err := db.Exec(createUserUpdateTrigger).Error; err != nil {
-		log.Printf("创建用户表更新时间戳触发器失败: %v", err)
-		return err
+	// 兼容已存在的商品表,补充乐观锁版本列
+	if err := addColumn(db, "products", "version", "BIGINT NOT NULL DEFAULT 1"); err != nil {
+		return fmt.Errorf("创建商品版本列失败: %w", err)
+	}
+	// 兼容已存在的商品表,补充租户列及索引
+	if err := addColumn(db, "products", "tenant_id", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("创建商品租户列失败: %w", err)
+	}
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_products_tenant_id ON products(tenant_id);`).Error; err != nil {
+		return fmt.Errorf("创建商品租户索引失败: %w", err)
+	}
+	// 创建商品表更新时间戳触发器
+	if err := updatedAtTrigger(db, "products"); err != nil {
+		return fmt.Errorf("创建商品表更新时间戳触发器失败: %w", err)
 	}
-
-	log.Println("用户表更新时间戳触发器初始化完成")
 	return nil
 }
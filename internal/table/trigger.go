@@ -1,12 +1,16 @@
 package table
 
 import (
-	"log"
+	"fmt"
 
 	"gorm.io/gorm"
 )
 
 func NewUpdateAtTrigger(db *gorm.DB) error {
+	// SQLite 的触发器在各表中直接回写 updated_at,不需要公共函数
+	if dialectOf(db).sqlite {
+		return nil
+	}
 	// 创建通用的更新时间戳函数（只执行一次）
 	createUpdateTimeFunc := `
 	CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -18,10 +22,7 @@ func NewUpdateAtTrigger(db *gorm.DB) error {
 	$$ LANGUAGE plpgsql;`
 
 	if err := db.Exec(createUpdateTimeFunc).Error; err != nil {
-		log.Printf("创建通用更新时间戳函数失败: %v", err)
-		return err
+		return fmt.Errorf("创建通用更新时间戳函数失败: %w", err)
 	}
-
-	log.Println("数据库通用函数初始化完成")
 	return nil
 }
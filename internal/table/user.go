@@ -1,38 +1,45 @@
 package table
 
 import (
-	"log"
+	"fmt"
 
 	"gorm.io/gorm"
 )
 
 func NewUserTable(db *gorm.DB) error {
-	table := `CREATE TABLE IF NOT EXISTS users(
-			id BIGSERIAL PRIMARY KEY,
+	d := dialectOf(db)
+	table := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS users(
+			id %[1]s,
+			tenant_id BIGINT NOT NULL DEFAULT 0,
 			name VARCHAR(50) NOT NULL,
 			email VARCHAR(50) ,
 			phone VARCHAR(20) ,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);`
+			version BIGINT NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT %[2]s,
+			updated_at TIMESTAMP DEFAULT %[2]s
+		);`, d.id, d.now)
 	err := db.Exec(table).Error
 	if err != nil {
-		log.Printf("NewUserTable(table): 创建用户表失败: %v", err)
-		return err
+		return fmt.Errorf("创建用户表失败: %w", err)
+	}
+	// 兼容已存在的用户表,补充租户列
+	if err := addColumn(db, "users", "tenant_id", "BIGINT NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("创建用户租户列失败: %w", err)
+	}
+	// 邮箱在租户内唯一,(tenant_id, email) 作为用户 upsert 的冲突列
+	tenantEmail := `
+	DROP INDEX IF EXISTS idx_users_email;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email ON users(tenant_id, email);`
+	if err := db.Exec(tenantEmail).Error; err != nil {
+		return fmt.Errorf("创建用户邮箱唯一索引失败: %w", err)
+	}
+	// 兼容已存在的用户表,补充乐观锁版本列
+	if err := addColumn(db, "users", "version", "BIGINT NOT NULL DEFAULT 1"); err != nil {
+		return fmt.Errorf("创建用户版本列失败: %w", err)
 	}
 	// 创建用户表更新时间戳触发器
-	createUserUpdateTrigger := `
-		DROP TRIGGER IF EXISTS update_users_updated_at ON users;
-		CREATE TRIGGER update_users_updated_at
-		BEFORE UPDATE ON users
-		FOR EACH ROW
-		EXECUTE FUNCTION update_updated_at_column();`
-
-	if err := db.Exec(createUserUpdateTrigger).Error; err != nil {
-		log.Printf("创建用户表更新时间戳触发器失败: %v", err)
-		return err
+	if err := updatedAtTrigger(db, "users"); err != nil {
+		return fmt.Errorf("创建用户表更新时间戳触发器失败: %w", err)
 	}
-
-	log.Println("用户表更新时间戳触发器初始化完成")
 	return nil
 }
@@ -0,0 +1,30 @@
+package tenant
+
+import "context"
+
+type tenantKey struct{}
+
+type bypassKey struct{}
+
+// WithTenant 将租户写入上下文,仓储据此为读写自动加上 tenant_id
+func WithTenant(ctx context.Context, tenantID uint64) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext 读取上下文中的租户,0 视为未设置
+func FromContext(ctx context.Context) (uint64, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(uint64)
+	return tenantID, ok && tenantID != 0
+}
+
+// Bypass 返回跳过租户隔离的上下文,只用于跨租户的后台任务与管理操作
+// Bypass 下的读操作不走缓存,写操作无法失效各租户的缓存项,依赖缓存过期
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed 上下文是否跳过租户隔离
+func Bypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(bypassKey{}).(bool)
+	return bypassed
+}
@@ -62,13 +62,21 @@ func NewAuthService(jwtConfig config.JWTConfig, issuer string, audience []string
 
 type CustomClaims struct {
 	UserID uint64 `json:"user_id"`
+	// TenantID 用户所属租户,0 表示未绑定租户
+	TenantID uint64 `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func (a *AuthService) GenerateToken(id uint64, subject string) (string, error) {
+	return a.GenerateTenantToken(id, 0, subject)
+}
+
+// GenerateTenantToken 生成携带租户的令牌,租户中间件优先使用该声明
+func (a *AuthService) GenerateTenantToken(id uint64, tenantID uint64, subject string) (string, error) {
 	// 生成 JWT 令牌
 	claims := CustomClaims{
-		UserID: id,
+		UserID:   id,
+		TenantID: tenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(a.tokenExpire) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -0,0 +1,32 @@
+package etag
+
+import (
+	"fmt"
+	"go-pattern/internal/errs"
+	"strconv"
+	"strings"
+)
+
+// Format 将模型版本号格式化为强ETag,如 "3"
+func Format(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// ParseIfMatch 解析 If-Match 请求头中的版本号,支持 "3"、W/"3" 以及不带引号的 3。
+// 请求头为空或为 * 时 ok 为 false,即不校验版本;
+// 列出多个版本时无法作为单一的期望版本,返回 ErrPreconditionFailed
+func ParseIfMatch(header string) (version uint64, ok bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, false, fmt.Errorf("If-Match %q lists more than one version: %w", header, errs.ErrPreconditionFailed)
+	}
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid If-Match version %q: %w", header, errs.ErrInvalidArgument)
+	}
+	return version, true, nil
+}
@@ -0,0 +1,43 @@
+package etag
+
+import (
+	"errors"
+	"go-pattern/internal/errs"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	if got := Format(3); got != `"3"` {
+		t.Fatalf("Format(3) = %s, want %q", got, `"3"`)
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	expectVersion := func(header string, want uint64) {
+		t.Helper()
+		version, ok, err := ParseIfMatch(header)
+		if err != nil || !ok || version != want {
+			t.Errorf("ParseIfMatch(%q) = %d, %v, %v, want %d", header, version, ok, err, want)
+		}
+	}
+	expectVersion(`"3"`, 3)
+	expectVersion(`W/"3"`, 3)
+	expectVersion("3", 3)
+	expectVersion(` "7" `, 7)
+	expectVersion(Format(42), 42)
+
+	// 未携带 If-Match 或为 * 时不做版本校验
+	for _, header := range []string{"", "  ", "*"} {
+		if _, ok, err := ParseIfMatch(header); ok || err != nil {
+			t.Errorf("ParseIfMatch(%q) = %v, %v, want no version", header, ok, err)
+		}
+	}
+	for _, header := range []string{`"-1"`, `"abc"`, `W/`} {
+		if _, _, err := ParseIfMatch(header); !errors.Is(err, errs.ErrInvalidArgument) {
+			t.Errorf("ParseIfMatch(%q) error = %v, want ErrInvalidArgument", header, err)
+		}
+	}
+	if _, _, err := ParseIfMatch(`"3", "4"`); !errors.Is(err, errs.ErrPreconditionFailed) {
+		t.Errorf("ParseIfMatch(list) error = %v, want ErrPreconditionFailed", err)
+	}
+}
@@ -0,0 +1,48 @@
+package fieldmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Field 描述一个允许部分更新的JSON字段:对应的数据库列及其解码方式
+type Field struct {
+	Column string
+	decode func(raw json.RawMessage) (any, error)
+}
+
+// As 声明一个解码为 V 类型的字段,JSON null 解码为 V 的零值
+func As[V any](column string) Field {
+	return Field{
+		Column: column,
+		decode: func(raw json.RawMessage) (any, error) {
+			var value V
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			}
+			return value, nil
+		},
+	}
+}
+
+// Parse 根据请求体中实际出现的键构建字段掩码与对应的值,
+// 未出现的键不会进入掩码,因此可以区分"未传"与"置空"
+func Parse(body map[string]json.RawMessage, fields map[string]Field) ([]string, map[string]any, error) {
+	mask := make([]string, 0, len(body))
+	values := make(map[string]any, len(body))
+	for key, raw := range body {
+		field, ok := fields[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("field %q can not be updated", key)
+		}
+		value, err := field.decode(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decode field %q failed: %w", key, err)
+		}
+		mask = append(mask, field.Column)
+		values[field.Column] = value
+	}
+	slices.Sort(mask)
+	return mask, values, nil
+}
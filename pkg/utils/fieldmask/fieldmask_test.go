@@ -0,0 +1,49 @@
+package fieldmask
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var userFields = map[string]Field{
+	"username": As[string]("name"),
+	"email":    As[string]("email"),
+	"age":      As[int]("age"),
+}
+
+func parse(t *testing.T, body string) ([]string, map[string]any, error) {
+	t.Helper()
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		t.Fatal(err)
+	}
+	return Parse(raw, userFields)
+}
+
+func TestParse(t *testing.T) {
+	mask, values, err := parse(t, `{"username":"alice","email":null,"age":0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 掩码使用列名并排序;null 清空为零值,显式传入的零值同样保留
+	if want := []string{"age", "email", "name"}; !reflect.DeepEqual(mask, want) {
+		t.Fatalf("mask = %v, want %v", mask, want)
+	}
+	if want := map[string]any{"name": "alice", "email": "", "age": 0}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("values = %v, want %v", values, want)
+	}
+
+	mask, values, err = parse(t, `{}`)
+	if err != nil || len(mask) != 0 || len(values) != 0 {
+		t.Fatalf("empty body = %v, %v, %v", mask, values, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, body := range []string{`{"role":"admin"}`, `{"age":"ten"}`} {
+		if _, _, err := parse(t, body); err == nil {
+			t.Errorf("Parse(%s) must fail", body)
+		}
+	}
+}